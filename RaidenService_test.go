@@ -1,6 +1,7 @@
 package smartraiden
 
 import (
+	"math/big"
 	"os"
 	"testing"
 
@@ -16,7 +17,13 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/initiator"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func init() {
@@ -154,3 +161,49 @@ func TestTimer(t *testing.T) {
 	<-tm.C
 	fmt.Println("fired after stop")
 }
+
+func TestCancelInvoicePayment(t *testing.T) {
+	rs := &RaidenService{NodeAddress: utils.NewRandomAddress(), Transfer2StateManager: make(map[common.Hash]*transfer.StateManager)}
+	token := utils.NewRandomAddress()
+	hop, target := utils.NewRandomAddress(), utils.NewRandomAddress()
+	//paying an invoice, only the target knows the secret
+	secret := utils.NewRandomHash()
+	lockSecretHash := utils.ShaSecret(secret[:])
+	r := route.NewState(newTestChannel(rs.NodeAddress, hop, token, 100, 100))
+	stateManager := transfer.NewStateManager(initiator.StateTransition, nil, initiator.NameInitiatorTransition, lockSecretHash, token)
+	stateManager.CurrentState = &mediatedtransfer.InitiatorState{
+		OurAddress: rs.NodeAddress,
+		Transfer: &mediatedtransfer.LockedTransferState{
+			TargetAmount:   big.NewInt(10),
+			Amount:         big.NewInt(10),
+			Token:          token,
+			Initiator:      rs.NodeAddress,
+			Target:         target,
+			Expiration:     100,
+			LockSecretHash: lockSecretHash,
+		},
+		Routes:         route.NewRoutesState([]*route.State{r}),
+		Route:          r,
+		BlockNumber:    1,
+		LockSecretHash: lockSecretHash,
+	}
+	rs.Transfer2StateManager[utils.Sha3(lockSecretHash[:], token[:])] = stateManager
+	if err := rs.cancelTransfer(lockSecretHash); err != rerr.ErrTransferCannotCancel {
+		t.Fatalf("invoice payment should not be canceled, err=%v", err)
+	}
+	//the target reveals the secret later, the payment completes
+	events := stateManager.Dispatch(&mediatedtransfer.ReceiveSecretRevealStateChange{
+		Secret: secret,
+		Sender: hop,
+	})
+	success := false
+	for _, e := range events {
+		switch e.(type) {
+		case *transfer.EventTransferSentSuccess:
+			success = true
+		case *transfer.EventTransferSentFailed:
+			t.Error("invoice payment should not fail")
+		}
+	}
+	assert(t, success, true)
+}
@@ -0,0 +1,102 @@
+package smartraiden
+
+import (
+	"fmt"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+autoSettle settles all the closed channels whose settle timeout has expired,
+a failed settle will be retried after `params.AutoSettleRetryInterval` blocks.
+must be called in the main loop.
+*/
+func (rs *RaidenService) autoSettle(blockNumber int64) {
+	for _, g := range rs.Token2ChannelGraph {
+		for _, c := range g.Channels() {
+			if c.State != channeltype.StateClosed || c.ExternState.ClosedBlock == 0 {
+				continue
+			}
+			if blockNumber <= c.GetSettleExpiration(blockNumber) {
+				continue
+			}
+			rs.autoSettleChannel(c, blockNumber)
+		}
+	}
+}
+
+func (rs *RaidenService) autoSettleChannel(c *channel.Channel, blockNumber int64) {
+	channelIdentifier := c.ChannelIdentifier.ChannelIdentifier
+	rs.autoSettleLock.Lock()
+	defer rs.autoSettleLock.Unlock()
+	if rs.autoSettlingChannels[channelIdentifier] {
+		return
+	}
+	r, err := rs.db.GetSettleRecord(channelIdentifier)
+	if err != nil {
+		r = &models.SettleRecord{
+			ChannelIdentifier: channelIdentifier,
+			OpenBlockNumber:   c.ChannelIdentifier.OpenBlockNumber,
+			TokenAddress:      c.TokenAddress,
+			PartnerAddress:    c.PartnerState.Address,
+		}
+	} else if r.OpenBlockNumber == c.ChannelIdentifier.OpenBlockNumber {
+		if r.Status == models.SettleStatusSuccess {
+			//waiting for the settled event
+			return
+		}
+		if r.Status == models.SettleStatusFailed && blockNumber < r.LastAttemptBlock+params.AutoSettleRetryInterval {
+			return
+		}
+	} else {
+		//record of an old channel with the same identifier
+		r.OpenBlockNumber = c.ChannelIdentifier.OpenBlockNumber
+		r.Attempts = 0
+		r.Error = ""
+	}
+	r.Status = models.SettleStatusSettling
+	r.Attempts++
+	r.LastAttemptBlock = blockNumber
+	rs.saveSettleRecord(r)
+	log.Info(fmt.Sprintf("auto settle channel %s, attempts=%d", utils.HPex(channelIdentifier), r.Attempts))
+	rs.autoSettlingChannels[channelIdentifier] = true
+	result := c.Settle()
+	go func() {
+		err := <-result.Result
+		rs.autoSettleLock.Lock()
+		defer rs.autoSettleLock.Unlock()
+		delete(rs.autoSettlingChannels, channelIdentifier)
+		if err != nil {
+			log.Error(fmt.Sprintf("auto settle channel %s err %s", utils.HPex(channelIdentifier), err))
+			r.Status = models.SettleStatusFailed
+			r.Error = err.Error()
+		} else {
+			r.Status = models.SettleStatusSuccess
+			r.Error = ""
+		}
+		rs.saveSettleRecord(r)
+	}()
+}
+
+func (rs *RaidenService) saveSettleRecord(r *models.SettleRecord) {
+	err := rs.db.SaveSettleRecord(r)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveSettleRecord %s err %s", utils.HPex(r.ChannelIdentifier), err))
+	}
+}
+
+//GetSettleRecords returns outcomes of all the channels settled automatically
+func (r *RaidenAPI) GetSettleRecords() ([]*models.SettleRecord, error) {
+	return r.Raiden.db.GetAllSettleRecords()
+}
+
+//GetSettleRecord returns outcome of channel `channelIdentifier` settled automatically
+func (r *RaidenAPI) GetSettleRecord(channelIdentifier common.Hash) (*models.SettleRecord, error) {
+	return r.Raiden.db.GetSettleRecord(channelIdentifier)
+}
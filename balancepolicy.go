@@ -0,0 +1,226 @@
+package smartraiden
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+SetBalancePolicy creates or updates the balance policy of a token, or of a channel if `p.ChannelIdentifier` is not empty.
+*/
+func (r *RaidenAPI) SetBalancePolicy(p *models.BalancePolicy) (err error) {
+	if !r.hasToken(p.TokenAddress) {
+		return errors.New("token not exist")
+	}
+	if p.ChannelIdentifier != utils.EmptyHash {
+		c, err := r.Raiden.db.GetChannelByAddress(p.ChannelIdentifier)
+		if err != nil {
+			return fmt.Errorf("channel %s not found", utils.HPex(p.ChannelIdentifier))
+		}
+		if c.TokenAddress() != p.TokenAddress {
+			return errors.New("channel is not of the token")
+		}
+	}
+	for _, x := range []**big.Int{&p.MinBalance, &p.MaxBalance, &p.TargetBalance, &p.MinAmount, &p.MaxGasPrice} {
+		if *x == nil {
+			*x = big.NewInt(0)
+		}
+		if (*x).Cmp(utils.BigInt0) < 0 {
+			return errors.New("amounts of policy must not be negative")
+		}
+	}
+	if p.MinBalance.Cmp(utils.BigInt0) == 0 && p.MaxBalance.Cmp(utils.BigInt0) == 0 {
+		return errors.New("one of min_balance and max_balance must be set")
+	}
+	if p.MaxBalance.Cmp(utils.BigInt0) > 0 && p.MinBalance.Cmp(p.MaxBalance) > 0 {
+		return errors.New("min_balance must not exceed max_balance")
+	}
+	if p.TargetBalance.Cmp(utils.BigInt0) == 0 {
+		switch {
+		case p.MaxBalance.Cmp(utils.BigInt0) == 0:
+			p.TargetBalance = new(big.Int).Set(p.MinBalance)
+		case p.MinBalance.Cmp(utils.BigInt0) == 0:
+			p.TargetBalance = new(big.Int).Set(p.MaxBalance)
+		default:
+			p.TargetBalance = new(big.Int).Add(p.MinBalance, p.MaxBalance)
+			p.TargetBalance.Div(p.TargetBalance, big.NewInt(2))
+		}
+	}
+	if p.TargetBalance.Cmp(p.MinBalance) < 0 || (p.MaxBalance.Cmp(utils.BigInt0) > 0 && p.TargetBalance.Cmp(p.MaxBalance) > 0) {
+		return errors.New("target_balance must be between min_balance and max_balance")
+	}
+	if p.Interval < 0 {
+		return errors.New("invalid interval")
+	}
+	if p.Interval == 0 {
+		p.Interval = params.DefaultBalancePolicyInterval
+	}
+	return r.Raiden.db.SaveBalancePolicy(p)
+}
+
+//GetBalancePolicies returns all the balance policies
+func (r *RaidenAPI) GetBalancePolicies() ([]*models.BalancePolicy, error) {
+	return r.Raiden.db.GetAllBalancePolicies()
+}
+
+//GetBalancePolicy returns the policy of token or channel `key`
+func (r *RaidenAPI) GetBalancePolicy(key string) (*models.BalancePolicy, error) {
+	return r.Raiden.db.GetBalancePolicy(key)
+}
+
+//RemoveBalancePolicy removes the policy of token or channel `key`
+func (r *RaidenAPI) RemoveBalancePolicy(key string) error {
+	return r.Raiden.db.RemoveBalancePolicy(key)
+}
+
+//GetBalancePolicyActions returns the last deposit or withdraw of every channel made by balance policies
+func (r *RaidenAPI) GetBalancePolicyActions() ([]*models.BalancePolicyAction, error) {
+	return r.Raiden.db.GetAllBalancePolicyActions()
+}
+
+/*
+applyBalancePolicies deposits to or withdraws from open channels whose balance is out of their policies.
+a channel has at most one action in flight, and waits `Interval` blocks after its last action.
+must be called in the main loop.
+*/
+func (rs *RaidenService) applyBalancePolicies(blockNumber int64) {
+	ps, err := rs.db.GetAllBalancePolicies()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetAllBalancePolicies err %s", err))
+		return
+	}
+	if len(ps) == 0 {
+		return
+	}
+	policies := make(map[string]*models.BalancePolicy)
+	for _, p := range ps {
+		policies[p.Key] = p
+	}
+	for _, g := range rs.Token2ChannelGraph {
+		for _, c := range g.Channels() {
+			if c.State != channeltype.StateOpened {
+				continue
+			}
+			p := policies[models.BalancePolicyKey(c.TokenAddress, c.ChannelIdentifier.ChannelIdentifier)]
+			if p == nil {
+				p = policies[models.BalancePolicyKey(c.TokenAddress, utils.EmptyHash)]
+			}
+			if p == nil {
+				continue
+			}
+			rs.applyBalancePolicy(c, p, blockNumber)
+		}
+	}
+}
+
+func (rs *RaidenService) applyBalancePolicy(c *channel.Channel, p *models.BalancePolicy, blockNumber int64) {
+	balance := c.Balance()
+	action := ""
+	amount := new(big.Int)
+	if p.MinBalance.Cmp(utils.BigInt0) > 0 && balance.Cmp(p.MinBalance) < 0 {
+		action = models.BalancePolicyActionDeposit
+		amount.Sub(p.TargetBalance, balance)
+	} else if p.MaxBalance.Cmp(utils.BigInt0) > 0 && balance.Cmp(p.MaxBalance) > 0 {
+		action = models.BalancePolicyActionWithdraw
+		amount.Sub(balance, p.TargetBalance)
+		//locked tokens cannot be withdrawn
+		if amount.Cmp(c.Distributable()) > 0 {
+			amount.Set(c.Distributable())
+		}
+		if _, isOnline := rs.Protocol.GetNetworkStatus(c.PartnerState.Address); !isOnline {
+			return
+		}
+	}
+	if action == "" || amount.Cmp(utils.BigInt0) <= 0 || amount.Cmp(p.MinAmount) < 0 {
+		return
+	}
+	channelIdentifier := c.ChannelIdentifier.ChannelIdentifier
+	rs.balancePolicyLock.Lock()
+	defer rs.balancePolicyLock.Unlock()
+	if rs.balancePolicyChannels[channelIdentifier] {
+		return
+	}
+	a, err := rs.db.GetBalancePolicyAction(channelIdentifier)
+	if err == nil && blockNumber < a.BlockNumber+p.Interval {
+		return
+	}
+	a = &models.BalancePolicyAction{
+		ChannelIdentifier: channelIdentifier,
+		Action:            action,
+		Amount:            amount,
+		BlockNumber:       blockNumber,
+		Status:            models.BalancePolicyStatusPending,
+	}
+	rs.saveBalancePolicyAction(a)
+	log.Info(fmt.Sprintf("balance policy %s %s of channel %s, balance=%s", action, amount, utils.HPex(channelIdentifier), balance))
+	rs.balancePolicyChannels[channelIdentifier] = true
+	go func() {
+		err := rs.runBalancePolicyAction(c.TokenAddress, a, p.MaxGasPrice)
+		rs.balancePolicyLock.Lock()
+		defer rs.balancePolicyLock.Unlock()
+		delete(rs.balancePolicyChannels, channelIdentifier)
+		if err != nil {
+			log.Error(fmt.Sprintf("balance policy %s channel %s err %s", action, utils.HPex(channelIdentifier), err))
+			a.Status = models.BalancePolicyStatusFailed
+			a.Error = err.Error()
+		} else {
+			a.Status = models.BalancePolicyStatusSuccess
+		}
+		rs.saveBalancePolicyAction(a)
+	}()
+}
+
+/*
+runBalancePolicyAction sends the deposit tx or withdraw request through the api request pipeline,
+nothing is sent if gas price is higher than `maxGasPrice`.
+a deposit is cut down to the balance of my account.
+*/
+func (rs *RaidenService) runBalancePolicyAction(tokenAddress common.Address, a *models.BalancePolicyAction, maxGasPrice *big.Int) error {
+	if maxGasPrice.Cmp(utils.BigInt0) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), params.DefaultPollTimeout)
+		gasPrice, err := rs.Chain.Client.SuggestGasPrice(ctx)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if gasPrice.Cmp(maxGasPrice) > 0 {
+			return fmt.Errorf("gas price %s exceeds max gas price %s", gasPrice, maxGasPrice)
+		}
+	}
+	if a.Action == models.BalancePolicyActionWithdraw {
+		return <-rs.withdrawClient(a.ChannelIdentifier, a.Amount).Result
+	}
+	token, err := rs.Chain.Token(tokenAddress)
+	if err != nil {
+		return err
+	}
+	balance, err := token.BalanceOf(rs.NodeAddress)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(utils.BigInt0) <= 0 {
+		return rerr.ErrInsufficientFunds
+	}
+	if balance.Cmp(a.Amount) < 0 {
+		a.Amount = balance
+	}
+	return <-rs.depositChannelClient(a.ChannelIdentifier, a.Amount).Result
+}
+
+func (rs *RaidenService) saveBalancePolicyAction(a *models.BalancePolicyAction) {
+	err := rs.db.SaveBalancePolicyAction(a)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveBalancePolicyAction %s err %s", utils.HPex(a.ChannelIdentifier), err))
+	}
+}
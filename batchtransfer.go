@@ -0,0 +1,157 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+BatchTransfer starts all the transfers of `items`, at most `concurrency` of them at the same time.
+it returns at once, status of each transfer can be queried by GetBatchTransfer with the id of the batch.
+*/
+func (r *RaidenAPI) BatchTransfer(items []*models.BatchTransferItem, concurrency int) (b *models.BatchTransfer, err error) {
+	if len(items) == 0 || len(items) > params.MaxBatchTransferItems {
+		err = fmt.Errorf("a batch must have 1 to %d transfers", params.MaxBatchTransferItems)
+		return
+	}
+	if concurrency <= 0 {
+		concurrency = params.DefaultBatchTransferConcurrency
+	}
+	if concurrency > params.MaxBatchTransferConcurrency {
+		err = fmt.Errorf("concurrency must not exceed %d", params.MaxBatchTransferConcurrency)
+		return
+	}
+	for i, item := range items {
+		if !r.hasToken(item.Token) {
+			err = fmt.Errorf("transfer %d: token not exist", i)
+			return
+		}
+		if item.Target == r.Raiden.NodeAddress {
+			err = fmt.Errorf("transfer %d: cannot transfer to myself", i)
+			return
+		}
+		if item.Amount == nil || item.Amount.Cmp(utils.BigInt0) <= 0 {
+			err = fmt.Errorf("transfer %d: %s", i, rerr.ErrInvalidAmount)
+			return
+		}
+		if item.Fee == nil {
+			item.Fee = utils.BigInt0
+		}
+		if item.Fee.Cmp(utils.BigInt0) < 0 {
+			err = fmt.Errorf("transfer %d: invalid fee", i)
+			return
+		}
+	}
+	running := &models.BatchTransfer{
+		ID:          utils.RandomString(16),
+		Concurrency: concurrency,
+		Items:       items,
+	}
+	err = r.Raiden.db.NewBatchTransfer(running)
+	if err != nil {
+		return
+	}
+	//the caller gets its own copy, the running one is changed by transfers
+	b, err = r.Raiden.db.GetBatchTransfer(running.ID)
+	if err != nil {
+		return
+	}
+	go r.runBatchTransfer(running)
+	return
+}
+
+/*
+runBatchTransfer sends transfers of `b` through the api request pipeline, at most `b.Concurrency` of them at the same time,
+and saves result of each transfer.
+*/
+func (r *RaidenAPI) runBatchTransfer(b *models.BatchTransfer) {
+	var lock sync.Mutex
+	update := func(item *models.BatchTransferItem, status string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		item.Status = status
+		if err != nil {
+			item.Error = err.Error()
+		}
+		err = r.Raiden.db.UpdateBatchTransfer(b)
+		if err != nil {
+			log.Error(fmt.Sprintf("UpdateBatchTransfer %s err %s", b.ID, err))
+		}
+	}
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, b.Concurrency)
+	for _, item := range b.Items {
+		sem <- struct{}{}
+		if r.Raiden.StopCreateNewTransfers {
+			<-sem
+			update(item, models.BatchTransferItemFailed, rerr.ErrStopCreateNewTransfer)
+			continue
+		}
+		update(item, models.BatchTransferItemPending, nil)
+		wg.Add(1)
+		go func(item *models.BatchTransferItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result, err := r.transferAsync(item.Token, item.Amount, item.Fee, item.Target, utils.EmptyHash, false, item.Identifier, "")
+			if err == nil {
+				if lockSecretHash, ok := result.Tag.(common.Hash); ok {
+					lock.Lock()
+					item.LockSecretHash = lockSecretHash
+					lock.Unlock()
+				}
+				err = <-result.Result
+			}
+			if err != nil {
+				update(item, models.BatchTransferItemFailed, err)
+				return
+			}
+			update(item, models.BatchTransferItemSuccess, nil)
+		}(item)
+	}
+	wg.Wait()
+	lock.Lock()
+	b.Status = models.BatchTransferStatusFinished
+	b.FinishedAt = time.Now().Unix()
+	err := r.Raiden.db.UpdateBatchTransfer(b)
+	lock.Unlock()
+	if err != nil {
+		log.Error(fmt.Sprintf("UpdateBatchTransfer %s err %s", b.ID, err))
+	}
+	log.Info(fmt.Sprintf("batch transfer %s finished, succeeded=%d failed=%d", b.ID, b.Succeeded, b.Failed))
+}
+
+//interruptBatchTransfers finishes batches interrupted by a restart, otherwise they would be running forever
+func (rs *RaidenService) interruptBatchTransfers() {
+	bs, err := rs.db.InterruptRunningBatchTransfers()
+	if err != nil {
+		log.Error(fmt.Sprintf("InterruptRunningBatchTransfers err %s", err))
+	}
+	for _, b := range bs {
+		log.Warn(fmt.Sprintf("batch transfer %s interrupted, succeeded=%d failed=%d interrupted=%d", b.ID, b.Succeeded, b.Failed, b.Interrupted))
+	}
+}
+
+//GetBatchTransfer returns the batch with `id`, with status of each transfer and totals
+func (r *RaidenAPI) GetBatchTransfer(id string) (b *models.BatchTransfer, err error) {
+	b, err = r.Raiden.db.GetBatchTransfer(id)
+	if err != nil {
+		err = errors.New("batch transfer not found")
+	}
+	return
+}
+
+//GetBatchTransfers returns all the batches
+func (r *RaidenAPI) GetBatchTransfers() (bs []*models.BatchTransfer, err error) {
+	return r.Raiden.db.GetBatchTransfers()
+}
@@ -0,0 +1,321 @@
+package smartraiden
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//off-chain events of a channel recorded by this node
+const (
+	ChannelEventTransferSent     = "TransferSent"
+	ChannelEventTransferReceived = "TransferReceived"
+)
+
+/*
+ChannelEvent is an on-chain event of a channel or a transfer this node sent/received on the channel.
+fields not used by an event are omitted:
+
+	ChannelOpened: Participant,Partner,SettleTimeout
+	ChannelOpenedAndDeposit: Participant,Partner,SettleTimeout,Amount(deposit of Participant)
+	ChannelNewDeposit: Participant,Amount(total deposit)
+	ChannelWithdraw: Participant,Amount(balance of Participant),Partner,PartnerAmount(balance of Partner)
+	ChannelClosed: Participant(closing participant),Amount(transferred amount),Locksroot
+	BalanceProofUpdated: Participant,Amount(transferred amount),Locksroot
+	ChannelUnlocked: Participant(payer),Amount(transferred amount),LockHash
+	ChannelPunished: Participant(beneficiary)
+	ChannelSettled,ChannelCooperativeSettled: Amount(amount of participant1),PartnerAmount(amount of participant2)
+	TransferSent,TransferReceived: Partner,Amount,Nonce,Identifier,Memo
+*/
+type ChannelEvent struct {
+	EventType         string          `json:"event_type"`
+	ChannelIdentifier common.Hash     `json:"channel_identifier"`
+	BlockNumber       int64           `json:"block_number"`
+	TxHash            *common.Hash    `json:"tx_hash,omitempty"` //empty for off-chain events
+	LogIndex          uint            `json:"log_index"`
+	Participant       *common.Address `json:"participant,omitempty"`
+	Partner           *common.Address `json:"partner,omitempty"`
+	Amount            *big.Int        `json:"amount,omitempty"`
+	PartnerAmount     *big.Int        `json:"partner_amount,omitempty"`
+	Locksroot         *common.Hash    `json:"locksroot,omitempty"`
+	LockHash          *common.Hash    `json:"lock_hash,omitempty"`
+	SettleTimeout     uint64          `json:"settle_timeout,omitempty"`
+	Nonce             uint64          `json:"nonce,omitempty"`
+	Identifier        uint64          `json:"identifier,omitempty"`
+	Memo              string          `json:"memo,omitempty"`
+}
+
+func newChannelEvent(eventType string, channelIdentifier [32]byte, raw *types.Log) *ChannelEvent {
+	txHash := raw.TxHash
+	return &ChannelEvent{
+		EventType:         eventType,
+		ChannelIdentifier: common.Hash(channelIdentifier),
+		BlockNumber:       int64(raw.BlockNumber),
+		TxHash:            &txHash,
+		LogIndex:          raw.Index,
+	}
+}
+
+func addressPtr(addr common.Address) *common.Address {
+	return &addr
+}
+
+func hashPtr(h [32]byte) *common.Hash {
+	h2 := common.Hash(h)
+	return &h2
+}
+
+/*
+sortChannelEvents sort events by block number and log index,
+off-chain events are after on-chain events of the same block.
+*/
+func sortChannelEvents(events []*ChannelEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		ei, ej := events[i], events[j]
+		if ei.BlockNumber != ej.BlockNumber {
+			return ei.BlockNumber < ej.BlockNumber
+		}
+		if (ei.TxHash == nil) != (ej.TxHash == nil) {
+			return ej.TxHash == nil
+		}
+		return ei.LogIndex < ej.LogIndex
+	})
+}
+
+/*
+getChannelChainEvents returns all the events of channel `channelIdentifier` on token network `tokenNetwork`
+between `fromBlock` and `toBlock`.
+*/
+func (r *RaidenAPI) getChannelChainEvents(tokenNetwork common.Address, channelIdentifier common.Hash, fromBlock, toBlock int64) (events []*ChannelEvent, err error) {
+	be := r.Raiden.BlockChainEvents
+	in := func(id [32]byte, raw *types.Log) bool {
+		return common.Hash(id) == channelIdentifier && int64(raw.BlockNumber) <= toBlock
+	}
+	opened, err := be.GetChannelNew(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range opened {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelOpened, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.Participant1)
+			ev.Partner = addressPtr(e.Participant2)
+			ev.SettleTimeout = e.SettleTimeout
+			events = append(events, ev)
+		}
+	}
+	openedAndDeposit, err := be.GetChannelNewAndDeposit(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range openedAndDeposit {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelOpenedAndDeposit, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.Participant1)
+			ev.Partner = addressPtr(e.Participant2)
+			ev.SettleTimeout = e.SettleTimeout
+			ev.Amount = e.Participant1Deposit
+			events = append(events, ev)
+		}
+	}
+	deposits, err := be.GetChannelNewDeposit(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range deposits {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelNewDeposit, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.Participant)
+			ev.Amount = e.TotalDeposit
+			events = append(events, ev)
+		}
+	}
+	withdraws, err := be.GetChannelWithdraw(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range withdraws {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelWithdraw, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.Participant1)
+			ev.Amount = e.Participant1Balance
+			ev.Partner = addressPtr(e.Participant2)
+			ev.PartnerAmount = e.Participant2Balance
+			events = append(events, ev)
+		}
+	}
+	closed, err := be.GetChannelClosed(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range closed {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelClosed, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.ClosingParticipant)
+			ev.Amount = e.TransferredAmount
+			ev.Locksroot = hashPtr(e.Locksroot)
+			events = append(events, ev)
+		}
+	}
+	updated, err := be.GetChannelBalanceProofUpdated(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range updated {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameBalanceProofUpdated, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.Participant)
+			ev.Amount = e.TransferredAmount
+			ev.Locksroot = hashPtr(e.Locksroot)
+			events = append(events, ev)
+		}
+	}
+	unlocked, err := be.GetChannelUnlocked(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range unlocked {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelUnlocked, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.PayerParticipant)
+			ev.Amount = e.TransferredAmount
+			ev.LockHash = hashPtr(e.Lockhash)
+			events = append(events, ev)
+		}
+	}
+	punished, err := be.GetChannelPunished(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range punished {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelPunished, e.ChannelIdentifier, &e.Raw)
+			ev.Participant = addressPtr(e.Beneficiary)
+			events = append(events, ev)
+		}
+	}
+	settled, err := be.GetChannelSettled(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range settled {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelSettled, e.ChannelIdentifier, &e.Raw)
+			ev.Amount = e.Participant1Amount
+			ev.PartnerAmount = e.Participant2Amount
+			events = append(events, ev)
+		}
+	}
+	cooperativeSettled, err := be.GetChannelCooperativeSettled(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, e := range cooperativeSettled {
+		if in(e.ChannelIdentifier, &e.Raw) {
+			ev := newChannelEvent(params.NameChannelCooperativeSettled, e.ChannelIdentifier, &e.Raw)
+			ev.Amount = e.Participant1Amount
+			ev.PartnerAmount = e.Participant2Amount
+			events = append(events, ev)
+		}
+	}
+	return
+}
+
+/*
+getChannelTransferEvents returns the transfers this node sent and received on channel `channelIdentifier`
+between `fromBlock` and `toBlock`.
+*/
+func (r *RaidenAPI) getChannelTransferEvents(channelIdentifier common.Hash, fromBlock, toBlock int64) (events []*ChannelEvent, err error) {
+	sents, err := r.Raiden.db.GetSentTransferInBlockRange(fromBlock, toBlock)
+	if err != nil {
+		return
+	}
+	for _, s := range sents {
+		if s.ChannelIdentifier != channelIdentifier {
+			continue
+		}
+		events = append(events, &ChannelEvent{
+			EventType:         ChannelEventTransferSent,
+			ChannelIdentifier: s.ChannelIdentifier,
+			BlockNumber:       s.BlockNumber,
+			Partner:           addressPtr(s.ToAddress),
+			Amount:            s.Amount,
+			Nonce:             s.Nonce,
+			Identifier:        s.Identifier,
+			Memo:              s.Memo,
+		})
+	}
+	receiveds, err := r.Raiden.db.GetReceivedTransferInBlockRange(fromBlock, toBlock)
+	if err != nil {
+		return
+	}
+	for _, s := range receiveds {
+		if s.ChannelIdentifier != channelIdentifier {
+			continue
+		}
+		events = append(events, &ChannelEvent{
+			EventType:         ChannelEventTransferReceived,
+			ChannelIdentifier: s.ChannelIdentifier,
+			BlockNumber:       s.BlockNumber,
+			Partner:           addressPtr(s.FromAddress),
+			Amount:            s.Amount,
+			Nonce:             s.Nonce,
+			Identifier:        s.Identifier,
+			Memo:              s.Memo,
+		})
+	}
+	return
+}
+
+/*
+GetChannelEvents returns events of channel `channelIdentifier` between `fromBlock` and `toBlock` ordered by block number.
+`fromBlock` < 0 means from the block this channel was opened, `toBlock` < 0 means to the latest block.
+if `withTransfers` is true, transfers this node sent and received on this channel are included.
+*/
+func (r *RaidenAPI) GetChannelEvents(channelIdentifier common.Hash, fromBlock, toBlock int64, withTransfers bool) (events []*ChannelEvent, err error) {
+	if toBlock < 0 {
+		toBlock = math.MaxInt64
+	}
+	var tokenNetworks []common.Address
+	c, err := r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err == nil {
+		tokenNetworks = append(tokenNetworks, r.Raiden.Token2TokenNetwork[c.TokenAddress()])
+		if fromBlock < 0 {
+			fromBlock = c.ChannelIdentifier.OpenBlockNumber
+		}
+	} else {
+		//channel may be settled and removed from db, have to search all the token networks
+		log.Trace(fmt.Sprintf("GetChannelEvents channel %s not found in db, search all token networks", channelIdentifier.String()))
+		for _, tn := range r.Raiden.Token2TokenNetwork {
+			tokenNetworks = append(tokenNetworks, tn)
+		}
+	}
+	err = nil
+	if fromBlock < 0 {
+		fromBlock = 0
+	}
+	for _, tn := range tokenNetworks {
+		var evs []*ChannelEvent
+		evs, err = r.getChannelChainEvents(tn, channelIdentifier, fromBlock, toBlock)
+		if err != nil {
+			return
+		}
+		events = append(events, evs...)
+	}
+	if withTransfers {
+		var evs []*ChannelEvent
+		evs, err = r.getChannelTransferEvents(channelIdentifier, fromBlock, toBlock)
+		if err != nil {
+			return
+		}
+		events = append(events, evs...)
+	}
+	sortChannelEvents(events)
+	return
+}
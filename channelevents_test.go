@@ -0,0 +1,24 @@
+package smartraiden
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSortChannelEvents(t *testing.T) {
+	h := common.Hash{}
+	events := []*ChannelEvent{
+		{EventType: ChannelEventTransferSent, BlockNumber: 5},
+		{EventType: "ChannelClosed", BlockNumber: 7, TxHash: &h, LogIndex: 2},
+		{EventType: "ChannelNewDeposit", BlockNumber: 5, TxHash: &h, LogIndex: 3},
+		{EventType: "ChannelOpened", BlockNumber: 5, TxHash: &h, LogIndex: 1},
+		{EventType: ChannelEventTransferReceived, BlockNumber: 6},
+	}
+	sortChannelEvents(events)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.EventType)
+	}
+	assert(t, []string{"ChannelOpened", "ChannelNewDeposit", ChannelEventTransferSent, ChannelEventTransferReceived, "ChannelClosed"}, types)
+}
@@ -0,0 +1,116 @@
+package mainimpl
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/urfave/cli.v1"
+)
+
+/*
+apiKeyCommand manages api keys of the REST API,
+it works on the db of `--address` under `--datadir`, so the node must be stopped.
+*/
+var apiKeyCommand = cli.Command{
+	Name:  "apikey",
+	Usage: "manage api keys of the REST API, the node must be stopped",
+	Subcommands: []cli.Command{
+		{
+			Name:  "add",
+			Usage: "create an api key, the key is printed only once",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "name of the key",
+				},
+				cli.StringFlag{
+					Name:  "role",
+					Usage: fmt.Sprintf("%s: queries and events, %s: everything", models.APIKeyRoleReadOnly, models.APIKeyRoleOperator),
+					Value: models.APIKeyRoleReadOnly,
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withAPIKeyDb(ctx, func(db *models.ModelDB) error {
+					key, err := db.NewAPIKey(ctx.String("name"), ctx.String("role"))
+					if err != nil {
+						return err
+					}
+					fmt.Printf("api key %s created, role %s\n%s\n", ctx.String("name"), ctx.String("role"), key)
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "list all the api keys",
+			Action: func(ctx *cli.Context) error {
+				return withAPIKeyDb(ctx, func(db *models.ModelDB) error {
+					ks, err := db.GetAPIKeys()
+					if err != nil {
+						return err
+					}
+					for _, k := range ks {
+						fmt.Printf("%s\t%s\t%s\n", k.Name, k.Role, time.Unix(k.CreatedAt, 0).Format(time.RFC3339))
+					}
+					return nil
+				})
+			},
+		},
+		{
+			Name:  "remove",
+			Usage: "remove an api key",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name",
+					Usage: "name of the key",
+				},
+			},
+			Action: func(ctx *cli.Context) error {
+				return withAPIKeyDb(ctx, func(db *models.ModelDB) error {
+					return db.RemoveAPIKey(ctx.String("name"))
+				})
+			},
+		},
+	},
+}
+
+//withAPIKeyDb opens db of the node specified by global flags `--address` and `--datadir`
+func withAPIKeyDb(ctx *cli.Context, fn func(db *models.ModelDB) error) error {
+	addr := ctx.GlobalString("address")
+	if !common.IsHexAddress(addr) {
+		return errors.New("please specify the node by --address")
+	}
+	dataDir := ctx.GlobalString("datadir")
+	if len(dataDir) == 0 {
+		dataDir = path.Join(utils.GetHomePath(), ".smartraiden")
+	}
+	databasePath := dbPath(dataDir, common.HexToAddress(addr))
+	if !utils.Exists(databasePath) {
+		return fmt.Errorf("db %s doesn't exist, start the node once first", databasePath)
+	}
+	db, err := models.OpenDb(databasePath)
+	if err != nil {
+		return err
+	}
+	//don't hide a crash from the node, it will restore on next start
+	if db.IsDbCrashedLastTime() {
+		defer db.CloseDBKeepStatus()
+	} else {
+		defer db.CloseDB()
+	}
+	return fn(db)
+}
+
+//dbPath returns path of the db of `address` under `dataDir`
+func dbPath(dataDir string, address common.Address) string {
+	userDbPath := hex.EncodeToString(address[:])
+	userDbPath = userDbPath[:8]
+	return filepath.Join(dataDir, userDbPath, "log.db")
+}
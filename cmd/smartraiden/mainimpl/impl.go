@@ -103,7 +103,7 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 		},
 		cli.BoolFlag{
 			Name:  "fee",
-			Usage: "enable mediation fee",
+			Usage: "enable mediation fee, rates are set through /api/1/fees",
 		},
 		cli.StringFlag{
 			Name:  "xmpp-server",
@@ -118,6 +118,40 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 			Name:  "enable-health-check",
 			Usage: "enable health check ",
 		},
+		cli.BoolFlag{
+			Name:  "auto-settle",
+			Usage: "settle closed channels automatically once settle timeout expires",
+		},
+		cli.DurationFlag{
+			Name:  "reap-idle-channels",
+			Usage: "cooperative settle channels without transfers for this long, for example 720h",
+		},
+		cli.DurationFlag{
+			Name:  "reap-close-after",
+			Usage: "close an idle channel if its partner is unreachable for this long",
+			Value: params.DefaultReapCloseTimeout,
+		},
+		cli.BoolFlag{
+			Name:  "reap-dry-run",
+			Usage: "only report idle channels, never settle them",
+		},
+		cli.StringFlag{
+			Name:  "routing-mode",
+			Usage: "how routes are preferred: fee, capacity (highest bottleneck capacity) or combined (least fee through channels large enough)",
+			Value: params.RoutingModeFee,
+		},
+		cli.StringFlag{
+			Name:  "webhook",
+			Usage: "url to post events like received transfer, channel closed by partner, deposit and withdraw",
+		},
+		cli.StringFlag{
+			Name:  "webhook-secret",
+			Usage: "secret to sign webhook payload with HMAC-SHA256",
+		},
+		cli.BoolFlag{
+			Name:  "debug-api",
+			Usage: "enable /api/1/debug/ routes, which can move tokens and force unlock",
+		},
 		cli.StringFlag{
 			Name:  "matrix-server",
 			Usage: "use another matrix server",
@@ -129,6 +163,7 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 		},
 	}
 	app.Flags = append(app.Flags, debug.Flags...)
+	app.Commands = []cli.Command{apiKeyCommand}
 	app.Action = mainCtx
 	app.Name = "smartraiden"
 	app.Version = "0.8"
@@ -179,7 +214,7 @@ func mainCtx(ctx *cli.Context) (err error) {
 		return
 	}
 	if cfg.EnableMediationFee {
-		//do nothing.
+		//rates are loaded from db, and can be changed through /api/1/fees
 	} else {
 		raidenService.SetFeePolicy(&smartraiden.NoFeePolicy{})
 	}
@@ -297,9 +332,8 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 			return
 		}
 	}
-	userDbPath := hex.EncodeToString(config.MyAddress[:])
-	userDbPath = userDbPath[:8]
-	userDbPath = filepath.Join(config.DataDir, userDbPath)
+	databasePath := dbPath(config.DataDir, config.MyAddress)
+	userDbPath := filepath.Dir(databasePath)
 	if !utils.Exists(userDbPath) {
 		err = os.MkdirAll(userDbPath, os.ModePerm)
 		if err != nil {
@@ -307,7 +341,6 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 			return
 		}
 	}
-	databasePath := filepath.Join(userDbPath, "log.db")
 	config.DataBasePath = databasePath
 	if ctx.Bool("debugcrash") {
 		config.DebugCrash = true
@@ -333,6 +366,22 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 	if ctx.Bool("enable-health-check") {
 		config.EnableHealthCheck = true
 	}
+	if ctx.Bool("auto-settle") {
+		config.EnableAutoSettle = true
+	}
+	config.ReapIdleTimeout = ctx.Duration("reap-idle-channels")
+	config.ReapCloseTimeout = ctx.Duration("reap-close-after")
+	config.ReapDryRun = ctx.Bool("reap-dry-run")
+	config.RoutingMode = ctx.String("routing-mode")
+	switch config.RoutingMode {
+	case params.RoutingModeFee, params.RoutingModeCapacity, params.RoutingModeCombined:
+	default:
+		err = fmt.Errorf("unknown routing mode %s", config.RoutingMode)
+		return
+	}
+	config.WebhookURL = ctx.String("webhook")
+	config.WebhookSecret = ctx.String("webhook-secret")
+	config.EnableDebugAPI = ctx.Bool("debug-api")
 	config.XMPPServer = ctx.String("xmpp-server")
 	if len(ctx.String("matrix-server")) > 0 {
 		s := ctx.String("matrix-server")
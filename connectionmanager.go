@@ -0,0 +1,303 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+ConnectionManager joins a token network automatically for the user.
+it opens `InitialChannelTarget` channels with nodes found in the token's ChannelGraph,
+and keeps `JoinableFundsTarget` of the funds to deposit into channels others open to us.
+ConnectionManager is thread safe, it works outside the loop of RaidenService.
+*/
+type ConnectionManager struct {
+	lock                 sync.Mutex
+	api                  *RaidenAPI
+	TokenAddress         common.Address
+	Funds                *big.Int //funds from last connect request
+	InitialChannelTarget int
+	JoinableFundsTarget  float64
+	joinableFunds        *big.Int //funds left for channels opened by partners
+	leaving              bool
+}
+
+func newConnectionManager(api *RaidenAPI, tokenAddress common.Address) *ConnectionManager {
+	cm := &ConnectionManager{
+		api:                  api,
+		TokenAddress:         tokenAddress,
+		Funds:                big.NewInt(0),
+		InitialChannelTarget: params.DefaultInitialChannelTarget,
+		JoinableFundsTarget:  params.DefaultJoinableFundsTarget,
+		joinableFunds:        big.NewInt(0),
+	}
+	return cm
+}
+
+//fundsFraction returns funds*fraction
+func fundsFraction(funds *big.Int, fraction float64) *big.Int {
+	f := new(big.Float).SetInt(funds)
+	f.Mul(f, big.NewFloat(fraction))
+	r, _ := f.Int(nil)
+	return r
+}
+
+/*
+connect opens channels with the funds, the funds is split as:
+1. JoinableFundsTarget of funds is kept for channels opened by others.
+2. the rest is divided equally among the channels we open.
+return when all the channels have been opened and deposited.
+*/
+func (cm *ConnectionManager) connect(funds *big.Int) (err error) {
+	chs, err := cm.api.GetChannelList(cm.TokenAddress, utils.EmptyAddress)
+	if err != nil {
+		return
+	}
+	existPartners := make(map[common.Address]bool)
+	for _, c := range chs {
+		if c.State == channeltype.StateOpened {
+			existPartners[c.PartnerAddress()] = true
+		}
+	}
+	joinableFunds := fundsFraction(funds, cm.JoinableFundsTarget)
+	cm.lock.Lock()
+	cm.Funds = new(big.Int).Set(funds)
+	cm.joinableFunds = new(big.Int).Set(joinableFunds)
+	need := cm.InitialChannelTarget - len(existPartners)
+	cm.lock.Unlock()
+	if need <= 0 {
+		log.Info(fmt.Sprintf("connection manager %s already has %d channels,no need to open new channel",
+			utils.APex2(cm.TokenAddress), len(existPartners)))
+		return nil
+	}
+	fundsPerChannel := new(big.Int).Sub(funds, joinableFunds)
+	fundsPerChannel.Div(fundsPerChannel, big.NewInt(int64(need)))
+	partners := cm.findPartners(existPartners)
+	if len(partners) == 0 {
+		log.Info(fmt.Sprintf("connection manager %s no partner found, wait for others to open channel with me",
+			utils.APex2(cm.TokenAddress)))
+		return nil
+	}
+	opened := 0
+	for _, partner := range partners {
+		if opened >= need {
+			break
+		}
+		_, err = cm.api.Open(cm.TokenAddress, partner, 0, 0, fundsPerChannel)
+		if err != nil {
+			log.Error(fmt.Sprintf("connection manager open channel with %s err %s", utils.APex2(partner), err))
+			continue
+		}
+		opened++
+	}
+	if opened > 0 {
+		err = nil
+	}
+	return
+}
+
+/*
+findPartners returns all nodes in the channel graph we have no channel with,
+online nodes first, the others are in random order.
+*/
+func (cm *ConnectionManager) findPartners(existPartners map[common.Address]bool) (partners []common.Address) {
+	g := cm.api.Raiden.getToken2ChannelGraph(cm.TokenAddress)
+	if g == nil {
+		return
+	}
+	var offline []common.Address
+	nodes := g.AllNodes()
+	for _, i := range rand.Perm(len(nodes)) {
+		n := nodes[i]
+		if n == cm.api.Raiden.NodeAddress || existPartners[n] {
+			continue
+		}
+		if _, isOnline := cm.api.GetNodeNetworkState(n); isOnline {
+			partners = append(partners, n)
+		} else {
+			offline = append(offline, n)
+		}
+	}
+	return append(partners, offline...)
+}
+
+/*
+onPartnerOpenedChannel deposits into the channel `partner` opened with me with the joinable funds,
+it's called in the main loop, so deposit in a new goroutine.
+*/
+func (cm *ConnectionManager) onPartnerOpenedChannel(partner common.Address) {
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	if cm.leaving {
+		return
+	}
+	amount := new(big.Int).Div(fundsFraction(cm.Funds, cm.JoinableFundsTarget), big.NewInt(int64(cm.InitialChannelTarget)))
+	if amount.Cmp(cm.joinableFunds) > 0 {
+		amount = new(big.Int).Set(cm.joinableFunds)
+	}
+	if amount.Cmp(utils.BigInt0) <= 0 {
+		return
+	}
+	cm.joinableFunds.Sub(cm.joinableFunds, amount)
+	go func() {
+		defer rpanic.PanicRecover(fmt.Sprintf("connection manager join %s", utils.APex2(partner)))
+		_, err := cm.api.Deposit(cm.TokenAddress, partner, amount, params.DefaultPollTimeout)
+		if err != nil {
+			log.Error(fmt.Sprintf("connection manager join channel with %s err %s", utils.APex2(partner), err))
+			cm.lock.Lock()
+			cm.joinableFunds.Add(cm.joinableFunds, amount)
+			cm.lock.Unlock()
+		}
+	}()
+}
+
+/*
+joinChannelOpenedByPartner lets the connection manager of `tokenAddress` join the channel `partner` opened with me,
+channels opened by myself, by the connection manager or by hand, are never joined.
+must be called in the main loop.
+*/
+func (rs *RaidenService) joinChannelOpenedByPartner(tokenAddress, partner common.Address) {
+	rs.connectionManagerLock.Lock()
+	cm := rs.Token2ConnectionManager[tokenAddress]
+	rs.connectionManagerLock.Unlock()
+	if cm != nil {
+		cm.onPartnerOpenedChannel(partner)
+	}
+}
+
+//leave stop joining channels opened by others
+func (cm *ConnectionManager) leave() {
+	cm.lock.Lock()
+	cm.leaving = true
+	cm.lock.Unlock()
+}
+
+//ConnectionInfo details of a joined token network
+type ConnectionInfo struct {
+	Funds       *big.Int `json:"funds"`
+	SumDeposits *big.Int `json:"sum_deposits"`
+	Channels    int      `json:"channels"`
+}
+
+/*
+Connect automatically joins the token network of `tokenAddress` with `funds`.
+*/
+func (r *RaidenAPI) Connect(tokenAddress common.Address, funds *big.Int) (err error) {
+	if funds == nil || funds.Cmp(utils.BigInt0) <= 0 {
+		return rerr.ErrInvalidAmount
+	}
+	if _, ok := r.Raiden.Token2TokenNetwork[tokenAddress]; !ok {
+		return errors.New("token not exist")
+	}
+	if r.Raiden.Registry == nil {
+		return errEthConnectionNotReady
+	}
+	token, err := r.Raiden.Chain.Token(tokenAddress)
+	if err != nil {
+		return
+	}
+	balance, err := token.BalanceOf(r.Raiden.NodeAddress)
+	if err != nil {
+		return
+	}
+	if balance.Cmp(funds) < 0 {
+		return fmt.Errorf("not enough balance to connect. %s Available=%d Tried=%d", tokenAddress.String(), balance, funds)
+	}
+	r.Raiden.connectionManagerLock.Lock()
+	cm := r.Raiden.Token2ConnectionManager[tokenAddress]
+	if cm == nil {
+		cm = newConnectionManager(r, tokenAddress)
+		r.Raiden.Token2ConnectionManager[tokenAddress] = cm
+	}
+	r.Raiden.connectionManagerLock.Unlock()
+	return cm.connect(funds)
+}
+
+/*
+Leave leaves the token network of `tokenAddress`,
+every channel is cooperative settled, if cooperative settle is not possible, the channel is closed.
+onlyReceivingChannels: only leave channels where we have received transfers.
+*/
+func (r *RaidenAPI) Leave(tokenAddress common.Address, onlyReceivingChannels bool) (chs []*channeltype.Serialization, err error) {
+	r.Raiden.connectionManagerLock.Lock()
+	cm := r.Raiden.Token2ConnectionManager[tokenAddress]
+	if cm != nil {
+		cm.leave()
+		delete(r.Raiden.Token2ConnectionManager, tokenAddress)
+	}
+	r.Raiden.connectionManagerLock.Unlock()
+	channels, err := r.GetChannelList(tokenAddress, utils.EmptyAddress)
+	if err != nil {
+		return
+	}
+	var failed int
+	for _, c := range channels {
+		if c.State != channeltype.StateOpened && c.State != channeltype.StatePrepareForCooperativeSettle {
+			continue
+		}
+		if onlyReceivingChannels && (c.PartnerBalanceProof == nil || c.PartnerBalanceProof.TransferAmount == nil ||
+			c.PartnerBalanceProof.TransferAmount.Cmp(utils.BigInt0) <= 0) {
+			continue
+		}
+		c2, err2 := r.CooperativeSettle(tokenAddress, c.PartnerAddress())
+		if err2 != nil {
+			log.Info(fmt.Sprintf("leave %s,cooperative settle err %s, try to close", c.ChannelIdentifier.String(), err2))
+			c2, err2 = r.Close(tokenAddress, c.PartnerAddress())
+		}
+		if err2 != nil {
+			log.Error(fmt.Sprintf("leave %s, close err %s", c.ChannelIdentifier.String(), err2))
+			failed++
+			err = err2
+			continue
+		}
+		chs = append(chs, c2)
+	}
+	if failed > 0 {
+		err = fmt.Errorf("%d channels cannot leave, last err %s", failed, err)
+	}
+	return
+}
+
+//GetConnections returns details of all joined token networks
+func (r *RaidenAPI) GetConnections() (infos map[common.Address]*ConnectionInfo, err error) {
+	infos = make(map[common.Address]*ConnectionInfo)
+	r.Raiden.connectionManagerLock.Lock()
+	funds := make(map[common.Address]*big.Int)
+	for t, cm := range r.Raiden.Token2ConnectionManager {
+		cm.lock.Lock()
+		funds[t] = new(big.Int).Set(cm.Funds)
+		cm.lock.Unlock()
+	}
+	r.Raiden.connectionManagerLock.Unlock()
+	for t, f := range funds {
+		info := &ConnectionInfo{
+			Funds:       f,
+			SumDeposits: big.NewInt(0),
+		}
+		var chs []*channeltype.Serialization
+		chs, err = r.GetChannelList(t, utils.EmptyAddress)
+		if err != nil {
+			return
+		}
+		for _, c := range chs {
+			if c.State != channeltype.StateOpened {
+				continue
+			}
+			info.Channels++
+			info.SumDeposits.Add(info.SumDeposits, c.OurContractBalance)
+		}
+		infos[t] = info
+	}
+	return
+}
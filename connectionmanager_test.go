@@ -0,0 +1,25 @@
+package smartraiden
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFundsFraction(t *testing.T) {
+	cases := []struct {
+		funds    int64
+		fraction float64
+		expect   int64
+	}{
+		{100, 0.4, 40},
+		{10, 0.4, 4},
+		{3, 0.5, 1},
+		{0, 0.4, 0},
+	}
+	for _, c := range cases {
+		r := fundsFraction(big.NewInt(c.funds), c.fraction)
+		if r.Cmp(big.NewInt(c.expect)) != 0 {
+			t.Errorf("fundsFraction(%d,%f) expect %d,got %s", c.funds, c.fraction, c.expect, r)
+		}
+	}
+}
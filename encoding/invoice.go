@@ -0,0 +1,153 @@
+package encoding
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//InvoicePrefix is the prefix of an encoded invoice
+const InvoicePrefix = "smartraiden:"
+
+const invoiceVersion = 1
+
+/*
+Invoice is a payment request signed by the target,
+it's encoded as a single string, so it can be shown as a QR code.
+*/
+type Invoice struct {
+	ChainID        *big.Int
+	Target         common.Address //who wants to be paid, also the signer
+	Token          common.Address
+	Amount         *big.Int
+	LockSecretHash common.Hash //optional, the target keeps the secret and registers it when the transfer arrives
+	Identifier     uint64      //payment identifier the payer must use
+	Expiration     int64       //unix time, after which the invoice should not be paid
+	Memo           string
+	Signature      []byte
+}
+
+//dataToSign is the packed invoice without signature
+func (i *Invoice) dataToSign() []byte {
+	var err error
+	memo := []byte(i.Memo)
+	if len(memo) > params.MaxMemoLength {
+		memo = memo[:params.MaxMemoLength]
+	}
+	buf := new(bytes.Buffer)
+	err = buf.WriteByte(invoiceVersion)
+	_, err = buf.Write(utils.BigIntTo32Bytes(i.ChainID))
+	_, err = buf.Write(i.Target[:])
+	_, err = buf.Write(i.Token[:])
+	_, err = buf.Write(utils.BigIntTo32Bytes(i.Amount))
+	_, err = buf.Write(i.LockSecretHash[:])
+	err = binary.Write(buf, binary.BigEndian, i.Identifier)
+	err = binary.Write(buf, binary.BigEndian, i.Expiration)
+	err = buf.WriteByte(byte(len(memo)))
+	_, err = buf.Write(memo)
+	if err != nil {
+		panic(fmt.Sprintf("Invoice dataToSign err %s", err))
+	}
+	return buf.Bytes()
+}
+
+//Hash of this invoice, signature is not included
+func (i *Invoice) Hash() common.Hash {
+	return utils.Sha3(i.dataToSign())
+}
+
+//Sign sign this invoice, `Target` is set to the address of `key`
+func (i *Invoice) Sign(key *ecdsa.PrivateKey) (err error) {
+	i.Target = crypto.PubkeyToAddress(key.PublicKey)
+	i.Signature, err = utils.SignData(key, i.dataToSign())
+	return
+}
+
+//Encode returns the string form of a signed invoice
+func (i *Invoice) Encode() string {
+	data := append(i.dataToSign(), i.Signature...)
+	return InvoicePrefix + base64.RawURLEncoding.EncodeToString(data)
+}
+
+//IsExpired returns true if this invoice expired at `now`
+func (i *Invoice) IsExpired(now time.Time) bool {
+	return i.Expiration > 0 && now.Unix() > i.Expiration
+}
+
+/*
+DecodeInvoice decodes an invoice and verifies it's signed by its target.
+the chain id and expiration are not checked here.
+*/
+func DecodeInvoice(s string) (i *Invoice, err error) {
+	if !strings.HasPrefix(s, InvoicePrefix) {
+		err = errors.New("invoice must start with " + InvoicePrefix)
+		return
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s[len(InvoicePrefix):])
+	if err != nil {
+		return
+	}
+	if len(data) <= signatureLength {
+		err = fmt.Errorf("invoice length error %d", len(data))
+		return
+	}
+	buf := bytes.NewBuffer(data[:len(data)-signatureLength])
+	version, err := buf.ReadByte()
+	if err != nil {
+		return
+	}
+	if version != invoiceVersion {
+		err = fmt.Errorf("unknown invoice version %d", version)
+		return
+	}
+	i = new(Invoice)
+	var chainID, amount [32]byte
+	//a truncated invoice must not decode into zero addresses
+	for _, field := range [][]byte{chainID[:], i.Target[:], i.Token[:], amount[:], i.LockSecretHash[:]} {
+		_, err = io.ReadFull(buf, field)
+		if err != nil {
+			return
+		}
+	}
+	i.ChainID = new(big.Int).SetBytes(chainID[:])
+	i.Amount = new(big.Int).SetBytes(amount[:])
+	err = binary.Read(buf, binary.BigEndian, &i.Identifier)
+	if err != nil {
+		return
+	}
+	err = binary.Read(buf, binary.BigEndian, &i.Expiration)
+	if err != nil {
+		return
+	}
+	l, err := buf.ReadByte()
+	if err != nil {
+		return
+	}
+	if int(l) > params.MaxMemoLength || int(l) != buf.Len() {
+		err = fmt.Errorf("invoice memo length error %d", l)
+		return
+	}
+	i.Memo = string(buf.Bytes())
+	i.Signature = make([]byte, signatureLength)
+	copy(i.Signature, data[len(data)-signatureLength:])
+	signer, err := utils.Ecrecover(utils.Sha3(data[:len(data)-signatureLength]), i.Signature)
+	if err != nil {
+		return
+	}
+	if signer != i.Target {
+		err = fmt.Errorf("invoice signature error, target=%s,signer=%s", utils.APex2(i.Target), utils.APex2(signer))
+	}
+	return
+}
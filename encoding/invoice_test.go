@@ -0,0 +1,72 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoice(t *testing.T) {
+	i := &Invoice{
+		ChainID:        big.NewInt(8888),
+		Token:          utils.NewRandomAddress(),
+		Amount:         big.NewInt(300),
+		LockSecretHash: utils.NewRandomHash(),
+		Identifier:     42,
+		Expiration:     time.Now().Add(time.Hour).Unix(),
+		Memo:           "order 42",
+	}
+	err := i.Sign(GetTestPrivKey())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, GetTestAddress(), i.Target)
+	s := i.Encode()
+	i2, err := DecodeInvoice(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, i, i2)
+	assert.EqualValues(t, i.Hash(), i2.Hash())
+	assert.EqualValues(t, false, i2.IsExpired(time.Now()))
+	assert.EqualValues(t, true, i2.IsExpired(time.Now().Add(2*time.Hour)))
+
+	//no optional fields
+	i3 := &Invoice{
+		ChainID: big.NewInt(8888),
+		Token:   utils.NewRandomAddress(),
+		Amount:  big.NewInt(1),
+	}
+	err = i3.Sign(GetTestPrivKey())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	i4, err := DecodeInvoice(i3.Encode())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, i3, i4)
+	assert.EqualValues(t, false, i4.IsExpired(time.Now()))
+
+	//tampered amount, the signature doesn't match anymore
+	i.Amount = big.NewInt(3)
+	_, err = DecodeInvoice(i.Encode())
+	assert.NotEqual(t, nil, err)
+	_, err = DecodeInvoice("0x1234")
+	assert.NotEqual(t, nil, err)
+	_, err = DecodeInvoice(InvoicePrefix + "AAAA")
+	assert.NotEqual(t, nil, err)
+	//truncated in the middle of the target
+	data := append([]byte{invoiceVersion}, make([]byte, 32+10+signatureLength)...)
+	_, err = DecodeInvoice(InvoicePrefix + base64.RawURLEncoding.EncodeToString(data))
+	assert.EqualValues(t, io.ErrUnexpectedEOF, err)
+}
@@ -783,6 +783,81 @@ func (m *RemoveExpiredHashlockTransfer) String() string {
 	return fmt.Sprintf("Message{type=RemoveExpiredHashlockTransfer LockSecretHash=%s,%s}", utils.HPex(m.LockSecretHash), m.EnvelopMessage.String())
 }
 
+/*
+PaymentData is the data a transfer carries from the initiator to the target.
+it's signed by the sender of each hop only, every mediator copies it into the transfer it signs itself,
+so any mediator can change it, the target must not trust it more than it trusts the mediators.
+it's packed into DirectTransfer and MediatedTransfer, nodes without PaymentData cannot decode these messages
+from nodes with it and vice versa, so all the nodes of a network must be upgraded together.
+PaymentIdentifier is chosen by the initiator, for example an order number, zero means no identifier.
+Memo is a short note, at most params.MaxMemoLength bytes.
+TotalAmount is only set for a multi-part payment, it's the amount the target should receive from all the parts,
+nil means this transfer is the whole payment.
+*/
+type PaymentData struct {
+	PaymentIdentifier uint64
+	Memo              string
+	TotalAmount       *big.Int
+}
+
+//IsMultiPart returns true if this transfer is one part of a multi-part payment
+func (p *PaymentData) IsMultiPart() bool {
+	return p.TotalAmount != nil && p.TotalAmount.Sign() > 0
+}
+
+func (p *PaymentData) pack(buf *bytes.Buffer) {
+	var err error
+	memo := []byte(p.Memo)
+	if len(memo) > params.MaxMemoLength {
+		memo = memo[:params.MaxMemoLength]
+	}
+	err = binary.Write(buf, binary.BigEndian, p.PaymentIdentifier)
+	err = buf.WriteByte(byte(len(memo)))
+	_, err = buf.Write(memo)
+	var total []byte
+	if p.IsMultiPart() {
+		total = p.TotalAmount.Bytes()
+	}
+	err = buf.WriteByte(byte(len(total)))
+	_, err = buf.Write(total)
+	if err != nil {
+		log.Error(fmt.Sprintf("PaymentData pack err %s", err))
+	}
+}
+
+func (p *PaymentData) unpack(buf *bytes.Buffer) error {
+	err := binary.Read(buf, binary.BigEndian, &p.PaymentIdentifier)
+	if err != nil {
+		return err
+	}
+	l, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	if int(l) > params.MaxMemoLength || int(l) > buf.Len() {
+		return fmt.Errorf("memo length error %d", l)
+	}
+	memo := make([]byte, l)
+	_, err = buf.Read(memo)
+	p.Memo = string(memo)
+	if err != nil {
+		return err
+	}
+	l, err = buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	if int(l) > 32 || int(l) > buf.Len() {
+		return fmt.Errorf("total amount length error %d", l)
+	}
+	if l > 0 {
+		total := make([]byte, l)
+		_, err = buf.Read(total)
+		p.TotalAmount = new(big.Int).SetBytes(total)
+	}
+	return err
+}
+
 /*
 DirectTransfer is a direct token exchange, used when both participants have a previously
 opened channel.
@@ -809,11 +884,12 @@ Args:
 */
 type DirectTransfer struct {
 	EnvelopMessage
+	PaymentData
 }
 
 //String is fmt.Stringer
 func (m *DirectTransfer) String() string {
-	return fmt.Sprintf("Message{type=DirectTransfer %s}", m.EnvelopMessage.String())
+	return fmt.Sprintf("Message{type=DirectTransfer identifier=%d,memo=%s,%s}", m.PaymentIdentifier, m.Memo, m.EnvelopMessage.String())
 }
 
 //NewDirectTransfer create DirectTransfer
@@ -831,6 +907,7 @@ func (m *DirectTransfer) Pack() []byte {
 	if err != nil {
 		log.Crit(fmt.Sprintf("DirectTransfer Pack err %s", err))
 	}
+	m.PaymentData.pack(buf)
 	m.EnvelopMessage.pack(buf)
 	return buf.Bytes()
 }
@@ -844,6 +921,10 @@ func (m *DirectTransfer) UnPack(data []byte) error {
 	if t != m.CmdID {
 		return errors.New("DirectTransfer unpack cmdid error")
 	}
+	err = m.PaymentData.unpack(buf)
+	if err != nil {
+		return err
+	}
 	err = m.EnvelopMessage.unpack(buf)
 	if err != nil {
 		return err
@@ -877,13 +958,14 @@ type MediatedTransfer struct {
 	Target         common.Address
 	Initiator      common.Address
 	Fee            *big.Int
+	PaymentData
 }
 
 //String is fmt.Stringer
 func (m *MediatedTransfer) String() string {
-	return fmt.Sprintf("Message{type=MediatedTransfer expiration=%d,target=%s,initiator=%s,hashlock=%s,amount=%s,fee=%s,%s}",
+	return fmt.Sprintf("Message{type=MediatedTransfer expiration=%d,target=%s,initiator=%s,hashlock=%s,amount=%s,fee=%s,identifier=%d,memo=%s,%s}",
 		m.Expiration, utils.APex2(m.Target), utils.APex2(m.Initiator),
-		utils.HPex(m.LockSecretHash), m.PaymentAmount, m.Fee, m.EnvelopMessage.String())
+		utils.HPex(m.LockSecretHash), m.PaymentAmount, m.Fee, m.PaymentIdentifier, m.Memo, m.EnvelopMessage.String())
 }
 
 //NewMediatedTransfer create MediatedTransfer
@@ -924,6 +1006,7 @@ func (m *MediatedTransfer) Pack() []byte {
 	_, err = buf.Write(m.Target[:])
 	_, err = buf.Write(m.Initiator[:])
 	_, err = buf.Write(utils.BigIntTo32Bytes(m.Fee))
+	m.PaymentData.pack(buf)
 	m.EnvelopMessage.pack(buf)
 	if err != nil {
 		log.Crit(fmt.Sprintf("MediatedTransfer Pack err %s", err))
@@ -949,6 +1032,10 @@ func (m *MediatedTransfer) UnPack(data []byte) error {
 	_, err = buf.Read(m.Target[:])
 	_, err = buf.Read(m.Initiator[:])
 	m.Fee = utils.ReadBigInt(buf)
+	err = m.PaymentData.unpack(buf)
+	if err != nil {
+		return err
+	}
 	err = m.EnvelopMessage.unpack(buf)
 	if err != nil {
 		return err
@@ -187,6 +187,69 @@ func TestMediatedTransfer(t *testing.T) {
 	}
 }
 
+func TestTransferWithPaymentData(t *testing.T) {
+	bp := &BalanceProof{
+		Nonce:             11,
+		ChannelIdentifier: utils.Sha3([]byte("123")),
+		TransferAmount:    big.NewInt(12),
+		OpenBlockNumber:   3,
+		Locksroot:         utils.EmptyHash,
+	}
+	lock := &mtree.Lock{
+		Amount:         big.NewInt(34),
+		Expiration:     4589895, //expiration block number
+		LockSecretHash: utils.ShaSecret([]byte("hashlock")),
+	}
+	m1 := NewMediatedTransfer(bp, lock, utils.NewRandomAddress(), utils.NewRandomAddress(), big.NewInt(33))
+	m1.PaymentIdentifier = 0x1234567890
+	m1.Memo = "order 1234567890"
+	m1.Sign(GetTestPrivKey(), m1)
+	m2 := new(MediatedTransfer)
+	err := m2.UnPack(m1.Pack())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, m1, m2)
+	if m2.IsMultiPart() {
+		t.Error("should not be multi-part")
+	}
+	//one part of a multi-part payment
+	m1.TotalAmount = big.NewInt(100)
+	m1.Signature = nil
+	m1.Sign(GetTestPrivKey(), m1)
+	m2 = new(MediatedTransfer)
+	err = m2.UnPack(m1.Pack())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, m1, m2)
+	if !m2.IsMultiPart() {
+		t.Error("should be multi-part")
+	}
+
+	d1 := NewDirectTransfer(bp)
+	d1.PaymentIdentifier = 3
+	d1.Memo = "直接转账"
+	d1.Sign(GetTestPrivKey(), d1)
+	d2 := new(DirectTransfer)
+	err = d2.UnPack(d1.Pack())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, d1, d2)
+	//memo is signed
+	data := d1.Pack()
+	data[4+8+1] = 'x' //cmdid(4)+identifier(8)+memo length(1)
+	d3 := new(DirectTransfer)
+	err = d3.UnPack(data)
+	if err == nil && d3.Sender == d1.Sender {
+		t.Error("memo changed, but signature is still valid")
+	}
+}
+
 func TestNewAnnounceDisposedTransfer(t *testing.T) {
 	bp := &AnnounceDisposedProof{
 		ChannelIDInMessage: ChannelIDInMessage{
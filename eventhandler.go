@@ -119,6 +119,7 @@ func (eh *stateMachineEventHandler) eventSendMediatedTransfer(event *mediatedtra
 	if err != nil {
 		return
 	}
+	mtr.PaymentData = event.PaymentData
 	err = mtr.Sign(eh.raiden.PrivateKey, mtr)
 	err = ch.RegisterTransfer(eh.raiden.GetBlockNumber(), mtr)
 	if err != nil {
@@ -126,7 +127,7 @@ func (eh *stateMachineEventHandler) eventSendMediatedTransfer(event *mediatedtra
 	}
 	eh.raiden.conditionQuit("EventSendMediatedTransferBefore")
 	if stateManager.LastReceivedMessage == nil {
-		if stateManager.Name != initiator.NameInitiatorTransition {
+		if stateManager.Name != initiator.NameInitiatorTransition && stateManager.Name != initiator.NameMultiPartInitiatorTransition {
 			log.Warn(fmt.Sprintf("EventSendMediatedTransfer %s,but has no lastReceviedMessage", utils.StringInterface(event, 3)))
 		}
 		err = eh.raiden.db.UpdateChannelNoTx(channel.NewChannelSerialization(ch))
@@ -169,6 +170,7 @@ func (eh *stateMachineEventHandler) eventSendUnlock(event *mediatedtransfer.Even
 	if err != nil {
 		return
 	}
+	eh.raiden.MissionControl.ReportSuccess(event.Token, eh.raiden.NodeAddress, receiver)
 	eh.raiden.conditionQuit("EventSendUnlockBefore")
 	err = eh.raiden.db.UpdateChannelNoTx(channel.NewChannelSerialization(ch))
 	err = eh.raiden.sendAsync(receiver, tr)
@@ -253,6 +255,10 @@ func (eh *stateMachineEventHandler) eventContractSendRegisterSecret(event *media
 func (eh *stateMachineEventHandler) eventWithdrawFailed(e2 *mediatedtransfer.EventWithdrawFailed, manager *transfer.StateManager) (err error) {
 	//wait from RemoveExpiredHashlockTransfer from partner.
 	//need do nothing ,just wait.
+	if manager != nil && manager.Name == target.NameTargetTransition {
+		//the target may give back a held payment by itself, for example the lock is about to expire
+		eh.raiden.holdRejected(e2.LockSecretHash, eh.raiden.getTokenForChannelIdentifier(e2.ChannelIdentifier), e2.Reason)
+	}
 	return nil
 }
 func (eh *stateMachineEventHandler) eventContractSendWithdraw(e2 *mediatedtransfer.EventContractSendWithdraw, manager *transfer.StateManager) (err error) {
@@ -293,6 +299,7 @@ func (eh *stateMachineEventHandler) eventUnlockFailed(e2 *mediatedtransfer.Event
 		log.Warn(fmt.Sprintf("Get Event UnlockFailed ,but hashlock cannot be removed err:%s", err))
 		return
 	}
+	eh.raiden.MissionControl.ReportFailure(ch.TokenAddress, eh.raiden.NodeAddress, ch.PartnerState.Address)
 	err = tr.Sign(eh.raiden.PrivateKey, tr)
 	err = ch.RegisterRemoveExpiredHashlockTransfer(tr, eh.raiden.GetBlockNumber())
 	if err != nil {
@@ -336,7 +343,8 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 		if err != nil {
 			log.Error(fmt.Sprintf("UpdateChannelNoTx err %s", err))
 		}
-		eh.raiden.db.NewSentTransfer(eh.raiden.GetBlockNumber(), e2.ChannelIdentifier, ch.TokenAddress, e2.Target, ch.GetNextNonce(), e2.Amount)
+		eh.raiden.db.NewSentTransfer(eh.raiden.GetBlockNumber(), e2.ChannelIdentifier, ch.TokenAddress, e2.Target, ch.GetNextNonce(), e2.Amount, e2.PaymentIdentifier, e2.Memo)
+		eh.raiden.invoiceTransferred(true, e2.Target, ch.TokenAddress, e2.Amount, e2.LockSecretHash, e2.PaymentIdentifier)
 		eh.finishOneTransfer(event)
 	case *transfer.EventTransferSentFailed:
 		eh.finishOneTransfer(event)
@@ -350,7 +358,8 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 		if err != nil {
 			log.Error(fmt.Sprintf("UpdateChannelNoTx err %s", err))
 		}
-		eh.raiden.db.NewReceivedTransfer(eh.raiden.GetBlockNumber(), e2.ChannelIdentifier, ch.TokenAddress, e2.Initiator, ch.PartnerState.BalanceProofState.Nonce, e2.Amount)
+		eh.raiden.db.NewReceivedTransfer(eh.raiden.GetBlockNumber(), e2.ChannelIdentifier, ch.TokenAddress, e2.Initiator, ch.PartnerState.BalanceProofState.Nonce, e2.Amount, e2.PaymentIdentifier, e2.Memo)
+		eh.raiden.invoiceTransferred(false, e2.Initiator, ch.TokenAddress, e2.Amount, e2.LockSecretHash, e2.PaymentIdentifier)
 	case *mediatedtransfer.EventUnlockSuccess:
 	case *mediatedtransfer.EventWithdrawFailed:
 		log.Error(fmt.Sprintf("EventWithdrawFailed hashlock=%s,reason=%s", utils.HPex(e2.LockSecretHash), e2.Reason))
@@ -360,6 +369,9 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 					  The withdraw is currently handled by the netting channel, once the close
 			     event is detected all locks will be withdrawn
 		*/
+		if e2.Pair != nil {
+			eh.raiden.recordMediation(e2.Pair)
+		}
 	case *mediatedtransfer.EventContractSendWithdraw:
 		//do nothing for five events above
 		err = eh.eventContractSendWithdraw(e2, stateManager)
@@ -371,6 +383,10 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 		err = eh.eventContractSendRegisterSecret(e2)
 	case *mediatedtransfer.EventRemoveStateManager:
 		delete(eh.raiden.Transfer2StateManager, e2.Key)
+		if stateManager.Name == initiator.NameInitiatorTransition {
+			//every initiator ends here, whether the transfer succeeds, fails or expires, so a rebalance cannot come back anymore
+			delete(eh.raiden.rebalances, stateManager.Identifier)
+		}
 	default:
 		err = fmt.Errorf("unkown event :%s", utils.StringInterface1(event))
 		log.Error(err.Error())
@@ -406,6 +422,8 @@ func (eh *stateMachineEventHandler) finishOneTransfer(ev transfer.Event) {
 		}
 		r.Result <- err
 		delete(eh.raiden.Transfer2Result, smkey)
+		//no secret request of this transfer needs to be ignored anymore
+		delete(eh.raiden.SecretRequestPredictorMap, lockSecretHash)
 	}
 }
 func (eh *stateMachineEventHandler) HandleTokenAdded(st *mediatedtransfer.ContractTokenAddedStateChange) error {
@@ -420,6 +438,8 @@ func (eh *stateMachineEventHandler) HandleTokenAdded(st *mediatedtransfer.Contra
 		return err
 	}
 	g := graph.NewChannelGraph(eh.raiden.NodeAddress, st.TokenAddress, nil)
+	g.SetRoutingMode(eh.raiden.Config.RoutingMode)
+	g.SetRouteHistory(eh.raiden.MissionControl)
 	eh.raiden.TokenNetwork2Token[tokenNetworkAddress] = tokenAddress
 	eh.raiden.Token2TokenNetwork[tokenAddress] = tokenNetworkAddress
 	eh.raiden.Token2ChannelGraph[tokenAddress] = g
@@ -449,7 +469,12 @@ func (eh *stateMachineEventHandler) handleChannelNew(st *mediatedtransfer.Contra
 		partner = st.Participant2
 	}
 	if isParticipant {
+		isNew := eh.raiden.getChannel(tokenAddress, partner) == nil
 		eh.raiden.registerChannel(tokenNetworkAddress, partner, st.ChannelIdentifier, st.SettleTimeout)
+		//a node always opens a channel as participant1
+		if isNew && participant1 == partner && eh.raiden.getChannel(tokenAddress, partner) != nil {
+			eh.raiden.joinChannelOpenedByPartner(tokenAddress, partner)
+		}
 		other := participant2
 		if other == eh.raiden.NodeAddress {
 			other = participant1
@@ -464,9 +489,18 @@ func (eh *stateMachineEventHandler) handleChannelNew(st *mediatedtransfer.Contra
 func (eh *stateMachineEventHandler) handleBalance(st *mediatedtransfer.ContractBalanceStateChange) error {
 	ch, err := eh.raiden.findChannelByAddress(st.ChannelIdentifier)
 	if err != nil {
-		//todo 处理这个事件,路由的时候可以考虑节点之间的权重,权重值=双方 deposit 之和
-		// todo handle this event, when routing we should consider the weight between nodes, weight = sum of deposits between a participant pair.
+		//i'm not a participant,record its deposit as the capacity when routing
 		log.Trace(fmt.Sprintf("ContractBalanceStateChange i'm not a participant,channelAddress=%s", utils.HPex(st.ChannelIdentifier)))
+		token := eh.raiden.TokenNetwork2Token[st.TokenNetworkAddress]
+		c, err := eh.raiden.db.UpdateNonParticipantChannelDeposit(token, st.ChannelIdentifier, st.ParticipantAddress, st.Balance)
+		if err != nil {
+			log.Error(fmt.Sprintf("handleBalance UpdateNonParticipantChannelDeposit err=%s", err))
+			return nil
+		}
+		g := eh.raiden.getToken2ChannelGraph(token)
+		if g != nil {
+			g.SetCapacity(c.Participant1, c.Participant2, c.Capacity())
+		}
 		return nil
 	}
 	err = eh.ChannelStateTransition(ch, st)
@@ -490,7 +524,10 @@ func (eh *stateMachineEventHandler) handleClosed(st *mediatedtransfer.ContractCl
 	if err != nil {
 		log.Error(fmt.Sprintf("handleBalance ChannelStateTransition err=%s", err))
 	}
+	//tells callbacks of UpdateChannelState who closed the channel, even if it was closed before a restart
+	eh.raiden.channelClosingAddress[channelAddress] = st.ClosingAddress
 	err = eh.raiden.db.UpdateChannelState(channel.NewChannelSerialization(ch))
+	delete(eh.raiden.channelClosingAddress, channelAddress)
 	return err
 }
 
@@ -761,6 +798,9 @@ func (eh *stateMachineEventHandler) updateStateManagerFromStateChange(mgr *trans
 	case *mediatedtransfer.ActionInitTargetStateChange:
 		quitName = "ActionInitTargetStateChange"
 		msg = st2.Message
+	case *mediatedtransfer.ReceiveTransferPartStateChange:
+		quitName = "ReceiveTransferPartStateChange"
+		msg = st2.Message
 	case *mediatedtransfer.ReceiveSecretRequestStateChange:
 		quitName = "ReceiveSecretRequestStateChange"
 		msg = st2.Message
@@ -769,6 +809,7 @@ func (eh *stateMachineEventHandler) updateStateManagerFromStateChange(mgr *trans
 		msg = st2.Message
 	case *mediatedtransfer.ReceiveUnlockStateChange:
 		quitName = "ReceiveUnlockStateChange"
+		mgr.LastMessage = fmt.Sprintf("%s from %s", st2.Message.Name(), utils.APex2(st2.Message.GetSender()))
 	case *mediatedtransfer.ActionInitMediatorStateChange:
 		quitName = "ActionInitMediatorStateChange"
 		msg = st2.Message
@@ -780,9 +821,11 @@ func (eh *stateMachineEventHandler) updateStateManagerFromStateChange(mgr *trans
 		//new transfer trigger from user
 	case *mediatedtransfer.ReceiveSecretRevealStateChange:
 		quitName = "ReceiveSecretRevealStateChange"
+		mgr.LastMessage = fmt.Sprintf("%s from %s", st2.Message.Name(), utils.APex2(st2.Sender))
 	}
 	if msg != nil {
 		mgr.LastReceivedMessage = msg
+		mgr.LastMessage = fmt.Sprintf("%s from %s", msg.Name(), utils.APex2(msg.GetSender()))
 	}
 	if len(quitName) > 0 {
 		eh.raiden.conditionQuit(quitName)
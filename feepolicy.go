@@ -1,8 +1,13 @@
 package smartraiden
 
 import (
+	"errors"
+	"fmt"
 	"math/big"
+	"sync"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -36,3 +41,228 @@ func (c *CombinationFeePolicy) GetNodeChargeFee(nodeAddress, tokenAddress common
 	f := new(big.Int).Div(amount, big.NewInt(1000)) //fee rate: one in thousand.
 	return f.Add(f, fixedFee)
 }
+
+/*
+FeePolicy charges `BaseFee + amount * ProportionalRate / 1000000` by the rates saved in db.
+for this node's own channels, the rate of a channel takes precedence over the rate of the partner, then the rate of the token, then the default rate,
+a mediated transfer forwarded here pays exactly GetOwnChargeFee of the next hop.
+rates of other nodes are unknown, so GetNodeChargeFee estimates them only by the rate of the token or the default rate.
+*/
+type FeePolicy struct {
+	db           *models.ModelDB
+	lock         sync.RWMutex
+	rates        map[string]*models.FeeRate
+	channelRates map[string]*models.FeeRate //rates of channels indexed by key of token and partner
+}
+
+//defaultFeeRate is used if no default rate saved, same as ConstantFeePolicy
+var defaultFeeRate = &models.FeeRate{
+	Key:     models.FeeRateDefaultKey,
+	BaseFee: fixedFee,
+}
+
+//NewFeePolicy creates a fee policy and loads rates from `db`
+func NewFeePolicy(db *models.ModelDB) (f *FeePolicy, err error) {
+	f = &FeePolicy{
+		db:           db,
+		rates:        make(map[string]*models.FeeRate),
+		channelRates: make(map[string]*models.FeeRate),
+	}
+	rs, err := db.GetAllFeeRates()
+	if err != nil {
+		return
+	}
+	for _, r := range rs {
+		f.setRate(r)
+	}
+	return
+}
+
+func (f *FeePolicy) setRate(r *models.FeeRate) {
+	if r.ChannelIdentifier != utils.EmptyHash {
+		f.channelRates[models.FeeRateKey(r.TokenAddress, utils.EmptyHash, r.PartnerAddress)] = r
+	} else {
+		f.rates[r.Key] = r
+	}
+}
+
+//SetFeeRate creates or updates a rate
+func (f *FeePolicy) SetFeeRate(r *models.FeeRate) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	err := f.db.SaveFeeRate(r)
+	if err != nil {
+		return err
+	}
+	f.setRate(r)
+	return nil
+}
+
+//RemoveFeeRate removes the rate with `key`
+func (f *FeePolicy) RemoveFeeRate(key string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	r, err := f.db.GetFeeRate(key)
+	if err != nil {
+		return err
+	}
+	err = f.db.RemoveFeeRate(key)
+	if err != nil {
+		return err
+	}
+	if r.ChannelIdentifier != utils.EmptyHash {
+		delete(f.channelRates, models.FeeRateKey(r.TokenAddress, utils.EmptyHash, r.PartnerAddress))
+	} else {
+		delete(f.rates, key)
+	}
+	return nil
+}
+
+//GetFeeRate returns the rate this node charges for mediating a transfer to `partner` on token `tokenAddress`
+func (f *FeePolicy) GetFeeRate(partner, tokenAddress common.Address) *models.FeeRate {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	key := models.FeeRateKey(tokenAddress, utils.EmptyHash, partner)
+	if r, ok := f.channelRates[key]; ok {
+		return r
+	}
+	if r, ok := f.rates[key]; ok {
+		return r
+	}
+	return f.tokenFeeRate(tokenAddress)
+}
+
+//tokenFeeRate is the rate of token `tokenAddress` or the default rate, must hold the lock
+func (f *FeePolicy) tokenFeeRate(tokenAddress common.Address) *models.FeeRate {
+	if r, ok := f.rates[models.FeeRateKey(tokenAddress, utils.EmptyHash, utils.EmptyAddress)]; ok {
+		return r
+	}
+	if r, ok := f.rates[models.FeeRateDefaultKey]; ok {
+		return r
+	}
+	return defaultFeeRate
+}
+
+func chargeFee(r *models.FeeRate, amount *big.Int) *big.Int {
+	fee := new(big.Int).Mul(amount, big.NewInt(r.ProportionalRate))
+	fee.Div(fee, big.NewInt(params.FeeRateDenominator))
+	return fee.Add(fee, r.BaseFee)
+}
+
+//GetOwnChargeFee returns fee of `amount` this node charges for mediating a transfer to `partner` on token `tokenAddress`
+func (f *FeePolicy) GetOwnChargeFee(partner, tokenAddress common.Address, amount *big.Int) *big.Int {
+	return chargeFee(f.GetFeeRate(partner, tokenAddress), amount)
+}
+
+/*
+GetNodeChargeFee estimates fee of `amount` charged by `nodeAddress` on token `tokenAddress` when searching paths,
+rates of partners and channels are this node's own, they never apply to other nodes.
+*/
+func (f *FeePolicy) GetNodeChargeFee(nodeAddress, tokenAddress common.Address, amount *big.Int) *big.Int {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return chargeFee(f.tokenFeeRate(tokenAddress), amount)
+}
+
+func (r *RaidenAPI) feePolicy() (*FeePolicy, error) {
+	f, ok := r.Raiden.FeePolicy.(*FeePolicy)
+	if !ok {
+		return nil, errors.New("mediation fee is not enabled")
+	}
+	return f, nil
+}
+
+//clearPathCaches makes paths weighed again with the fees changed, must be called in the main loop
+func (rs *RaidenService) clearPathCaches() {
+	for _, g := range rs.Token2ChannelGraph {
+		g.ClearPathCache()
+	}
+}
+
+/*
+SetFeeRate creates or updates the default rate, the rate of a token,
+or the rate of a partner or a channel of the token.
+partner of a channel rate is taken from the channel.
+*/
+func (r *RaidenAPI) SetFeeRate(rate *models.FeeRate) (err error) {
+	f, err := r.feePolicy()
+	if err != nil {
+		return
+	}
+	if rate.TokenAddress == utils.EmptyAddress {
+		if rate.ChannelIdentifier != utils.EmptyHash || rate.PartnerAddress != utils.EmptyAddress {
+			return errors.New("token_address is required by rate of a partner or a channel")
+		}
+	} else if !r.hasToken(rate.TokenAddress) {
+		return errors.New("token not exist")
+	}
+	if rate.ChannelIdentifier != utils.EmptyHash {
+		c, err := r.Raiden.db.GetChannelByAddress(rate.ChannelIdentifier)
+		if err != nil {
+			return fmt.Errorf("channel %s not found", utils.HPex(rate.ChannelIdentifier))
+		}
+		if c.TokenAddress() != rate.TokenAddress {
+			return errors.New("channel is not of the token")
+		}
+		if rate.PartnerAddress != utils.EmptyAddress && rate.PartnerAddress != c.PartnerAddress() {
+			return errors.New("partner is not of the channel")
+		}
+		rate.PartnerAddress = c.PartnerAddress()
+	}
+	if rate.BaseFee == nil {
+		rate.BaseFee = big.NewInt(0)
+	}
+	if rate.BaseFee.Cmp(utils.BigInt0) < 0 {
+		return errors.New("base_fee must not be negative")
+	}
+	if rate.ProportionalRate < 0 || rate.ProportionalRate > params.FeeRateDenominator {
+		return fmt.Errorf("proportional_rate must be between 0 and %d", params.FeeRateDenominator)
+	}
+	err = f.SetFeeRate(rate)
+	if err != nil {
+		return
+	}
+	return <-r.Raiden.clearPathCachesClient().Result
+}
+
+//GetFeeRates returns all the fee rates saved
+func (r *RaidenAPI) GetFeeRates() ([]*models.FeeRate, error) {
+	return r.Raiden.db.GetAllFeeRates()
+}
+
+//GetFeeRate returns the rate with `key`
+func (r *RaidenAPI) GetFeeRate(key string) (*models.FeeRate, error) {
+	return r.Raiden.db.GetFeeRate(key)
+}
+
+//RemoveFeeRate removes the rate with `key`
+func (r *RaidenAPI) RemoveFeeRate(key string) error {
+	f, err := r.feePolicy()
+	if err != nil {
+		return err
+	}
+	err = f.RemoveFeeRate(key)
+	if err != nil {
+		return err
+	}
+	return <-r.Raiden.clearPathCachesClient().Result
+}
+
+/*
+GetChargeFee returns the fee this node charges for mediating a transfer of `amount` to `partner` on token `tokenAddress`,
+it's 0 if mediation fee is not enabled.
+*/
+func (r *RaidenAPI) GetChargeFee(tokenAddress, partner common.Address, amount *big.Int) *big.Int {
+	return r.Raiden.getOwnChargeFee(partner, tokenAddress, amount)
+}
+
+/*
+getOwnChargeFee returns the fee this node charges for mediating a transfer of `amount` to `partner`,
+only FeePolicy has rates of partners and channels, other policies charge the same as GetNodeChargeFee.
+*/
+func (rs *RaidenService) getOwnChargeFee(partner, tokenAddress common.Address, amount *big.Int) *big.Int {
+	if f, ok := rs.FeePolicy.(*FeePolicy); ok {
+		return f.GetOwnChargeFee(partner, tokenAddress, amount)
+	}
+	return rs.FeePolicy.GetNodeChargeFee(partner, tokenAddress, amount)
+}
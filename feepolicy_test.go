@@ -0,0 +1,65 @@
+package smartraiden
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+func TestFeePolicy(t *testing.T) {
+	db := setupDb(t, "testfeepolicy.db")
+	defer db.CloseDB()
+	token := utils.NewRandomAddress()
+	partner := utils.NewRandomAddress()
+	other := utils.NewRandomAddress()
+	amount := big.NewInt(20000)
+	f, err := NewFeePolicy(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, fixedFee, f.GetNodeChargeFee(partner, token, amount))
+	err = f.SetFeeRate(&models.FeeRate{BaseFee: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(1), f.GetNodeChargeFee(partner, token, amount))
+	//1 + 20000 * 500 / 1000000
+	err = f.SetFeeRate(&models.FeeRate{TokenAddress: token, BaseFee: big.NewInt(1), ProportionalRate: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(11), f.GetNodeChargeFee(partner, token, amount))
+	err = f.SetFeeRate(&models.FeeRate{TokenAddress: token, PartnerAddress: partner, BaseFee: big.NewInt(0), ProportionalRate: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(20), f.GetOwnChargeFee(partner, token, amount))
+	assert(t, big.NewInt(11), f.GetOwnChargeFee(other, token, amount))
+	//my rate of a partner is not what the partner charges
+	assert(t, big.NewInt(11), f.GetNodeChargeFee(partner, token, amount))
+	ch := utils.NewRandomHash()
+	err = f.SetFeeRate(&models.FeeRate{TokenAddress: token, ChannelIdentifier: ch, PartnerAddress: partner, BaseFee: big.NewInt(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(5), f.GetOwnChargeFee(partner, token, amount))
+	assert(t, big.NewInt(11), f.GetNodeChargeFee(partner, token, amount))
+	//rates are restored from db
+	f, err = NewFeePolicy(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(5), f.GetOwnChargeFee(partner, token, amount))
+	err = f.RemoveFeeRate(ch.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(20), f.GetOwnChargeFee(partner, token, amount))
+	err = f.RemoveFeeRate(models.FeeRateKey(token, utils.EmptyHash, utils.EmptyAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, big.NewInt(1), f.GetNodeChargeFee(other, token, amount))
+}
@@ -0,0 +1,239 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+RegisterHoldPayment tells this node to expect `amount` of `token` locked with `lockSecretHash`,
+transfers received with this lock secret hash are held until SettleHoldPayment or RejectHoldPayment,
+they are rejected automatically if not settled before `deadline` (unix time).
+*/
+func (r *RaidenAPI) RegisterHoldPayment(lockSecretHash common.Hash, token common.Address, amount *big.Int, deadline int64) (h *models.HoldPayment, err error) {
+	if lockSecretHash == utils.EmptyHash {
+		err = errors.New("lock secret hash is empty")
+		return
+	}
+	if amount == nil || amount.Cmp(utils.BigInt0) <= 0 {
+		err = rerr.ErrInvalidAmount
+		return
+	}
+	if deadline <= time.Now().Unix() {
+		err = errors.New("deadline must be in the future")
+		return
+	}
+	if !r.hasToken(token) {
+		err = errors.New("token not exist")
+		return
+	}
+	h = &models.HoldPayment{
+		LockSecretHash: lockSecretHash,
+		Token:          token,
+		Amount:         amount,
+		Deadline:       deadline,
+	}
+	err = r.Raiden.db.NewHoldPayment(h)
+	return
+}
+
+//SettleHoldPayment reveals `secret` to the payers of a held payment, the payment is received when they unlock
+func (r *RaidenAPI) SettleHoldPayment(secret common.Hash) (err error) {
+	result := r.Raiden.settleHoldPaymentClient(secret)
+	err = <-result.Result
+	return
+}
+
+//RejectHoldPayment gives back the transfers held by AnnounceDisposed, transfers received later are rejected too
+func (r *RaidenAPI) RejectHoldPayment(lockSecretHash common.Hash) (err error) {
+	result := r.Raiden.rejectHoldPaymentClient(lockSecretHash)
+	err = <-result.Result
+	return
+}
+
+//GetHoldPayment returns the hold payment with `lockSecretHash`
+func (r *RaidenAPI) GetHoldPayment(lockSecretHash common.Hash) (h *models.HoldPayment, err error) {
+	return r.Raiden.db.GetHoldPayment(lockSecretHash)
+}
+
+//GetHoldPayments returns all the hold payments
+func (r *RaidenAPI) GetHoldPayments() (hs []*models.HoldPayment, err error) {
+	return r.Raiden.db.GetHoldPayments()
+}
+
+/*
+getHoldPayment returns the hold payment for transfers with `lockSecretHash`,
+nil means transfers with this lock secret hash are not held.
+*/
+func (rs *RaidenService) getHoldPayment(lockSecretHash common.Hash) *models.HoldPayment {
+	h, err := rs.db.GetHoldPayment(lockSecretHash)
+	if err != nil || h.Status == models.HoldPaymentStatusSettled {
+		return nil
+	}
+	return h
+}
+
+//heldAmount returns the amount of transfers a target is holding
+func heldAmount(state *mediatedtransfer.TargetState) *big.Int {
+	if len(state.Parts) == 0 {
+		return new(big.Int).Set(state.FromTransfer.Amount)
+	}
+	amount := new(big.Int)
+	for _, p := range state.Parts {
+		if p.State == mediatedtransfer.StatePayerPending {
+			amount.Add(amount, p.FromTransfer.Amount)
+		}
+	}
+	return amount
+}
+
+/*
+holdTransfer checks a transfer just received for hold payment `h`,
+a transfer which doesn't match is rejected at once, otherwise it's held.
+must be called in the main loop.
+*/
+func (rs *RaidenService) holdTransfer(stateManager *transfer.StateManager, h *models.HoldPayment) {
+	state, ok := stateManager.CurrentState.(*mediatedtransfer.TargetState)
+	if !ok || !state.Hold {
+		return
+	}
+	received := heldAmount(state)
+	reason := ""
+	if h.Status == models.HoldPaymentStatusRejected {
+		reason = "hold payment rejected"
+	} else if state.FromTransfer.Token != h.Token {
+		reason = "token doesn't match hold payment"
+	} else if time.Now().Unix() > h.Deadline {
+		reason = "hold payment deadline passed"
+	} else if !state.FromTransfer.IsMultiPart() && received.Cmp(h.Amount) < 0 {
+		reason = fmt.Sprintf("amount %s is less than expected %s", received, h.Amount)
+	}
+	if reason != "" {
+		log.Info(fmt.Sprintf("reject transfer of hold payment %s, %s", utils.HPex(h.LockSecretHash), reason))
+		rs.StateMachineEventHandler.dispatch(stateManager, &mediatedtransfer.ActionRejectHoldStateChange{
+			LockSecretHash: h.LockSecretHash,
+			Reason:         reason,
+		})
+		return
+	}
+	h.Status = models.HoldPaymentStatusHeld
+	h.Received = received
+	err := rs.db.UpdateHoldPayment(h)
+	if err != nil {
+		log.Error(fmt.Sprintf("UpdateHoldPayment err %s", err))
+	}
+}
+
+//heldTransfer returns the state manager holding transfers of `h`
+func (rs *RaidenService) heldTransfer(h *models.HoldPayment) (*transfer.StateManager, *mediatedtransfer.TargetState) {
+	mgr := rs.Transfer2StateManager[utils.Sha3(h.LockSecretHash[:], h.Token[:])]
+	if mgr == nil {
+		return nil, nil
+	}
+	state, ok := mgr.CurrentState.(*mediatedtransfer.TargetState)
+	if !ok || !state.Hold {
+		return nil, nil
+	}
+	return mgr, state
+}
+
+/*
+settleHoldPayment reveals the secret to the payers if the expected amount is held.
+must be called in the main loop.
+*/
+func (rs *RaidenService) settleHoldPayment(secret common.Hash) error {
+	lockSecretHash := utils.ShaSecret(secret[:])
+	h, err := rs.db.GetHoldPayment(lockSecretHash)
+	if err != nil {
+		return fmt.Errorf("no hold payment with lock secret hash %s", lockSecretHash.String())
+	}
+	if h.Status != models.HoldPaymentStatusHeld {
+		return fmt.Errorf("hold payment is %s, cannot settle", h.Status)
+	}
+	mgr, state := rs.heldTransfer(h)
+	if mgr == nil {
+		return errors.New("no transfer is held, it may be rejected because lock is about to expire")
+	}
+	received := heldAmount(state)
+	if received.Cmp(h.Amount) < 0 {
+		return fmt.Errorf("only %s of %s received", received, h.Amount)
+	}
+	rs.StateMachineEventHandler.dispatch(mgr, &mediatedtransfer.ActionSettleHoldStateChange{
+		Secret: secret,
+	})
+	h.Status = models.HoldPaymentStatusSettled
+	h.Received = received
+	return rs.db.UpdateHoldPayment(h)
+}
+
+/*
+rejectHoldPayment gives back the transfers held and rejects transfers received later.
+must be called in the main loop.
+*/
+func (rs *RaidenService) rejectHoldPayment(lockSecretHash common.Hash, reason string) error {
+	h, err := rs.db.GetHoldPayment(lockSecretHash)
+	if err != nil {
+		return fmt.Errorf("no hold payment with lock secret hash %s", lockSecretHash.String())
+	}
+	if h.IsFinished() {
+		return fmt.Errorf("hold payment is already %s", h.Status)
+	}
+	mgr, _ := rs.heldTransfer(h)
+	if mgr != nil {
+		rs.StateMachineEventHandler.dispatch(mgr, &mediatedtransfer.ActionRejectHoldStateChange{
+			LockSecretHash: lockSecretHash,
+			Reason:         reason,
+		})
+	}
+	h.Status = models.HoldPaymentStatusRejected
+	h.Reason = reason
+	return rs.db.UpdateHoldPayment(h)
+}
+
+/*
+holdRejected marks hold payment `lockSecretHash` rejected when its held transfers are given back,
+so a hold payment the target rejected by itself is not reported as held anymore.
+must be called in the main loop.
+*/
+func (rs *RaidenService) holdRejected(lockSecretHash common.Hash, token common.Address, reason string) {
+	h, err := rs.db.GetHoldPayment(lockSecretHash)
+	//transfers of other tokens with the same lock secret hash are not held
+	if err != nil || h.Token != token || h.Status != models.HoldPaymentStatusHeld {
+		return
+	}
+	h.Status = models.HoldPaymentStatusRejected
+	h.Reason = reason
+	err = rs.db.UpdateHoldPayment(h)
+	if err != nil {
+		log.Error(fmt.Sprintf("UpdateHoldPayment err %s", err))
+	}
+}
+
+//rejectExpiredHoldPayments rejects hold payments not settled before their deadline
+func (rs *RaidenService) rejectExpiredHoldPayments() {
+	hs, err := rs.db.GetUnfinishedHoldPayments()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetUnfinishedHoldPayments err %s", err))
+		return
+	}
+	now := time.Now().Unix()
+	for _, h := range hs {
+		if now <= h.Deadline {
+			continue
+		}
+		err = rs.rejectHoldPayment(h.LockSecretHash, "hold payment deadline passed")
+		if err != nil {
+			log.Error(fmt.Sprintf("reject hold payment %s err %s", utils.HPex(h.LockSecretHash), err))
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package smartraiden
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+func TestHoldRejected(t *testing.T) {
+	db := setupDb(t, "testholdpayment.db")
+	defer db.CloseDB()
+	rs := &RaidenService{db: db}
+	h := &models.HoldPayment{
+		LockSecretHash: utils.NewRandomHash(),
+		Token:          utils.NewRandomAddress(),
+		Amount:         big.NewInt(10),
+		Deadline:       1,
+	}
+	err := db.NewHoldPayment(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//nothing held yet
+	rs.holdRejected(h.LockSecretHash, h.Token, "hold payment not settled in time")
+	h2, err := db.GetHoldPayment(h.LockSecretHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, models.HoldPaymentStatusWaiting, h2.Status)
+	h2.Status = models.HoldPaymentStatusHeld
+	err = db.UpdateHoldPayment(h2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//a transfer of another token is not held
+	rs.holdRejected(h.LockSecretHash, utils.NewRandomAddress(), "token doesn't match hold payment")
+	h2, _ = db.GetHoldPayment(h.LockSecretHash)
+	assert(t, models.HoldPaymentStatusHeld, h2.Status)
+	rs.holdRejected(h.LockSecretHash, h.Token, "hold payment not settled in time")
+	h2, _ = db.GetHoldPayment(h.LockSecretHash)
+	assert(t, models.HoldPaymentStatusRejected, h2.Status)
+	assert(t, "hold payment not settled in time", h2.Reason)
+}
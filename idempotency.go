@@ -0,0 +1,161 @@
+package smartraiden
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//operations which accept an idempotency key
+const (
+	idempotentTransfer    = "transfer"
+	idempotentOpenChannel = "open channel"
+	idempotentDeposit     = "deposit"
+)
+
+/*
+beginIdempotent records that `operation` with `params` starts with `key`.
+isNew is false if `key` has been used, then the record of the first request is returned.
+*/
+func (r *RaidenAPI) beginIdempotent(key, operation string, params ...interface{}) (rec *models.IdempotencyRecord, isNew bool, err error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	paramsHash := utils.Sha3(data)
+	rec, isNew, err = r.Raiden.db.NewIdempotencyRecord(key, operation, paramsHash)
+	if err != nil || isNew {
+		return
+	}
+	if rec.Operation != operation || rec.ParamsHash != paramsHash {
+		err = rerr.ErrIdempotencyKeyReused
+		return
+	}
+	switch rec.Status {
+	case models.IdempotencyStatusPending:
+		err = rerr.ErrRequestInProgress
+	case models.IdempotencyStatusInterrupted:
+		err = rerr.ErrRequestInterrupted
+	case models.IdempotencyStatusFailed:
+		err = errors.New(rec.Error)
+	}
+	return
+}
+
+/*
+interruptIdempotencyRecords marks requests which were still in progress when the node stopped interrupted,
+otherwise a retry would wait for them forever.
+the result of such a transfer can be found by the lock secret hash saved in its record.
+*/
+func (rs *RaidenService) interruptIdempotencyRecords() {
+	recs, err := rs.db.InterruptPendingIdempotencyRecords()
+	if err != nil {
+		log.Error(fmt.Sprintf("InterruptPendingIdempotencyRecords err %s", err))
+	}
+	for _, rec := range recs {
+		log.Warn(fmt.Sprintf("%s with idempotency key %s interrupted, lock secret hash=%s", rec.Operation, rec.Key, utils.HPex(rec.LockSecretHash)))
+	}
+}
+
+//finishIdempotent saves the result of the request with `key`
+func (r *RaidenAPI) finishIdempotent(key string, channelIdentifier common.Hash, result error) {
+	err := r.Raiden.db.FinishIdempotencyRecord(key, channelIdentifier, result)
+	if err != nil {
+		log.Error(fmt.Sprintf("FinishIdempotencyRecord %s err %s", key, err))
+	}
+}
+
+/*
+TransferIdempotent is TransferAndWait with an idempotency key,
+a retry with the same `key` never starts a second transfer, it gets the result of the first one.
+rerr.ErrRequestInProgress means the first transfer has not finished yet,
+rerr.ErrRequestInterrupted means the node restarted before that, the lock secret hash in `rec` tells which transfer it is.
+the result is saved when the transfer finishes, even if it's after `timeout`.
+*/
+func (r *RaidenAPI) TransferIdempotent(key string, token common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, timeout time.Duration, isDirectTransfer bool, identifier uint64, memo string) (rec *models.IdempotencyRecord, err error) {
+	rec, isNew, err := r.beginIdempotent(key, idempotentTransfer, token, amount, fee, target, secret, isDirectTransfer, identifier, memo)
+	if !isNew {
+		return
+	}
+	result, err := r.transferAsync(token, amount, fee, target, secret, isDirectTransfer, identifier, memo)
+	if err != nil {
+		r.finishIdempotent(key, utils.EmptyHash, err)
+		return
+	}
+	if lockSecretHash, ok := result.Tag.(common.Hash); ok {
+		err = r.Raiden.db.SetIdempotencyLockSecretHash(key, lockSecretHash)
+		if err != nil {
+			log.Error(fmt.Sprintf("SetIdempotencyLockSecretHash %s err %s", key, err))
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := <-result.Result
+		r.finishIdempotent(key, utils.EmptyHash, err)
+		done <- err
+	}()
+	if timeout > 0 {
+		select {
+		case <-time.After(timeout):
+			err = rerr.ErrTransferTimeout
+		case err = <-done:
+		}
+	} else {
+		err = <-done
+	}
+	rec, _ = r.Raiden.db.GetIdempotencyRecord(key)
+	return
+}
+
+/*
+OpenIdempotent is Open with an idempotency key,
+a retry with the same `key` never opens a second channel, it gets the channel opened by the first one.
+*/
+func (r *RaidenAPI) OpenIdempotent(key string, tokenAddress, partnerAddress common.Address, settleTimeout, revealTimeout int, deposit *big.Int) (rec *models.IdempotencyRecord, ch *channeltype.Serialization, err error) {
+	rec, isNew, err := r.beginIdempotent(key, idempotentOpenChannel, tokenAddress, partnerAddress, settleTimeout, revealTimeout, deposit)
+	if !isNew {
+		if err == nil {
+			ch, err = r.Raiden.db.GetChannelByAddress(rec.ChannelIdentifier)
+		}
+		return
+	}
+	ch, err = r.Open(tokenAddress, partnerAddress, settleTimeout, revealTimeout, deposit)
+	channelIdentifier := utils.EmptyHash
+	if err == nil {
+		channelIdentifier = ch.ChannelIdentifier.ChannelIdentifier
+	}
+	r.finishIdempotent(key, channelIdentifier, err)
+	rec, _ = r.Raiden.db.GetIdempotencyRecord(key)
+	return
+}
+
+/*
+DepositIdempotent is Deposit with an idempotency key,
+a retry with the same `key` never deposits again, it gets the channel deposited by the first one.
+*/
+func (r *RaidenAPI) DepositIdempotent(key string, tokenAddress, partnerAddress common.Address, amount *big.Int, pollTimeout time.Duration) (rec *models.IdempotencyRecord, ch *channeltype.Serialization, err error) {
+	rec, isNew, err := r.beginIdempotent(key, idempotentDeposit, tokenAddress, partnerAddress, amount)
+	if !isNew {
+		if err == nil {
+			ch, err = r.Raiden.db.GetChannelByAddress(rec.ChannelIdentifier)
+		}
+		return
+	}
+	ch, err = r.Deposit(tokenAddress, partnerAddress, amount, pollTimeout)
+	channelIdentifier := utils.EmptyHash
+	if err == nil {
+		channelIdentifier = ch.ChannelIdentifier.ChannelIdentifier
+	}
+	r.finishIdempotent(key, channelIdentifier, err)
+	rec, _ = r.Raiden.db.GetIdempotencyRecord(key)
+	return
+}
@@ -0,0 +1,185 @@
+package smartraiden
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func newInvoiceModel(i *encoding.Invoice, isPayer bool) *models.Invoice {
+	return &models.Invoice{
+		Hash:           i.Hash(),
+		Invoice:        i.Encode(),
+		Target:         i.Target,
+		Token:          i.Token,
+		Amount:         i.Amount,
+		LockSecretHash: i.LockSecretHash,
+		Identifier:     i.Identifier,
+		Expiration:     i.Expiration,
+		Memo:           i.Memo,
+		IsPayer:        isPayer,
+	}
+}
+
+/*
+CreateInvoice creates an invoice signed by this node, asking for `amount` of `token`.
+`lockSecretHash` is optional, if it's given, I must register the secret when the transfer arrives.
+`expiration` is unix time, 0 means never expire.
+*/
+func (r *RaidenAPI) CreateInvoice(token common.Address, amount *big.Int, lockSecretHash common.Hash, expiration int64, memo string) (inv *models.Invoice, err error) {
+	if amount == nil || amount.Cmp(utils.BigInt0) <= 0 {
+		err = rerr.ErrInvalidAmount
+		return
+	}
+	if len(memo) > params.MaxMemoLength {
+		err = fmt.Errorf("memo too long, max %d bytes", params.MaxMemoLength)
+		return
+	}
+	if expiration != 0 && expiration <= time.Now().Unix() {
+		err = errors.New("invoice expiration must be in the future")
+		return
+	}
+	if !r.hasToken(token) {
+		err = errors.New("token not exist")
+		return
+	}
+	i := &encoding.Invoice{
+		ChainID:        params.ChainID,
+		Token:          token,
+		Amount:         amount,
+		LockSecretHash: lockSecretHash,
+		Identifier:     binary.BigEndian.Uint64(utils.Random(8)),
+		Expiration:     expiration,
+		Memo:           memo,
+	}
+	err = i.Sign(r.Raiden.PrivateKey)
+	if err != nil {
+		return
+	}
+	inv = newInvoiceModel(i, false)
+	err = r.Raiden.db.NewInvoice(inv)
+	return
+}
+
+func (r *RaidenAPI) hasToken(token common.Address) bool {
+	for _, t := range r.Tokens() {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+PayInvoice decodes and verifies `invoice`, then pays it with a mediated transfer.
+it waits at most `timeout`, rerr.ErrTransferTimeout means the transfer is still going on,
+for example when the target keeps the secret of this invoice.
+*/
+func (r *RaidenAPI) PayInvoice(invoice string, timeout time.Duration) (inv *models.Invoice, err error) {
+	i, err := encoding.DecodeInvoice(invoice)
+	if err != nil {
+		return
+	}
+	if i.ChainID.Cmp(params.ChainID) != 0 {
+		err = fmt.Errorf("invoice is for chain %s, but I'm on chain %s", i.ChainID, params.ChainID)
+		return
+	}
+	if i.IsExpired(time.Now()) {
+		err = errors.New("invoice expired")
+		return
+	}
+	if i.Target == r.Raiden.NodeAddress {
+		err = errors.New("cannot pay my own invoice")
+		return
+	}
+	if !r.hasToken(i.Token) {
+		err = errors.New("token not exist")
+		return
+	}
+	inv, err = r.Raiden.db.GetInvoice(i.Hash())
+	if err == nil {
+		if inv.Status == models.InvoiceStatusPaid {
+			err = errors.New("invoice already paid")
+			return
+		}
+	} else {
+		inv = newInvoiceModel(i, true)
+		err = r.Raiden.db.NewInvoice(inv)
+		if err != nil {
+			return
+		}
+	}
+	log.Debug(fmt.Sprintf("pay invoice %s target=%s token=%s amount=%s identifier=%d",
+		utils.HPex(inv.Hash), utils.APex(i.Target), utils.APex(i.Token), i.Amount, i.Identifier))
+	data := &encoding.PaymentData{
+		PaymentIdentifier: i.Identifier,
+		Memo:              i.Memo,
+	}
+	var result *utils.AsyncResult
+	if i.LockSecretHash == utils.EmptyHash {
+		result = r.Raiden.transferAsyncClient(i.Token, i.Amount, utils.BigInt0, i.Target, utils.EmptyHash, false, data)
+	} else {
+		result = r.Raiden.transferWithLockSecretHashClient(i.Token, i.Amount, i.Target, i.LockSecretHash, data)
+	}
+	select {
+	case <-time.After(timeout):
+		err = rerr.ErrTransferTimeout
+	case err = <-result.Result:
+	}
+	if err != nil {
+		return
+	}
+	return r.Raiden.db.GetInvoice(inv.Hash)
+}
+
+//GetInvoice returns the invoice created or paid by me with `hash`
+func (r *RaidenAPI) GetInvoice(hash common.Hash) (inv *models.Invoice, err error) {
+	return r.Raiden.db.GetInvoice(hash)
+}
+
+//GetInvoices returns all the invoices created or paid by me
+func (r *RaidenAPI) GetInvoices() (invs []*models.Invoice, err error) {
+	return r.Raiden.db.GetInvoices()
+}
+
+/*
+invoiceTransferred marks the open invoice which is paid by a successful transfer paid.
+`isPayer` is true for a transfer sent by me, `partner` is the target or the initiator of this transfer.
+*/
+func (rs *RaidenService) invoiceTransferred(isPayer bool, partner, token common.Address, amount *big.Int, lockSecretHash common.Hash, identifier uint64) {
+	if identifier == 0 {
+		return
+	}
+	invs, err := rs.db.GetOpenInvoicesByIdentifier(identifier, isPayer)
+	if err != nil {
+		log.Error(fmt.Sprintf("GetOpenInvoicesByIdentifier err %s", err))
+		return
+	}
+	for _, inv := range invs {
+		if inv.Token != token || amount.Cmp(inv.Amount) < 0 {
+			continue
+		}
+		if inv.LockSecretHash != utils.EmptyHash && inv.LockSecretHash != lockSecretHash {
+			continue
+		}
+		if isPayer && inv.Target != partner {
+			continue
+		}
+		log.Info(fmt.Sprintf("invoice %s paid, isPayer=%v,partner=%s", utils.HPex(inv.Hash), isPayer, utils.APex(partner)))
+		err = rs.db.MarkInvoicePaid(inv.Hash)
+		if err != nil {
+			log.Error(fmt.Sprintf("MarkInvoicePaid err %s", err))
+		}
+		return
+	}
+}
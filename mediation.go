@@ -0,0 +1,140 @@
+package smartraiden
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//how fee earnings are grouped
+const (
+	FeeEarningsByToken   = "token"
+	FeeEarningsByChannel = "channel" //channel the transfer was forwarded through
+	FeeEarningsByPeriod  = "period"
+)
+
+//defaultFeeEarningsPeriod is one day
+const defaultFeeEarningsPeriod = 24 * 3600
+
+/*
+FeeEarning is the fee earned by mediating on a token, or on a channel, or in a period of time
+*/
+type FeeEarning struct {
+	TokenAddress      common.Address `json:"token_address"`
+	ChannelIdentifier common.Hash    `json:"channel_identifier"` //empty if not grouped by channel
+	PartnerAddress    common.Address `json:"partner_address"`    //partner of the channel
+	PeriodStart       int64          `json:"period_start"`       //unix seconds, 0 if not grouped by period
+	Count             int            `json:"count"`
+	AmountIn          *big.Int       `json:"amount_in"`
+	AmountOut         *big.Int       `json:"amount_out"`
+	Fee               *big.Int       `json:"fee"`
+}
+
+/*
+recordMediation saves a pair of a mediator whose payer has paid me and payee has been paid by me.
+the pair is carried by EventWithdrawSuccess, the mediator may be finished and removed before the event is handled.
+*/
+func (rs *RaidenService) recordMediation(pair *mediatedtransfer.MediationPairState) {
+	if pair.PayerState != mediatedtransfer.StatePayerBalanceProof || pair.PayeeState != mediatedtransfer.StatePayeeBalanceProof {
+		return
+	}
+	rs.db.NewMediationRecord(&models.MediationRecord{
+		LockSecretHash: pair.PayerTransfer.LockSecretHash,
+		TokenAddress:   pair.PayerTransfer.Token,
+		PayerChannel:   pair.PayerRoute.ChannelIdentifier,
+		PayerAddress:   pair.PayerRoute.HopNode(),
+		PayeeChannel:   pair.PayeeRoute.ChannelIdentifier,
+		PayeeAddress:   pair.PayeeRoute.HopNode(),
+		AmountIn:       pair.PayerTransfer.Amount,
+		AmountOut:      pair.PayeeTransfer.Amount,
+		Fee:            new(big.Int).Sub(pair.PayerTransfer.Amount, pair.PayeeTransfer.Amount),
+		BlockNumber:    rs.GetBlockNumber(),
+		Time:           time.Now().Unix(),
+	})
+}
+
+//GetMediationRecords returns the transfers mediated between `from` and `to`, both are unix seconds, -1 means no limit
+func (r *RaidenAPI) GetMediationRecords(from, to int64) ([]*models.MediationRecord, error) {
+	return r.Raiden.db.GetMediationRecordsInTimeRange(from, to)
+}
+
+/*
+GetFeeEarnings returns the fee earned by mediating between `from` and `to`, grouped by `groupBy`:
+FeeEarningsByToken, FeeEarningsByChannel or FeeEarningsByPeriod with periods of `period` seconds, default one day.
+*/
+func (r *RaidenAPI) GetFeeEarnings(from, to int64, groupBy string, period int64) (earnings []*FeeEarning, err error) {
+	if groupBy == "" {
+		groupBy = FeeEarningsByToken
+	}
+	if groupBy != FeeEarningsByToken && groupBy != FeeEarningsByChannel && groupBy != FeeEarningsByPeriod {
+		err = errors.New("group_by must be one of token, channel and period")
+		return
+	}
+	if period < 0 {
+		err = errors.New("invalid period")
+		return
+	}
+	if period == 0 {
+		period = defaultFeeEarningsPeriod
+	}
+	records, err := r.Raiden.db.GetMediationRecordsInTimeRange(from, to)
+	if err != nil {
+		return
+	}
+	return aggregateFeeEarnings(records, groupBy, period), nil
+}
+
+func aggregateFeeEarnings(records []*models.MediationRecord, groupBy string, period int64) (earnings []*FeeEarning) {
+	type key struct {
+		token       common.Address
+		channel     common.Hash
+		periodStart int64
+	}
+	m := make(map[key]*FeeEarning)
+	for _, r := range records {
+		k := key{token: r.TokenAddress}
+		switch groupBy {
+		case FeeEarningsByChannel:
+			k.channel = r.PayeeChannel
+		case FeeEarningsByPeriod:
+			k.periodStart = r.Time - r.Time%period
+		}
+		e := m[k]
+		if e == nil {
+			e = &FeeEarning{
+				TokenAddress:      k.token,
+				ChannelIdentifier: k.channel,
+				PeriodStart:       k.periodStart,
+				AmountIn:          big.NewInt(0),
+				AmountOut:         big.NewInt(0),
+				Fee:               big.NewInt(0),
+			}
+			if k.channel != utils.EmptyHash {
+				e.PartnerAddress = r.PayeeAddress
+			}
+			m[k] = e
+			earnings = append(earnings, e)
+		}
+		e.Count++
+		e.AmountIn.Add(e.AmountIn, r.AmountIn)
+		e.AmountOut.Add(e.AmountOut, r.AmountOut)
+		e.Fee.Add(e.Fee, r.Fee)
+	}
+	sort.Slice(earnings, func(i, j int) bool {
+		if c := bytes.Compare(earnings[i].TokenAddress[:], earnings[j].TokenAddress[:]); c != 0 {
+			return c < 0
+		}
+		if c := bytes.Compare(earnings[i].ChannelIdentifier[:], earnings[j].ChannelIdentifier[:]); c != 0 {
+			return c < 0
+		}
+		return earnings[i].PeriodStart < earnings[j].PeriodStart
+	})
+	return
+}
@@ -0,0 +1,125 @@
+package smartraiden
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/mediator"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAggregateFeeEarnings(t *testing.T) {
+	token1 := utils.NewRandomAddress()
+	token2 := utils.NewRandomAddress()
+	ch1 := utils.NewRandomHash()
+	ch2 := utils.NewRandomHash()
+	newRecord := func(token common.Address, ch common.Hash, fee, time int64) *models.MediationRecord {
+		return &models.MediationRecord{
+			TokenAddress: token,
+			PayeeChannel: ch,
+			AmountIn:     big.NewInt(100 + fee),
+			AmountOut:    big.NewInt(100),
+			Fee:          big.NewInt(fee),
+			Time:         time,
+		}
+	}
+	records := []*models.MediationRecord{
+		newRecord(token1, ch1, 1, 10),
+		newRecord(token1, ch2, 2, 20),
+		newRecord(token1, ch1, 3, 110),
+		newRecord(token2, utils.NewRandomHash(), 4, 30),
+	}
+	es := aggregateFeeEarnings(records, FeeEarningsByToken, 100)
+	assert(t, 2, len(es))
+	for _, e := range es {
+		if e.TokenAddress == token1 {
+			assert(t, 3, e.Count)
+			assert(t, big.NewInt(6), e.Fee)
+			assert(t, big.NewInt(306), e.AmountIn)
+			assert(t, big.NewInt(300), e.AmountOut)
+		} else {
+			assert(t, 1, e.Count)
+			assert(t, big.NewInt(4), e.Fee)
+		}
+	}
+	es = aggregateFeeEarnings(records, FeeEarningsByChannel, 100)
+	assert(t, 3, len(es))
+	for _, e := range es {
+		if e.ChannelIdentifier == ch1 {
+			assert(t, big.NewInt(4), e.Fee)
+		}
+	}
+	es = aggregateFeeEarnings(records, FeeEarningsByPeriod, 100)
+	assert(t, 3, len(es))
+	for _, e := range es {
+		if e.TokenAddress == token1 && e.PeriodStart == 0 {
+			assert(t, big.NewInt(3), e.Fee)
+		}
+		if e.TokenAddress == token1 && e.PeriodStart == 100 {
+			assert(t, big.NewInt(3), e.Fee)
+		}
+	}
+}
+
+func TestRecordMediation(t *testing.T) {
+	db := setupDb(t, "testrecordmediation.db")
+	defer db.CloseDB()
+	rs := &RaidenService{db: db, NodeAddress: utils.NewRandomAddress(), BlockNumber: new(atomic.Value)}
+	rs.BlockNumber.Store(int64(1))
+	eh := newStateMachineEventHandler(rs)
+	token := utils.NewRandomAddress()
+	payer, payee := utils.NewRandomAddress(), utils.NewRandomAddress()
+	payerRoute := route.NewState(newTestChannel(rs.NodeAddress, payer, token, 100, 100))
+	payerRoute.Fee = utils.BigInt0
+	payeeRoute := route.NewState(newTestChannel(rs.NodeAddress, payee, token, 100, 100))
+	payeeRoute.Fee = big.NewInt(3)
+	secret := utils.NewRandomHash()
+	lockSecretHash := utils.ShaSecret(secret[:])
+	stateManager := transfer.NewStateManager(mediator.StateTransition, nil, mediator.NameMediatorTransition, lockSecretHash, token)
+	stateManager.Dispatch(&mediatedtransfer.ActionInitMediatorStateChange{
+		OurAddress: rs.NodeAddress,
+		FromTranfer: &mediatedtransfer.LockedTransferState{
+			TargetAmount:   big.NewInt(10),
+			Amount:         big.NewInt(13),
+			Token:          token,
+			Initiator:      payer,
+			Target:         payee,
+			Expiration:     100,
+			LockSecretHash: lockSecretHash,
+			Fee:            big.NewInt(3),
+		},
+		Routes:      route.NewRoutesState([]*route.State{payeeRoute}),
+		FromRoute:   payerRoute,
+		BlockNumber: 1,
+	})
+	stateManager.Dispatch(&mediatedtransfer.ReceiveSecretRevealStateChange{
+		Secret: secret,
+		Sender: payee,
+	})
+	events := stateManager.Dispatch(&mediatedtransfer.ReceiveUnlockStateChange{
+		LockSecretHash: lockSecretHash,
+		NodeAddress:    payer,
+	})
+	//the mediator is finished before its events are handled
+	assert(t, stateManager.CurrentState == nil, true)
+	for _, e := range events {
+		if _, ok := e.(*mediatedtransfer.EventWithdrawSuccess); ok {
+			eh.OnEvent(e, stateManager)
+		}
+	}
+	records, err := db.GetMediationRecordsInTimeRange(-1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 1, len(records))
+	assert(t, payee, records[0].PayeeAddress)
+	assert(t, big.NewInt(13), records[0].AmountIn)
+	assert(t, big.NewInt(10), records[0].AmountOut)
+	assert(t, big.NewInt(3), records[0].Fee)
+}
@@ -282,6 +282,9 @@ func (mh *raidenMessageHandler) messageAnnounceDisposed(msg *encoding.AnnounceDi
 		Lock:    msg.Lock,
 		Message: msg,
 	}
+	if mh.raiden.isRoutingFailure(msg.Lock.LockSecretHash, ch.TokenAddress, msg.Sender) {
+		mh.raiden.MissionControl.ReportFailure(ch.TokenAddress, mh.raiden.NodeAddress, msg.Sender)
+	}
 	mh.raiden.StateMachineEventHandler.dispatchBySecretHash(msg.Lock.LockSecretHash, stateChange)
 	return nil
 }
@@ -381,6 +384,7 @@ func (mh *raidenMessageHandler) messageDirectTransfer(msg *encoding.DirectTransf
 		Amount:            amount,
 		Initiator:         msg.Sender,
 		ChannelIdentifier: msg.ChannelIdentifier,
+		PaymentData:       msg.PaymentData,
 	}
 	mh.raiden.updateChannelAndSaveAck(ch, msg.Tag())
 	err = mh.raiden.StateMachineEventHandler.OnEvent(receiveSuccess, nil)
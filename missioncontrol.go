@@ -0,0 +1,188 @@
+package smartraiden
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+MissionControl remembers which hops failed or succeeded when routing transfers,
+so the next transfer tries reliable hops first and skips hops failing again and again.
+both the edge from a node to its partner on a token and the partner itself on all tokens are recorded,
+the counts halve every params.MissionControlHalfLife, so a hop is forgiven as time goes.
+*/
+type MissionControl struct {
+	db        *models.ModelDB
+	lock      sync.Mutex
+	histories map[string]*models.RouteHistory
+	now       func() time.Time
+}
+
+//forgottenFailures failures decayed below this don't penalise a hop any more
+const forgottenFailures = 0.1
+
+//NewMissionControl creates a MissionControl and loads route histories from `db`
+func NewMissionControl(db *models.ModelDB) (mc *MissionControl, err error) {
+	mc = &MissionControl{
+		db:        db,
+		histories: make(map[string]*models.RouteHistory),
+		now:       time.Now,
+	}
+	hs, err := db.GetAllRouteHistories()
+	if err != nil {
+		return
+	}
+	for _, h := range hs {
+		mc.histories[h.Key] = h
+	}
+	return
+}
+
+//ReportFailure records a transfer routed from `from` to `to` failed, either refused by a hop other than the target or expired
+func (mc *MissionControl) ReportFailure(tokenAddress, from, to common.Address) {
+	mc.report(tokenAddress, from, to, false)
+}
+
+//ReportSuccess records a transfer routed from `from` to `to` succeeded
+func (mc *MissionControl) ReportSuccess(tokenAddress, from, to common.Address) {
+	mc.report(tokenAddress, from, to, true)
+}
+
+func (mc *MissionControl) report(tokenAddress, from, to common.Address, success bool) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	now := mc.now().Unix()
+	for _, key := range []string{models.RouteHistoryKey(tokenAddress, from, to), models.RouteHistoryKey(utils.EmptyAddress, utils.EmptyAddress, to)} {
+		h := mc.histories[key]
+		if h == nil {
+			h = &models.RouteHistory{To: to}
+			if key != to.String() {
+				h.TokenAddress = tokenAddress
+				h.From = from
+			}
+			mc.histories[key] = h
+		}
+		decay(h, now)
+		if success {
+			h.Successes++
+			h.LastSuccess = now
+		} else {
+			h.Failures++
+			h.LastFailure = now
+		}
+		err := mc.db.SaveRouteHistory(h)
+		if err != nil {
+			log.Error(fmt.Sprintf("SaveRouteHistory %s err %s", h.Key, err))
+		}
+	}
+}
+
+/*
+Penalty returns how likely a transfer routed from `from` to `to` fails, between 0 and 1,
+a hop is excluded if it has failed params.MissionControlMaxFailures times recently and never succeeded since.
+the worse of the edge and the node is taken.
+*/
+func (mc *MissionControl) Penalty(tokenAddress, from, to common.Address) (penalty float64, exclude bool) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	now := mc.now().Unix()
+	for _, key := range []string{models.RouteHistoryKey(tokenAddress, from, to), models.RouteHistoryKey(utils.EmptyAddress, utils.EmptyAddress, to)} {
+		h := mc.histories[key]
+		if h == nil {
+			continue
+		}
+		h2 := *h
+		decay(&h2, now)
+		if h2.Failures < forgottenFailures {
+			continue
+		}
+		if p := h2.Failures / (h2.Failures + h2.Successes + 1); p > penalty {
+			penalty = p
+		}
+		if h2.Failures >= params.MissionControlMaxFailures && h2.LastFailure > h2.LastSuccess {
+			exclude = true
+		}
+	}
+	return
+}
+
+//GetRouteHistories returns all the route histories with counts decayed to now
+func (mc *MissionControl) GetRouteHistories() (hs []*models.RouteHistory) {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	now := mc.now().Unix()
+	for _, h := range mc.histories {
+		h2 := *h
+		decay(&h2, now)
+		hs = append(hs, &h2)
+	}
+	sort.Slice(hs, func(i, j int) bool {
+		return hs[i].Key < hs[j].Key
+	})
+	return
+}
+
+//Reset forgets all the route histories
+func (mc *MissionControl) Reset() error {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	mc.histories = make(map[string]*models.RouteHistory)
+	return mc.db.RemoveAllRouteHistories()
+}
+
+//decay brings counts of `h` from its update time to `now`
+func decay(h *models.RouteHistory, now int64) {
+	if h.UpdateTime > 0 && now > h.UpdateTime {
+		f := math.Pow(0.5, float64(now-h.UpdateTime)/params.MissionControlHalfLife.Seconds())
+		h.Failures *= f
+		h.Successes *= f
+	}
+	h.UpdateTime = now
+}
+
+/*
+isRoutingFailure tells whether a transfer disposed by `sender` failed to be routed,
+a target rejecting a transfer on purpose, like a hold payment, is not.
+rebalances are never counted, when I reject one coming back through another channel,
+the AnnounceDisposed goes back around the circle and the first hop would be blamed for it.
+transfers whose target is unknown, such as ones restored after a restart, are not counted either.
+*/
+func (rs *RaidenService) isRoutingFailure(lockSecretHash common.Hash, tokenAddress, sender common.Address) bool {
+	mgr := rs.Transfer2StateManager[utils.Sha3(lockSecretHash[:], tokenAddress[:])]
+	if mgr == nil {
+		return false
+	}
+	var target common.Address
+	switch state := mgr.CurrentState.(type) {
+	case *mediatedtransfer.InitiatorState:
+		target = state.Transfer.Target
+	case *mediatedtransfer.MediatorState:
+		if len(state.TransfersPair) == 0 {
+			return false
+		}
+		target = state.TransfersPair[0].PayerTransfer.Target
+	default:
+		return false
+	}
+	return target != sender && target != rs.NodeAddress
+}
+
+//GetRouteHistories returns what mission control knows about hops
+func (r *RaidenAPI) GetRouteHistories() []*models.RouteHistory {
+	return r.Raiden.MissionControl.GetRouteHistories()
+}
+
+//ResetRouteHistories makes mission control forget all it knows about hops
+func (r *RaidenAPI) ResetRouteHistories() error {
+	return r.Raiden.MissionControl.Reset()
+}
@@ -0,0 +1,108 @@
+package smartraiden
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMissionControl(t *testing.T) {
+	db := setupDb(t, "testmissioncontrol.db")
+	defer db.CloseDB()
+	mc, err := NewMissionControl(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	mc.now = func() time.Time { return now }
+	token := utils.NewRandomAddress()
+	token2 := utils.NewRandomAddress()
+	me := utils.NewRandomAddress()
+	bob := utils.NewRandomAddress()
+	penalty, exclude := mc.Penalty(token, me, bob)
+	assert(t, 0.0, penalty)
+	assert(t, false, exclude)
+	mc.ReportFailure(token, me, bob)
+	penalty, exclude = mc.Penalty(token, me, bob)
+	assert(t, 0.5, penalty)
+	assert(t, false, exclude)
+	//failure of the node counts on other tokens too
+	penalty, _ = mc.Penalty(token2, me, bob)
+	assert(t, 0.5, penalty)
+	mc.ReportFailure(token, me, bob)
+	mc.ReportFailure(token, me, bob)
+	_, exclude = mc.Penalty(token, me, bob)
+	assert(t, true, exclude)
+	mc.ReportSuccess(token, me, bob)
+	penalty, exclude = mc.Penalty(token, me, bob)
+	assert(t, 0.6, penalty)
+	assert(t, false, exclude)
+	//reload from db
+	mc, err = NewMissionControl(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.now = func() time.Time { return now.Add(params.MissionControlHalfLife) }
+	hs := mc.GetRouteHistories()
+	assert(t, 2, len(hs))
+	assert(t, 1.5, hs[0].Failures)
+	assert(t, 0.5, hs[0].Successes)
+	mc.now = func() time.Time { return now.Add(params.MissionControlHalfLife * 3) }
+	penalty, _ = mc.Penalty(token, me, bob)
+	if penalty <= 0 || penalty >= 0.6 {
+		t.Errorf("penalty should decay,got %f", penalty)
+	}
+	mc.now = func() time.Time { return now.Add(params.MissionControlHalfLife * 10) }
+	penalty, _ = mc.Penalty(token, me, bob)
+	assert(t, 0.0, penalty)
+	err = mc.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 0, len(mc.GetRouteHistories()))
+	hs, err = db.GetAllRouteHistories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 0, len(hs))
+}
+
+func TestIsRoutingFailure(t *testing.T) {
+	token := utils.NewRandomAddress()
+	target := utils.NewRandomAddress()
+	hop := utils.NewRandomAddress()
+	lockSecretHash := utils.NewRandomHash()
+	rs := &RaidenService{NodeAddress: utils.NewRandomAddress(), Transfer2StateManager: make(map[common.Hash]*transfer.StateManager)}
+	if rs.isRoutingFailure(lockSecretHash, token, hop) {
+		t.Error("unknown transfer should not be a routing failure")
+	}
+	tr := &mediatedtransfer.LockedTransferState{Target: target, Token: token, LockSecretHash: lockSecretHash}
+	smkey := utils.Sha3(lockSecretHash[:], token[:])
+	rs.Transfer2StateManager[smkey] = &transfer.StateManager{CurrentState: &mediatedtransfer.InitiatorState{Transfer: tr}}
+	if !rs.isRoutingFailure(lockSecretHash, token, hop) {
+		t.Error("hop refusing should be a routing failure")
+	}
+	if rs.isRoutingFailure(lockSecretHash, token, target) {
+		t.Error("target refusing should not be a routing failure")
+	}
+	rs.Transfer2StateManager[smkey] = &transfer.StateManager{CurrentState: &mediatedtransfer.MediatorState{
+		TransfersPair: []*mediatedtransfer.MediationPairState{{PayerTransfer: tr}},
+	}}
+	if !rs.isRoutingFailure(lockSecretHash, token, hop) {
+		t.Error("hop refusing should be a routing failure")
+	}
+	if rs.isRoutingFailure(lockSecretHash, token, target) {
+		t.Error("target refusing should not be a routing failure")
+	}
+	//a rebalance is a transfer to myself
+	tr.Target = rs.NodeAddress
+	rs.Transfer2StateManager[smkey] = &transfer.StateManager{CurrentState: &mediatedtransfer.InitiatorState{Transfer: tr}}
+	if rs.isRoutingFailure(lockSecretHash, token, hop) {
+		t.Error("rebalance should not be a routing failure")
+	}
+}
@@ -277,10 +277,10 @@ func (a *API) TokensEvent(fromBlock, toBlock int64, tokenAddress string) (events
 	return
 }
 
-//ChannelsEvent GET /api/1/events/channels/0x2a65aca4d5fc5b5c859090a6c34d164135398226?from_block=1337
-func (a *API) ChannelsEvent(fromBlock, toBlock int64, channelAddress string) (eventsString string, err error) {
+//ChannelsEvent GET /api/1/events/channels/0x2a65aca4d5fc5b5c859090a6c34d164135398226?from_block=1337&transfers=true
+func (a *API) ChannelsEvent(fromBlock, toBlock int64, channelAddress string, withTransfers bool) (eventsString string, err error) {
 	channel := common.HexToHash(channelAddress)
-	events, err := a.api.GetChannelEvents(channel, fromBlock, toBlock)
+	events, err := a.api.GetChannelEvents(channel, fromBlock, toBlock, withTransfers)
 	if err != nil {
 		log.Error(err.Error())
 		return
@@ -395,6 +395,43 @@ func (a *API) Transfers(tokenAddress, targetAddress string, amountstr string, fe
 	return marshal(req)
 }
 
+/*
+GetRoutes returns the routes a transfer of `amountstr` to `targetAddress` would try now, without sending anything.
+*/
+func (a *API) GetRoutes(tokenAddress, targetAddress string, amountstr string) (routes string, err error) {
+	tokenAddr, err := utils.HexToAddressWithoutValidation(tokenAddress)
+	if err != nil {
+		return
+	}
+	targetAddr, err := utils.HexToAddressWithoutValidation(targetAddress)
+	if err != nil {
+		return
+	}
+	amount, ok := new(big.Int).SetString(amountstr, 0)
+	if !ok {
+		err = errors.New("invalid amount")
+		return
+	}
+	rs, err := a.api.GetRoutes(tokenAddr, targetAddr, amount)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	return marshal(rs)
+}
+
+/*
+CancelTransfer cancels the transfer with `lockSecretHash` started by this node,
+it fails if the secret of this transfer is already revealed.
+*/
+func (a *API) CancelTransfer(lockSecretHash string) (err error) {
+	err = a.api.CancelTransfer(common.HexToHash(lockSecretHash))
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return
+}
+
 /*
 TokenSwap token swap for maker
 role: "maker" or "taker"
@@ -542,6 +579,105 @@ func (a *API) GetReceivedTransfers(from, to int64) (r string, err error) {
 	return
 }
 
+/*
+CreateSchedule creates a schedule of payments, `schedule` is json like
+{"token_address":"0x...","target_address":"0x...","amount":10,"interval":3600,"max_count":24}
+`interval` is in seconds, or use `cron` like "0 8 * * 1" in UTC instead.
+*/
+func (a *API) CreateSchedule(schedule string) (r string, err error) {
+	req := &v1.ScheduleData{}
+	err = json.Unmarshal([]byte(schedule), req)
+	if err != nil {
+		return
+	}
+	s, err := req.ToSchedule(true)
+	if err != nil {
+		return
+	}
+	s, err = a.api.CreatePaymentSchedule(s)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	return marshal(s)
+}
+
+/*
+UpdateSchedule changes amount, fee, timing and limits of schedule `id`,
+`status` in `schedule` pauses it with "paused" and resumes it with "active".
+*/
+func (a *API) UpdateSchedule(id string, schedule string) (r string, err error) {
+	req := &v1.ScheduleData{}
+	err = json.Unmarshal([]byte(schedule), req)
+	if err != nil {
+		return
+	}
+	s, _ := req.ToSchedule(false)
+	s, err = a.api.UpdatePaymentSchedule(id, s)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	return marshal(s)
+}
+
+//GetSchedules returns all the payment schedules
+func (a *API) GetSchedules() (r string, err error) {
+	ss, err := a.api.GetPaymentSchedules()
+	if err != nil {
+		return
+	}
+	return marshal(ss)
+}
+
+//GetSchedule returns the payment schedule with `id`
+func (a *API) GetSchedule(id string) (r string, err error) {
+	s, err := a.api.GetPaymentSchedule(id)
+	if err != nil {
+		return
+	}
+	return marshal(s)
+}
+
+//RemoveSchedule removes the payment schedule with `id` and its history
+func (a *API) RemoveSchedule(id string) (err error) {
+	err = a.api.RemovePaymentSchedule(id)
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return
+}
+
+//GetScheduleRuns returns history of payments of the schedule with `id`
+func (a *API) GetScheduleRuns(id string) (r string, err error) {
+	runs, err := a.api.GetScheduleRuns(id)
+	if err != nil {
+		return
+	}
+	return marshal(runs)
+}
+
+/*
+GetFeeEarnings returns the fee earned by mediating between `from` and `to`, both are unix seconds, -1 means no limit.
+groupBy is "token","channel" or "period", period is seconds of a period, 0 means one day.
+*/
+func (a *API) GetFeeEarnings(from, to int64, groupBy string, period int64) (r string, err error) {
+	earnings, err := a.api.GetFeeEarnings(from, to, groupBy, period)
+	if err != nil {
+		return
+	}
+	return marshal(earnings)
+}
+
+//GetMediationRecords returns the transfers mediated between `from` and `to`
+func (a *API) GetMediationRecords(from, to int64) (r string, err error) {
+	records, err := a.api.GetMediationRecords(from, to)
+	if err != nil {
+		return
+	}
+	return marshal(records)
+}
+
 // Subscription represents an event subscription where events are
 // delivered on a data channel.
 type Subscription struct {
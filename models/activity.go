@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/gob"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+PartnerActivity is the last time a partner is known to be online,
+from its network status, messages received from it and its answers to health check pings.
+*/
+type PartnerActivity struct {
+	Key      []byte         `json:"-" storm:"id"`
+	Address  common.Address `json:"address"`
+	LastSeen int64          `json:"last_seen"`
+}
+
+//status of reaping an idle channel
+const (
+	ReapStatusNone                = ""                     //channel is not idle
+	ReapStatusCooperativeSettling = "cooperative-settling" //cooperative settle request has been sent
+	ReapStatusClosing             = "closing"              //partner is unreachable, channel is closed and will be settled
+)
+
+/*
+ChannelActivity is the last time a transfer happens on a channel,
+a transfer is detected by the change of nonces of the balance proofs.
+*/
+type ChannelActivity struct {
+	Key               []byte      `json:"-" storm:"id"`
+	ChannelIdentifier common.Hash `json:"channel_identifier"`
+	OpenBlockNumber   int64       `json:"open_block_number"`
+	Nonce             uint64      `json:"nonce"`            //sum of nonces of both balance proofs
+	LastTransferAt    int64       `json:"last_transfer_at"` //the time channel is first seen if no transfer
+	ReapStatus        string      `json:"reap_status"`
+	ReapStartedAt     int64       `json:"reap_started_at"`
+	Error             string      `json:"error"` //error of last reap attempt
+}
+
+func init() {
+	gob.Register(&PartnerActivity{})
+	gob.Register(&ChannelActivity{})
+}
+
+//SavePartnerActivity create or update last seen time of a partner
+func (model *ModelDB) SavePartnerActivity(a *PartnerActivity) error {
+	a.Key = a.Address[:]
+	return model.db.Save(a)
+}
+
+//GetAllPartnerActivities returns last seen time of all the partners
+func (model *ModelDB) GetAllPartnerActivities() (as []*PartnerActivity, err error) {
+	err = model.db.All(&as)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//SaveChannelActivity create or update activity of a channel
+func (model *ModelDB) SaveChannelActivity(a *ChannelActivity) error {
+	a.Key = a.ChannelIdentifier[:]
+	return model.db.Save(a)
+}
+
+//GetChannelActivity returns activity of channel `channelIdentifier`
+func (model *ModelDB) GetChannelActivity(channelIdentifier common.Hash) (a *ChannelActivity, err error) {
+	a = new(ChannelActivity)
+	err = model.db.One("Key", channelIdentifier[:], a)
+	return
+}
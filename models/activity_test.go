@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_PartnerActivity(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	as, err := model.GetAllPartnerActivities()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 0, len(as))
+	a := &PartnerActivity{
+		Address:  utils.NewRandomAddress(),
+		LastSeen: 100,
+	}
+	err = model.SavePartnerActivity(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a.LastSeen = 200
+	err = model.SavePartnerActivity(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	as, err = model.GetAllPartnerActivities()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 1, len(as))
+	assert.EqualValues(t, a, as[0])
+}
+
+func TestModelDB_ChannelActivity(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	ch := utils.NewRandomHash()
+	a := &ChannelActivity{
+		ChannelIdentifier: ch,
+		OpenBlockNumber:   3,
+		Nonce:             5,
+		LastTransferAt:    100,
+	}
+	err := model.SaveChannelActivity(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a.ReapStatus = ReapStatusCooperativeSettling
+	a.ReapStartedAt = 200
+	err = model.SaveChannelActivity(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a2, err := model.GetChannelActivity(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, a, a2)
+	_, err = model.GetChannelActivity(utils.NewRandomHash())
+	assert.NotEqual(t, nil, err)
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/asdine/storm"
+)
+
+//roles of api key
+const (
+	APIKeyRoleReadOnly = "readonly" //queries and events only
+	APIKeyRoleOperator = "operator" //everything, including transfers, channel operations and debug
+)
+
+/*
+APIKey grants access to the REST API,
+only hash of the key is saved, the key itself is shown once when created.
+*/
+type APIKey struct {
+	Name      string `json:"name" storm:"id"`
+	Hash      string `json:"-" storm:"unique"` //hex encoded sha256 of the key
+	Role      string `json:"role"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func init() {
+	gob.Register(&APIKey{})
+}
+
+//HashAPIKey returns the hash of `key` which is saved in db
+func HashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+//NewAPIKey creates a random key with `name` and `role`
+func (model *ModelDB) NewAPIKey(name, role string) (key string, err error) {
+	if role != APIKeyRoleReadOnly && role != APIKeyRoleOperator {
+		err = fmt.Errorf("unknown role %s", role)
+		return
+	}
+	if len(name) == 0 {
+		err = fmt.Errorf("api key name is empty")
+		return
+	}
+	_, err = model.GetAPIKey(name)
+	if err == nil {
+		err = fmt.Errorf("api key %s already exists", name)
+		return
+	}
+	key = hex.EncodeToString(utils.Random(32))
+	err = model.db.Save(&APIKey{
+		Name:      name,
+		Hash:      HashAPIKey(key),
+		Role:      role,
+		CreatedAt: time.Now().Unix(),
+	})
+	return
+}
+
+//GetAPIKey returns the key named `name`
+func (model *ModelDB) GetAPIKey(name string) (k *APIKey, err error) {
+	k = new(APIKey)
+	err = model.db.One("Name", name, k)
+	return
+}
+
+//GetAPIKeyByKey returns the api key whose hash matches `key`
+func (model *ModelDB) GetAPIKeyByKey(key string) (k *APIKey, err error) {
+	k = new(APIKey)
+	err = model.db.One("Hash", HashAPIKey(key), k)
+	return
+}
+
+//GetAPIKeys returns all the api keys
+func (model *ModelDB) GetAPIKeys() (ks []*APIKey, err error) {
+	err = model.db.All(&ks)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//HasAPIKeys returns true if any api key exists
+func (model *ModelDB) HasAPIKeys() bool {
+	n, err := model.db.Count(&APIKey{})
+	return err == nil && n > 0
+}
+
+//RemoveAPIKey removes the key named `name`
+func (model *ModelDB) RemoveAPIKey(name string) error {
+	k, err := model.GetAPIKey(name)
+	if err != nil {
+		return err
+	}
+	return model.db.DeleteStruct(k)
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_APIKey(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	ks, err := model.GetAPIKeys()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(ks), 0)
+	_, err = model.NewAPIKey("a", "admin")
+	if err == nil {
+		t.Error("should fail with unknown role")
+		return
+	}
+	key, err := model.NewAPIKey("a", APIKeyRoleReadOnly)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.NewAPIKey("a", APIKeyRoleOperator)
+	if err == nil {
+		t.Error("should fail with duplicate name")
+		return
+	}
+	k, err := model.GetAPIKeyByKey(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, k.Name, "a")
+	assert.EqualValues(t, k.Role, APIKeyRoleReadOnly)
+	_, err = model.GetAPIKeyByKey(key + "0")
+	if err == nil {
+		t.Error("should not find wrong key")
+		return
+	}
+	err = model.RemoveAPIKey("a")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.GetAPIKeyByKey(key)
+	if err == nil {
+		t.Error("should not find removed key")
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/gob"
+	"math/big"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+BalancePolicy keeps balance of channels within [MinBalance,MaxBalance],
+a channel whose balance is less than MinBalance is deposited to TargetBalance,
+a channel whose balance is more than MaxBalance is withdrawn to TargetBalance.
+a policy of a channel takes precedence over the policy of its token.
+*/
+type BalancePolicy struct {
+	Key               string         `json:"-" storm:"id"`
+	TokenAddress      common.Address `json:"token_address"`
+	ChannelIdentifier common.Hash    `json:"channel_identifier"` //empty for all the channels of the token
+	MinBalance        *big.Int       `json:"min_balance"`        //0 means never deposit
+	MaxBalance        *big.Int       `json:"max_balance"`        //0 means never withdraw
+	TargetBalance     *big.Int       `json:"target_balance"`
+	MinAmount         *big.Int       `json:"min_amount"`    //amount less than this is not worth the gas
+	MaxGasPrice       *big.Int       `json:"max_gas_price"` //wei, 0 means no limit
+	Interval          int64          `json:"interval"`      //blocks between two actions of a channel
+}
+
+//BalancePolicyKey returns key of the policy of a channel or a token when `channelIdentifier` is empty
+func BalancePolicyKey(tokenAddress common.Address, channelIdentifier common.Hash) string {
+	if channelIdentifier == (common.Hash{}) {
+		return tokenAddress.String()
+	}
+	return channelIdentifier.String()
+}
+
+//status of actions of balance policies
+const (
+	BalancePolicyActionDeposit  = "deposit"
+	BalancePolicyActionWithdraw = "withdraw"
+
+	BalancePolicyStatusPending = "pending" //tx or withdraw request has been sent,waiting for result
+	BalancePolicyStatusFailed  = "failed"
+	BalancePolicyStatusSuccess = "success"
+)
+
+/*
+BalancePolicyAction is the last deposit or withdraw of a channel made by its balance policy
+*/
+type BalancePolicyAction struct {
+	Key               []byte      `json:"-" storm:"id"`
+	ChannelIdentifier common.Hash `json:"channel_identifier"`
+	Action            string      `json:"action"`
+	Amount            *big.Int    `json:"amount"`
+	BlockNumber       int64       `json:"block_number"`
+	Status            string      `json:"status"`
+	Error             string      `json:"error"`
+}
+
+func init() {
+	gob.Register(&BalancePolicy{})
+	gob.Register(&BalancePolicyAction{})
+}
+
+//SaveBalancePolicy create or update a balance policy
+func (model *ModelDB) SaveBalancePolicy(p *BalancePolicy) error {
+	p.Key = BalancePolicyKey(p.TokenAddress, p.ChannelIdentifier)
+	return model.db.Save(p)
+}
+
+//GetBalancePolicy returns the policy with `key`
+func (model *ModelDB) GetBalancePolicy(key string) (p *BalancePolicy, err error) {
+	p = new(BalancePolicy)
+	err = model.db.One("Key", key, p)
+	return
+}
+
+//GetAllBalancePolicies returns all the balance policies
+func (model *ModelDB) GetAllBalancePolicies() (ps []*BalancePolicy, err error) {
+	err = model.db.All(&ps)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//RemoveBalancePolicy removes the policy with `key`
+func (model *ModelDB) RemoveBalancePolicy(key string) error {
+	p, err := model.GetBalancePolicy(key)
+	if err != nil {
+		return err
+	}
+	return model.db.DeleteStruct(p)
+}
+
+//SaveBalancePolicyAction create or update the last action of a channel
+func (model *ModelDB) SaveBalancePolicyAction(a *BalancePolicyAction) error {
+	a.Key = a.ChannelIdentifier[:]
+	return model.db.Save(a)
+}
+
+//GetBalancePolicyAction returns the last action of channel `channelIdentifier`
+func (model *ModelDB) GetBalancePolicyAction(channelIdentifier common.Hash) (a *BalancePolicyAction, err error) {
+	a = new(BalancePolicyAction)
+	err = model.db.One("Key", channelIdentifier[:], a)
+	return
+}
+
+//GetAllBalancePolicyActions returns the last actions of all the channels
+func (model *ModelDB) GetAllBalancePolicyActions() (as []*BalancePolicyAction, err error) {
+	err = model.db.All(&as)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
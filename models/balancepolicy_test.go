@@ -0,0 +1,111 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_BalancePolicy(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	ch := utils.NewRandomHash()
+	p1 := &BalancePolicy{
+		TokenAddress:  token,
+		MinBalance:    big.NewInt(10),
+		MaxBalance:    big.NewInt(100),
+		TargetBalance: big.NewInt(50),
+		MinAmount:     big.NewInt(0),
+		MaxGasPrice:   big.NewInt(0),
+		Interval:      10,
+	}
+	p2 := &BalancePolicy{
+		TokenAddress:      token,
+		ChannelIdentifier: ch,
+		MinBalance:        big.NewInt(20),
+		MaxBalance:        big.NewInt(0),
+		TargetBalance:     big.NewInt(20),
+		MinAmount:         big.NewInt(5),
+		MaxGasPrice:       big.NewInt(1000),
+		Interval:          20,
+	}
+	err := model.SaveBalancePolicy(p1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.SaveBalancePolicy(p2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	p, err := model.GetBalancePolicy(BalancePolicyKey(token, ch))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, p2, p)
+	p, err = model.GetBalancePolicy(BalancePolicyKey(token, utils.EmptyHash))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, p1, p)
+	ps, err := model.GetAllBalancePolicies()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 2, len(ps))
+	err = model.RemoveBalancePolicy(p1.Key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.GetBalancePolicy(p1.Key)
+	assert.NotEqual(t, nil, err)
+	err = model.RemoveBalancePolicy(p1.Key)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestModelDB_BalancePolicyAction(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	ch := utils.NewRandomHash()
+	a := &BalancePolicyAction{
+		ChannelIdentifier: ch,
+		Action:            BalancePolicyActionDeposit,
+		Amount:            big.NewInt(10),
+		BlockNumber:       30,
+		Status:            BalancePolicyStatusPending,
+	}
+	err := model.SaveBalancePolicyAction(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a.Status = BalancePolicyStatusFailed
+	a.Error = "insufficient funds"
+	err = model.SaveBalancePolicyAction(a)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	a2, err := model.GetBalancePolicyAction(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, a, a2)
+	_, err = model.GetBalancePolicyAction(utils.NewRandomHash())
+	assert.NotEqual(t, nil, err)
+	as, err := model.GetAllBalancePolicyActions()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 1, len(as))
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//status of a batch transfer
+const (
+	BatchTransferStatusRunning  = "running"
+	BatchTransferStatusFinished = "finished"
+)
+
+//status of a transfer in a batch
+const (
+	BatchTransferItemWaiting = "waiting" //not started yet
+	BatchTransferItemPending = "pending" //transfer is going on
+	BatchTransferItemSuccess = "success"
+	BatchTransferItemFailed  = "failed"
+	//the node restarted while the transfer was going on, it may still be unlocked or expire
+	BatchTransferItemInterrupted = "interrupted"
+)
+
+//BatchTransferItem is one transfer of a batch
+type BatchTransferItem struct {
+	Token      common.Address `json:"token_address"`
+	Target     common.Address `json:"target_address"`
+	Amount     *big.Int       `json:"amount"`
+	Fee        *big.Int       `json:"fee"`
+	Identifier uint64         `json:"identifier"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	//lock secret hash of the transfer started for this item
+	LockSecretHash common.Hash `json:"lock_secret_hash,omitempty"`
+}
+
+//BatchTransferTotal is the sum of amounts of a token in a batch
+type BatchTransferTotal struct {
+	Amount    *big.Int `json:"amount"`
+	Succeeded *big.Int `json:"succeeded"`
+	Failed    *big.Int `json:"failed"`
+}
+
+/*
+BatchTransfer is a list of transfers started by one request,
+they are sent at most `Concurrency` at a time.
+*/
+type BatchTransfer struct {
+	ID          string                                 `json:"id" storm:"id"`
+	Concurrency int                                    `json:"concurrency"`
+	Status      string                                 `json:"status"`
+	Items       []*BatchTransferItem                   `json:"items"`
+	Waiting     int                                    `json:"waiting"`
+	Pending     int                                    `json:"pending"`
+	Succeeded   int                                    `json:"succeeded"`
+	Failed      int                                    `json:"failed"`
+	Interrupted int                                    `json:"interrupted"`
+	Totals      map[common.Address]*BatchTransferTotal `json:"totals"` //token -> amounts
+	CreatedAt   int64                                  `json:"created_at"`
+	FinishedAt  int64                                  `json:"finished_at"`
+}
+
+func init() {
+	gob.Register(&BatchTransfer{})
+}
+
+//updateTotals counts the items of each status and sums the amounts of each token
+func (b *BatchTransfer) updateTotals() {
+	b.Waiting, b.Pending, b.Succeeded, b.Failed, b.Interrupted = 0, 0, 0, 0, 0
+	b.Totals = make(map[common.Address]*BatchTransferTotal)
+	for _, item := range b.Items {
+		t := b.Totals[item.Token]
+		if t == nil {
+			t = &BatchTransferTotal{
+				Amount:    big.NewInt(0),
+				Succeeded: big.NewInt(0),
+				Failed:    big.NewInt(0),
+			}
+			b.Totals[item.Token] = t
+		}
+		t.Amount.Add(t.Amount, item.Amount)
+		switch item.Status {
+		case BatchTransferItemWaiting:
+			b.Waiting++
+		case BatchTransferItemPending:
+			b.Pending++
+		case BatchTransferItemSuccess:
+			b.Succeeded++
+			t.Succeeded.Add(t.Succeeded, item.Amount)
+		case BatchTransferItemFailed:
+			b.Failed++
+			t.Failed.Add(t.Failed, item.Amount)
+		case BatchTransferItemInterrupted:
+			b.Interrupted++
+		}
+	}
+}
+
+//NewBatchTransfer save a new batch, all of its transfers are waiting
+func (model *ModelDB) NewBatchTransfer(b *BatchTransfer) error {
+	_, err := model.GetBatchTransfer(b.ID)
+	if err == nil {
+		return fmt.Errorf("batch transfer %s already exists", b.ID)
+	}
+	b.Status = BatchTransferStatusRunning
+	for _, item := range b.Items {
+		item.Status = BatchTransferItemWaiting
+	}
+	b.CreatedAt = time.Now().Unix()
+	b.updateTotals()
+	return model.db.Save(b)
+}
+
+//UpdateBatchTransfer saves status of `b` and its transfers
+func (model *ModelDB) UpdateBatchTransfer(b *BatchTransfer) error {
+	b.updateTotals()
+	return model.db.Save(b)
+}
+
+//GetBatchTransfer returns the batch with `id`
+func (model *ModelDB) GetBatchTransfer(id string) (b *BatchTransfer, err error) {
+	b = new(BatchTransfer)
+	err = model.db.One("ID", id, b)
+	return
+}
+
+//GetBatchTransfers returns all the batches
+func (model *ModelDB) GetBatchTransfers() (bs []*BatchTransfer, err error) {
+	err = model.db.All(&bs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+/*
+InterruptRunningBatchTransfers finishes batches which were running when the node stopped, call it on startup.
+transfers not started yet fail, transfers going on are interrupted, they are never resumed.
+*/
+func (model *ModelDB) InterruptRunningBatchTransfers() (bs []*BatchTransfer, err error) {
+	all, err := model.GetBatchTransfers()
+	if err != nil {
+		return
+	}
+	for _, b := range all {
+		if b.Status != BatchTransferStatusRunning {
+			continue
+		}
+		for _, item := range b.Items {
+			switch item.Status {
+			case BatchTransferItemWaiting:
+				item.Status = BatchTransferItemFailed
+				item.Error = "not started before the node restarted"
+			case BatchTransferItemPending:
+				item.Status = BatchTransferItemInterrupted
+			}
+		}
+		b.Status = BatchTransferStatusFinished
+		b.FinishedAt = time.Now().Unix()
+		err = model.UpdateBatchTransfer(b)
+		if err != nil {
+			return
+		}
+		bs = append(bs, b)
+	}
+	return
+}
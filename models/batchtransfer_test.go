@@ -0,0 +1,117 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_BatchTransfer(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	b := &BatchTransfer{
+		ID:          utils.RandomString(10),
+		Concurrency: 2,
+		Items: []*BatchTransferItem{
+			{Token: token, Target: utils.NewRandomAddress(), Amount: big.NewInt(10), Fee: big.NewInt(0)},
+			{Token: token, Target: utils.NewRandomAddress(), Amount: big.NewInt(20), Fee: big.NewInt(0)},
+			{Token: token, Target: utils.NewRandomAddress(), Amount: big.NewInt(30), Fee: big.NewInt(0)},
+		},
+	}
+	err := model.NewBatchTransfer(b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.NewBatchTransfer(b)
+	if err == nil {
+		t.Error("should fail with duplicate id")
+		return
+	}
+	assert.EqualValues(t, 3, b.Waiting)
+	assert.EqualValues(t, big.NewInt(60), b.Totals[token].Amount)
+	b.Items[0].Status = BatchTransferItemSuccess
+	b.Items[1].Status = BatchTransferItemFailed
+	b.Items[1].Error = "no route"
+	b.Items[2].Status = BatchTransferItemPending
+	err = model.UpdateBatchTransfer(b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b2, err := model.GetBatchTransfer(b.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, BatchTransferStatusRunning, b2.Status)
+	assert.EqualValues(t, 0, b2.Waiting)
+	assert.EqualValues(t, 1, b2.Pending)
+	assert.EqualValues(t, 1, b2.Succeeded)
+	assert.EqualValues(t, 1, b2.Failed)
+	assert.EqualValues(t, big.NewInt(10), b2.Totals[token].Succeeded)
+	assert.EqualValues(t, big.NewInt(20), b2.Totals[token].Failed)
+	bs, err := model.GetBatchTransfers()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 1, len(bs))
+	_, err = model.GetBatchTransfer("notexist")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestModelDB_InterruptRunningBatchTransfers(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	b := &BatchTransfer{
+		ID:          utils.RandomString(10),
+		Concurrency: 1,
+		Items: []*BatchTransferItem{
+			{Token: token, Target: utils.NewRandomAddress(), Amount: big.NewInt(10), Fee: big.NewInt(0)},
+			{Token: token, Target: utils.NewRandomAddress(), Amount: big.NewInt(20), Fee: big.NewInt(0)},
+			{Token: token, Target: utils.NewRandomAddress(), Amount: big.NewInt(30), Fee: big.NewInt(0)},
+		},
+	}
+	err := model.NewBatchTransfer(b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	b.Items[0].Status = BatchTransferItemSuccess
+	b.Items[1].Status = BatchTransferItemPending
+	err = model.UpdateBatchTransfer(b)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	bs, err := model.InterruptRunningBatchTransfers()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 1, len(bs))
+	b2, err := model.GetBatchTransfer(b.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, BatchTransferStatusFinished, b2.Status)
+	assert.EqualValues(t, BatchTransferItemSuccess, b2.Items[0].Status)
+	assert.EqualValues(t, BatchTransferItemInterrupted, b2.Items[1].Status)
+	assert.EqualValues(t, BatchTransferItemFailed, b2.Items[2].Status)
+	assert.EqualValues(t, 1, b2.Interrupted)
+	assert.EqualValues(t, 1, b2.Failed)
+	assert.EqualValues(t, big.NewInt(30), b2.Totals[token].Failed)
+	//finished batches are not touched again
+	bs, err = model.InterruptRunningBatchTransfers()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 0, len(bs))
+}
@@ -30,6 +30,17 @@ func (model *ModelDB) RegisterChannelStateCallback(f cb.ChannelCb) {
 	model.mlock.Unlock()
 }
 
+//ReceivedTransferCb notify when a transfer received
+//return true to remove this callback, all the callback should never block.
+type ReceivedTransferCb func(t *ReceivedTransfer) (remove bool)
+
+//RegisterReceivedTransferCallback notify when a transfer received
+func (model *ModelDB) RegisterReceivedTransferCallback(f ReceivedTransferCb) {
+	model.mlock.Lock()
+	model.receivedTransferCallbacks[&f] = true
+	model.mlock.Unlock()
+}
+
 //RegisterChannelSettleCallback notify when channel settled
 func (model *ModelDB) RegisterChannelSettleCallback(f cb.ChannelCb) {
 	model.mlock.Lock()
@@ -21,15 +21,16 @@ import (
 
 //ModelDB is thread safe
 type ModelDB struct {
-	db                      *storm.DB
-	lock                    sync.Mutex
-	newTokenCallbacks       map[*cb.NewTokenCb]bool
-	newChannelCallbacks     map[*cb.ChannelCb]bool
-	channelDepositCallbacks map[*cb.ChannelCb]bool
-	channelStateCallbacks   map[*cb.ChannelCb]bool
-	channelSettledCallbacks map[*cb.ChannelCb]bool
-	mlock                   sync.Mutex
-	Name                    string
+	db                        *storm.DB
+	lock                      sync.Mutex
+	newTokenCallbacks         map[*cb.NewTokenCb]bool
+	newChannelCallbacks       map[*cb.ChannelCb]bool
+	channelDepositCallbacks   map[*cb.ChannelCb]bool
+	channelStateCallbacks     map[*cb.ChannelCb]bool
+	channelSettledCallbacks   map[*cb.ChannelCb]bool
+	receivedTransferCallbacks map[*ReceivedTransferCb]bool
+	mlock                     sync.Mutex
+	Name                      string
 	//SentTransferChan SentTransfer notify ,should never close
 	SentTransferChan chan *SentTransfer
 	//ReceivedTransferChan  ReceivedTransfer notify, should never close
@@ -42,13 +43,14 @@ const dbVersion = 1
 
 func newModelDB() (db *ModelDB) {
 	return &ModelDB{
-		newTokenCallbacks:       make(map[*cb.NewTokenCb]bool),
-		newChannelCallbacks:     make(map[*cb.ChannelCb]bool),
-		channelDepositCallbacks: make(map[*cb.ChannelCb]bool),
-		channelStateCallbacks:   make(map[*cb.ChannelCb]bool),
-		channelSettledCallbacks: make(map[*cb.ChannelCb]bool),
-		SentTransferChan:        make(chan *SentTransfer, 10),
-		ReceivedTransferChan:    make(chan *ReceivedTransfer, 10),
+		newTokenCallbacks:         make(map[*cb.NewTokenCb]bool),
+		newChannelCallbacks:       make(map[*cb.ChannelCb]bool),
+		channelDepositCallbacks:   make(map[*cb.ChannelCb]bool),
+		channelStateCallbacks:     make(map[*cb.ChannelCb]bool),
+		channelSettledCallbacks:   make(map[*cb.ChannelCb]bool),
+		receivedTransferCallbacks: make(map[*ReceivedTransferCb]bool),
+		SentTransferChan:          make(chan *SentTransfer, 10),
+		ReceivedTransferChan:      make(chan *ReceivedTransfer, 10),
 	}
 
 }
@@ -145,6 +147,19 @@ func (model *ModelDB) CloseDB() {
 	model.lock.Unlock()
 }
 
+/*
+CloseDBKeepStatus close db without marking it closed normally,
+so a crash of the node last time can still be detected when it starts.
+*/
+func (model *ModelDB) CloseDBKeepStatus() {
+	model.lock.Lock()
+	err := model.db.Close()
+	if err != nil {
+		log.Error(fmt.Sprintf("db err %s", err))
+	}
+	model.lock.Unlock()
+}
+
 //SaveRegistryAddress save registry address to db
 func (model *ModelDB) SaveRegistryAddress(registryAddress common.Address) {
 	err := model.db.Set(bucketMeta, "registry", registryAddress)
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/gob"
+	"math/big"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+FeeRate is what a mediator charges for a transfer: BaseFee + amount * ProportionalRate / 1000000.
+a rate is the default of all tokens, the rate of a token,
+or the rate of a partner or a channel of a token which overrides the rate of the token.
+*/
+type FeeRate struct {
+	Key               string         `json:"key" storm:"id"`
+	TokenAddress      common.Address `json:"token_address"`      //empty for the default rate
+	ChannelIdentifier common.Hash    `json:"channel_identifier"` //empty if not a rate of channel
+	PartnerAddress    common.Address `json:"partner_address"`    //partner of the channel, empty if rate of a token
+	BaseFee           *big.Int       `json:"base_fee"`
+	ProportionalRate  int64          `json:"proportional_rate"` //parts per million of the amount
+}
+
+//FeeRateDefaultKey is the key of the default rate of all tokens
+const FeeRateDefaultKey = "default"
+
+//FeeRateKey returns key of the rate of a channel, a partner, a token or the default rate
+func FeeRateKey(tokenAddress common.Address, channelIdentifier common.Hash, partnerAddress common.Address) string {
+	switch {
+	case channelIdentifier != common.Hash{}:
+		return channelIdentifier.String()
+	case partnerAddress != common.Address{}:
+		return tokenAddress.String() + "-" + partnerAddress.String()
+	case tokenAddress != common.Address{}:
+		return tokenAddress.String()
+	}
+	return FeeRateDefaultKey
+}
+
+func init() {
+	gob.Register(&FeeRate{})
+}
+
+//SaveFeeRate create or update a fee rate
+func (model *ModelDB) SaveFeeRate(r *FeeRate) error {
+	r.Key = FeeRateKey(r.TokenAddress, r.ChannelIdentifier, r.PartnerAddress)
+	return model.db.Save(r)
+}
+
+//GetFeeRate returns the rate with `key`
+func (model *ModelDB) GetFeeRate(key string) (r *FeeRate, err error) {
+	r = new(FeeRate)
+	err = model.db.One("Key", key, r)
+	return
+}
+
+//GetAllFeeRates returns all the fee rates
+func (model *ModelDB) GetAllFeeRates() (rs []*FeeRate, err error) {
+	err = model.db.All(&rs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//RemoveFeeRate removes the rate with `key`
+func (model *ModelDB) RemoveFeeRate(key string) error {
+	r, err := model.GetFeeRate(key)
+	if err != nil {
+		return err
+	}
+	return model.db.DeleteStruct(r)
+}
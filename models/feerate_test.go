@@ -0,0 +1,56 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_FeeRate(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	partner := utils.NewRandomAddress()
+	ch := utils.NewRandomHash()
+	rates := []*FeeRate{
+		{BaseFee: big.NewInt(3)},
+		{TokenAddress: token, BaseFee: big.NewInt(1), ProportionalRate: 100},
+		{TokenAddress: token, PartnerAddress: partner, BaseFee: big.NewInt(0), ProportionalRate: 50},
+		{TokenAddress: token, ChannelIdentifier: ch, PartnerAddress: partner, BaseFee: big.NewInt(2), ProportionalRate: 10},
+	}
+	for _, r := range rates {
+		err := model.SaveFeeRate(r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	assert.EqualValues(t, FeeRateDefaultKey, rates[0].Key)
+	assert.EqualValues(t, token.String(), rates[1].Key)
+	assert.EqualValues(t, ch.String(), rates[3].Key)
+	for _, r := range rates {
+		r2, err := model.GetFeeRate(r.Key)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		assert.EqualValues(t, r, r2)
+	}
+	rs, err := model.GetAllFeeRates()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 4, len(rs))
+	err = model.RemoveFeeRate(rates[2].Key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.GetFeeRate(rates[2].Key)
+	assert.NotEqual(t, nil, err)
+	err = model.RemoveFeeRate(rates[2].Key)
+	assert.NotEqual(t, nil, err)
+}
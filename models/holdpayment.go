@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//status of a hold payment
+const (
+	HoldPaymentStatusWaiting  = "waiting"  //no transfer received yet
+	HoldPaymentStatusHeld     = "held"     //transfers received are held, waiting for the user to settle or reject
+	HoldPaymentStatusSettled  = "settled"  //the secret is revealed to the payer
+	HoldPaymentStatusRejected = "rejected" //transfers are given back to the payer
+)
+
+/*
+HoldPayment is a payment the receiver expects,
+transfers with its lock secret hash are held until the user settles or rejects them.
+*/
+type HoldPayment struct {
+	LockSecretHash common.Hash    `json:"lock_secret_hash" storm:"id"`
+	Token          common.Address `json:"token_address"`
+	Amount         *big.Int       `json:"amount"`
+	Received       *big.Int       `json:"received"` //amount of transfers held
+	Deadline       int64          `json:"deadline"` //unix time, transfers are rejected if not settled before it
+	Status         string         `json:"status" storm:"index"`
+	Reason         string         `json:"reason,omitempty"` //why rejected
+	CreatedAt      int64          `json:"created_at"`
+}
+
+func init() {
+	gob.Register(&HoldPayment{})
+}
+
+//IsFinished returns true if this hold payment is settled or rejected
+func (h *HoldPayment) IsFinished() bool {
+	return h.Status == HoldPaymentStatusSettled || h.Status == HoldPaymentStatusRejected
+}
+
+//NewHoldPayment save a new hold payment, its status is waiting
+func (model *ModelDB) NewHoldPayment(h *HoldPayment) error {
+	_, err := model.GetHoldPayment(h.LockSecretHash)
+	if err == nil {
+		return fmt.Errorf("hold payment %s already exists", h.LockSecretHash.String())
+	}
+	h.Status = HoldPaymentStatusWaiting
+	h.Received = big.NewInt(0)
+	h.CreatedAt = time.Now().Unix()
+	return model.db.Save(h)
+}
+
+//GetHoldPayment returns the hold payment with `lockSecretHash`
+func (model *ModelDB) GetHoldPayment(lockSecretHash common.Hash) (h *HoldPayment, err error) {
+	h = new(HoldPayment)
+	err = model.db.One("LockSecretHash", lockSecretHash, h)
+	return
+}
+
+//GetHoldPayments returns all the hold payments
+func (model *ModelDB) GetHoldPayments() (hs []*HoldPayment, err error) {
+	err = model.db.All(&hs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//GetUnfinishedHoldPayments returns hold payments which are neither settled nor rejected
+func (model *ModelDB) GetUnfinishedHoldPayments() (hs []*HoldPayment, err error) {
+	all, err := model.GetHoldPayments()
+	if err != nil {
+		return
+	}
+	for _, h := range all {
+		if !h.IsFinished() {
+			hs = append(hs, h)
+		}
+	}
+	return
+}
+
+//UpdateHoldPayment saves the changed status or received amount of `h`
+func (model *ModelDB) UpdateHoldPayment(h *HoldPayment) error {
+	return model.db.Save(h)
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_HoldPayment(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	h := &HoldPayment{
+		LockSecretHash: utils.NewRandomHash(),
+		Token:          utils.NewRandomAddress(),
+		Amount:         big.NewInt(10),
+	}
+	err := model.NewHoldPayment(h)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.NewHoldPayment(h)
+	if err == nil {
+		t.Error("should fail with duplicate lock secret hash")
+		return
+	}
+	h2, err := model.GetHoldPayment(h.LockSecretHash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, h2.Status, HoldPaymentStatusWaiting)
+	assert.EqualValues(t, h2.Received, big.NewInt(0))
+	hs, err := model.GetUnfinishedHoldPayments()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(hs), 1)
+	h2.Status = HoldPaymentStatusSettled
+	err = model.UpdateHoldPayment(h2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	hs, err = model.GetUnfinishedHoldPayments()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(hs), 0)
+	hs, err = model.GetHoldPayments()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(hs), 1)
+	_, err = model.GetHoldPayment(utils.NewRandomHash())
+	assert.NotEqual(t, nil, err)
+}
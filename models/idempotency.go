@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/gob"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//status of a request with idempotency key
+const (
+	IdempotencyStatusPending = "pending" //the first request has not finished
+	IdempotencyStatusSuccess = "success"
+	IdempotencyStatusFailed  = "failed"
+	//the node stopped before the first request finished, a transfer may still be unlocked or expire later
+	IdempotencyStatusInterrupted = "interrupted"
+)
+
+/*
+IdempotencyRecord is the result of the first request with an idempotency key,
+a retry with the same key gets this result instead of starting the operation again.
+*/
+type IdempotencyRecord struct {
+	Key               string      `json:"idempotency_key" storm:"id"`
+	Operation         string      `json:"operation"`
+	ParamsHash        common.Hash `json:"-"` //a key can only be reused with the same parameters
+	Status            string      `json:"status"`
+	Error             string      `json:"error,omitempty"`
+	ChannelIdentifier common.Hash `json:"channel_identifier,omitempty"` //channel opened or deposited
+	LockSecretHash    common.Hash `json:"lock_secret_hash,omitempty"`   //transfer started by the first request
+	CreatedAt         int64       `json:"created_at"`
+	FinishedAt        int64       `json:"finished_at"`
+}
+
+func init() {
+	gob.Register(&IdempotencyRecord{})
+}
+
+/*
+NewIdempotencyRecord saves a pending record for `key`,
+if `key` is already used, the record saved before is returned and `isNew` is false.
+*/
+func (model *ModelDB) NewIdempotencyRecord(key, operation string, paramsHash common.Hash) (rec *IdempotencyRecord, isNew bool, err error) {
+	tx, err := model.db.Begin(true)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback()
+	rec = new(IdempotencyRecord)
+	err = tx.One("Key", key, rec)
+	if err == nil {
+		return
+	}
+	if err != storm.ErrNotFound {
+		return
+	}
+	rec = &IdempotencyRecord{
+		Key:        key,
+		Operation:  operation,
+		ParamsHash: paramsHash,
+		Status:     IdempotencyStatusPending,
+		CreatedAt:  time.Now().Unix(),
+	}
+	err = tx.Save(rec)
+	if err != nil {
+		return
+	}
+	err = tx.Commit()
+	isNew = err == nil
+	return
+}
+
+//GetIdempotencyRecord returns the record of `key`
+func (model *ModelDB) GetIdempotencyRecord(key string) (rec *IdempotencyRecord, err error) {
+	rec = new(IdempotencyRecord)
+	err = model.db.One("Key", key, rec)
+	return
+}
+
+//FinishIdempotencyRecord saves the result of the request with `key`
+func (model *ModelDB) FinishIdempotencyRecord(key string, channelIdentifier common.Hash, result error) error {
+	rec, err := model.GetIdempotencyRecord(key)
+	if err != nil {
+		return err
+	}
+	rec.Status = IdempotencyStatusSuccess
+	if result != nil {
+		rec.Status = IdempotencyStatusFailed
+		rec.Error = result.Error()
+	}
+	rec.ChannelIdentifier = channelIdentifier
+	rec.FinishedAt = time.Now().Unix()
+	return model.db.Save(rec)
+}
+
+//SetIdempotencyLockSecretHash saves the lock secret hash of the transfer started by the request with `key`
+func (model *ModelDB) SetIdempotencyLockSecretHash(key string, lockSecretHash common.Hash) error {
+	rec, err := model.GetIdempotencyRecord(key)
+	if err != nil {
+		return err
+	}
+	rec.LockSecretHash = lockSecretHash
+	return model.db.Save(rec)
+}
+
+/*
+InterruptPendingIdempotencyRecords marks all the pending records interrupted,
+call it on startup, requests started before the node stopped will never finish.
+*/
+func (model *ModelDB) InterruptPendingIdempotencyRecords() (recs []*IdempotencyRecord, err error) {
+	var all []*IdempotencyRecord
+	err = model.db.All(&all)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	for _, rec := range all {
+		if rec.Status != IdempotencyStatusPending {
+			continue
+		}
+		rec.Status = IdempotencyStatusInterrupted
+		rec.FinishedAt = time.Now().Unix()
+		err = model.db.Save(rec)
+		if err != nil {
+			return
+		}
+		recs = append(recs, rec)
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_IdempotencyRecord(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	paramsHash := utils.NewRandomHash()
+	rec, isNew, err := model.NewIdempotencyRecord("key1", "transfer", paramsHash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, true, isNew)
+	assert.EqualValues(t, IdempotencyStatusPending, rec.Status)
+	rec, isNew, err = model.NewIdempotencyRecord("key1", "deposit", utils.NewRandomHash())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, false, isNew)
+	assert.EqualValues(t, "transfer", rec.Operation)
+	assert.EqualValues(t, paramsHash, rec.ParamsHash)
+	err = model.FinishIdempotencyRecord("key1", utils.EmptyHash, errors.New("no route"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rec, err = model.GetIdempotencyRecord("key1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, IdempotencyStatusFailed, rec.Status)
+	assert.EqualValues(t, "no route", rec.Error)
+	ch := utils.NewRandomHash()
+	_, _, err = model.NewIdempotencyRecord("key2", "open channel", paramsHash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.FinishIdempotencyRecord("key2", ch, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	rec, err = model.GetIdempotencyRecord("key2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, IdempotencyStatusSuccess, rec.Status)
+	assert.EqualValues(t, ch, rec.ChannelIdentifier)
+	err = model.FinishIdempotencyRecord("key3", ch, nil)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestModelDB_InterruptPendingIdempotencyRecords(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	lockSecretHash := utils.NewRandomHash()
+	_, _, err := model.NewIdempotencyRecord("pending", "transfer", utils.NewRandomHash())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.SetIdempotencyLockSecretHash("pending", lockSecretHash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, _, err = model.NewIdempotencyRecord("finished", "transfer", utils.NewRandomHash())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.FinishIdempotencyRecord("finished", utils.EmptyHash, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	recs, err := model.InterruptPendingIdempotencyRecords()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 1, len(recs))
+	rec, err := model.GetIdempotencyRecord("pending")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, IdempotencyStatusInterrupted, rec.Status)
+	assert.EqualValues(t, lockSecretHash, rec.LockSecretHash)
+	rec, err = model.GetIdempotencyRecord("finished")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, IdempotencyStatusSuccess, rec.Status)
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//status of an invoice
+const (
+	InvoiceStatusOpen    = "open"
+	InvoiceStatusPaid    = "paid"
+	InvoiceStatusExpired = "expired"
+)
+
+/*
+Invoice is a signed payment request created or paid by this node.
+invoices created by me are marked paid when a matching transfer is received,
+invoices paid by me are saved to avoid paying twice.
+*/
+type Invoice struct {
+	Hash           common.Hash    `json:"hash" storm:"id"`
+	Invoice        string         `json:"invoice"` //the encoded invoice
+	Target         common.Address `json:"target_address"`
+	Token          common.Address `json:"token_address"`
+	Amount         *big.Int       `json:"amount"`
+	LockSecretHash common.Hash    `json:"lock_secret_hash"`
+	Identifier     uint64         `json:"identifier" storm:"index"`
+	Expiration     int64          `json:"expiration"` //unix time, 0 means never expire
+	Memo           string         `json:"memo"`
+	IsPayer        bool           `json:"is_payer"` //true if paid by me, false if created by me
+	Status         string         `json:"status"`
+	CreatedAt      int64          `json:"created_at"`
+	PaidAt         int64          `json:"paid_at"`
+}
+
+func init() {
+	gob.Register(&Invoice{})
+}
+
+//checkExpired an open invoice which expired is reported as expired
+func (i *Invoice) checkExpired(now int64) {
+	if i.Status == InvoiceStatusOpen && i.Expiration > 0 && now > i.Expiration {
+		i.Status = InvoiceStatusExpired
+	}
+}
+
+//NewInvoice save a new invoice,its status is open
+func (model *ModelDB) NewInvoice(i *Invoice) error {
+	_, err := model.GetInvoice(i.Hash)
+	if err == nil {
+		return fmt.Errorf("invoice %s already exists", i.Hash.String())
+	}
+	i.Status = InvoiceStatusOpen
+	i.CreatedAt = time.Now().Unix()
+	return model.db.Save(i)
+}
+
+//GetInvoice returns the invoice with `hash`
+func (model *ModelDB) GetInvoice(hash common.Hash) (i *Invoice, err error) {
+	i = new(Invoice)
+	err = model.db.One("Hash", hash, i)
+	if err != nil {
+		return
+	}
+	i.checkExpired(time.Now().Unix())
+	return
+}
+
+//GetInvoices returns all the invoices created or paid by me
+func (model *ModelDB) GetInvoices() (is []*Invoice, err error) {
+	err = model.db.All(&is)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	now := time.Now().Unix()
+	for _, i := range is {
+		i.checkExpired(now)
+	}
+	return
+}
+
+//MarkInvoicePaid marks the invoice with `hash` paid
+func (model *ModelDB) MarkInvoicePaid(hash common.Hash) error {
+	i := new(Invoice)
+	err := model.db.One("Hash", hash, i)
+	if err != nil {
+		return err
+	}
+	i.Status = InvoiceStatusPaid
+	i.PaidAt = time.Now().Unix()
+	return model.db.Save(i)
+}
+
+/*
+GetOpenInvoicesByIdentifier returns the open invoices with payment identifier `identifier`,
+paid by me if `isPayer`, otherwise created by me. expired invoices are not included.
+*/
+func (model *ModelDB) GetOpenInvoicesByIdentifier(identifier uint64, isPayer bool) (is []*Invoice, err error) {
+	var all []*Invoice
+	err = model.db.Find("Identifier", identifier, &all)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	now := time.Now().Unix()
+	for _, i := range all {
+		i.checkExpired(now)
+		if i.IsPayer == isPayer && i.Status == InvoiceStatusOpen {
+			is = append(is, i)
+		}
+	}
+	return
+}
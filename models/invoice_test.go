@@ -0,0 +1,91 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_Invoice(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	is, err := model.GetInvoices()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(is), 0)
+	i := &Invoice{
+		Hash:       utils.NewRandomHash(),
+		Token:      utils.NewRandomAddress(),
+		Amount:     big.NewInt(10),
+		Identifier: 3,
+		Expiration: time.Now().Add(time.Hour).Unix(),
+	}
+	err = model.NewInvoice(i)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.NewInvoice(i)
+	if err == nil {
+		t.Error("should fail with duplicate invoice")
+		return
+	}
+	expired := &Invoice{
+		Hash:       utils.NewRandomHash(),
+		Amount:     big.NewInt(10),
+		Identifier: 3,
+		Expiration: time.Now().Add(-time.Hour).Unix(),
+	}
+	err = model.NewInvoice(expired)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	i2, err := model.GetInvoice(expired.Hash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, i2.Status, InvoiceStatusExpired)
+	is, err = model.GetOpenInvoicesByIdentifier(3, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(is), 1)
+	assert.EqualValues(t, is[0].Hash, i.Hash)
+	is, err = model.GetOpenInvoicesByIdentifier(3, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(is), 0)
+	err = model.MarkInvoicePaid(i.Hash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	i2, err = model.GetInvoice(i.Hash)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, i2.Status, InvoiceStatusPaid)
+	is, err = model.GetOpenInvoicesByIdentifier(3, false)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(is), 0)
+	is, err = model.GetInvoices()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(is), 2)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+MediationRecord is a transfer I have mediated and been paid for,
+I received AmountIn from the payer and sent AmountOut to the payee, the difference is the fee I earned.
+*/
+type MediationRecord struct {
+	Key            []byte         `json:"-" storm:"id"`
+	LockSecretHash common.Hash    `json:"lock_secret_hash"`
+	TokenAddress   common.Address `json:"token_address"`
+	PayerChannel   common.Hash    `json:"payer_channel"`
+	PayerAddress   common.Address `json:"payer_address"`
+	PayeeChannel   common.Hash    `json:"payee_channel"`
+	PayeeAddress   common.Address `json:"payee_address"`
+	AmountIn       *big.Int       `json:"amount_in"`
+	AmountOut      *big.Int       `json:"amount_out"`
+	Fee            *big.Int       `json:"fee"`
+	BlockNumber    int64          `json:"block_number"`
+	Time           int64          `json:"time" storm:"index"` //unix seconds when the payer paid
+}
+
+func init() {
+	gob.Register(&MediationRecord{})
+}
+
+/*
+NewMediationRecord saves a completed mediation,
+a mediation is saved only once even if the payer's balance proof is received again.
+*/
+func (model *ModelDB) NewMediationRecord(r *MediationRecord) {
+	r.Key = utils.Sha3(r.LockSecretHash[:], r.PayerChannel[:], r.PayeeChannel[:]).Bytes()
+	var or MediationRecord
+	if err := model.db.One("Key", r.Key, &or); err == nil {
+		log.Debug(fmt.Sprintf("NewMediationRecord, but already exist, lockSecretHash=%s", utils.HPex(r.LockSecretHash)))
+		return
+	}
+	err := model.db.Save(r)
+	if err != nil {
+		log.Error(fmt.Sprintf("save MediationRecord err %s", err))
+	}
+}
+
+//GetMediationRecordsInTimeRange returns the mediations completed between from and to, both are unix seconds
+func (model *ModelDB) GetMediationRecordsInTimeRange(from, to int64) (rs []*MediationRecord, err error) {
+	if from < 0 {
+		from = 0
+	}
+	if to < 0 {
+		to = math.MaxInt64
+	}
+	err = model.db.Range("Time", from, to, &rs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
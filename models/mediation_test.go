@@ -0,0 +1,55 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_MediationRecord(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	for i := int64(1); i <= 3; i++ {
+		model.NewMediationRecord(&MediationRecord{
+			LockSecretHash: utils.NewRandomHash(),
+			TokenAddress:   token,
+			PayerChannel:   utils.NewRandomHash(),
+			PayeeChannel:   utils.NewRandomHash(),
+			AmountIn:       big.NewInt(103),
+			AmountOut:      big.NewInt(100),
+			Fee:            big.NewInt(3),
+			BlockNumber:    i,
+			Time:           i * 100,
+		})
+	}
+	rs, err := model.GetMediationRecordsInTimeRange(-1, -1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 3, len(rs))
+	//saved only once
+	r := *rs[0]
+	model.NewMediationRecord(&r)
+	rs, err = model.GetMediationRecordsInTimeRange(0, -1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 3, len(rs))
+	rs, err = model.GetMediationRecordsInTimeRange(150, 300)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 2, len(rs))
+	rs, err = model.GetMediationRecordsInTimeRange(400, 500)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 0, len(rs))
+}
@@ -108,6 +108,10 @@ func (model *ModelDB) RemoveNonParticipantChannel(token common.Address, channel
 		return fmt.Errorf("delete channel ,but channel don't exists")
 	}
 	delete(m, channel)
+	err = model.db.Delete(bucketChannelDeposit, channel[:])
+	if err != nil && err != storm.ErrNotFound {
+		log.Error(fmt.Sprintf("RemoveNonParticipantChannel delete deposit err %s", err))
+	}
 	log.Trace(fmt.Sprintf("RemoveNonParticipantChannel token=%s,channel=%s", utils.APex2(token),
 		utils.HPex(channel)))
 	err = model.db.Set(bucketChannel, token[:], m)
@@ -129,3 +133,75 @@ func (model *ModelDB) GetAllNonParticipantChannel(token common.Address) (edges [
 	}
 	return
 }
+
+const bucketChannelDeposit = "bucketChannelDeposit"
+
+/*
+UpdateNonParticipantChannelDeposit records the total deposit of `participant` in `channel`,
+returns the channel with deposits of both participants known so far.
+*/
+func (model *ModelDB) UpdateNonParticipantChannelDeposit(token common.Address, channel common.Hash, participant common.Address, deposit *big.Int) (c *NonParticipantChannel, err error) {
+	var m ChannelParticipantMap
+	err = model.db.Get(bucketChannel, token[:], &m)
+	if err != nil {
+		return
+	}
+	if m[channel] == nil {
+		err = fmt.Errorf("update deposit ,but channel don't exists")
+		return
+	}
+	p1, p2 := bytes2participant(m[channel])
+	c = &NonParticipantChannel{}
+	err = model.db.Get(bucketChannelDeposit, channel[:], c)
+	if err != nil && err != storm.ErrNotFound {
+		return
+	}
+	c.Participant1 = p1
+	c.Participant2 = p2
+	switch participant {
+	case p1:
+		c.Participant1Balance = deposit
+	case p2:
+		c.Participant2Balance = deposit
+	default:
+		err = fmt.Errorf("%s is not a participant of channel %s", utils.APex2(participant), utils.HPex(channel))
+		return
+	}
+	err = model.db.Set(bucketChannelDeposit, channel[:], c)
+	return
+}
+
+//GetAllNonParticipantChannelDeposit returns the channels on `token` whose deposit is known
+func (model *ModelDB) GetAllNonParticipantChannelDeposit(token common.Address) (cs []*NonParticipantChannel, err error) {
+	var m ChannelParticipantMap
+	err = model.db.Get(bucketChannel, token[:], &m)
+	if err == storm.ErrNotFound {
+		err = nil
+		return
+	}
+	for channel := range m {
+		c := &NonParticipantChannel{}
+		err = model.db.Get(bucketChannelDeposit, channel[:], c)
+		if err == storm.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		cs = append(cs, c)
+	}
+	err = nil
+	return
+}
+
+//Capacity returns the total deposit of the channel
+func (c *NonParticipantChannel) Capacity() *big.Int {
+	capacity := new(big.Int)
+	if c.Participant1Balance != nil {
+		capacity.Add(capacity, c.Participant1Balance)
+	}
+	if c.Participant2Balance != nil {
+		capacity.Add(capacity, c.Participant2Balance)
+	}
+	return capacity
+}
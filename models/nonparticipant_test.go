@@ -5,6 +5,8 @@ import (
 
 	"fmt"
 
+	"math/big"
+
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -62,3 +64,34 @@ func TestReadDbAgain(t *testing.T) {
 	}
 	log.Trace(fmt.Sprintf("edges=%s", utils.StringInterface(edges, 3)))
 }
+
+func TestModelDB_UpdateNonParticipantChannelDeposit(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	p1 := utils.NewRandomAddress()
+	p2 := utils.NewRandomAddress()
+	channel := utils.Sha3(p1[:], p2[:], token[:])
+	_, err := model.UpdateNonParticipantChannelDeposit(token, channel, p1, big.NewInt(10))
+	assert.EqualValues(t, err != nil, true)
+	err = model.NewNonParticipantChannel(token, channel, p1, p2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.UpdateNonParticipantChannelDeposit(token, channel, utils.NewRandomAddress(), big.NewInt(10))
+	assert.EqualValues(t, err != nil, true)
+	_, err = model.UpdateNonParticipantChannelDeposit(token, channel, p1, big.NewInt(10))
+	assert.EqualValues(t, err, nil)
+	c, err := model.UpdateNonParticipantChannelDeposit(token, channel, p2, big.NewInt(20))
+	assert.EqualValues(t, err, nil)
+	assert.EqualValues(t, c.Capacity(), big.NewInt(30))
+	cs, err := model.GetAllNonParticipantChannelDeposit(token)
+	assert.EqualValues(t, err, nil)
+	assert.EqualValues(t, len(cs), 1)
+	err = model.RemoveNonParticipantChannel(token, channel)
+	assert.EqualValues(t, err, nil)
+	cs, err = model.GetAllNonParticipantChannelDeposit(token)
+	assert.EqualValues(t, err, nil)
+	assert.EqualValues(t, len(cs), 0)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/gob"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+RouteHistory is what happened to transfers routed from `From` to `To` on `TokenAddress`,
+or to transfers routed through node `To` on any token if `From` is empty.
+failures and successes decay as time goes, they are the counts at `UpdateTime`.
+*/
+type RouteHistory struct {
+	Key          string         `json:"key" storm:"id"`
+	TokenAddress common.Address `json:"token_address"` //empty for the history of a node
+	From         common.Address `json:"from"`          //empty for the history of a node
+	To           common.Address `json:"to"`
+	Failures     float64        `json:"failures"`
+	Successes    float64        `json:"successes"`
+	LastFailure  int64          `json:"last_failure"` //unix seconds,0 if never failed
+	LastSuccess  int64          `json:"last_success"` //unix seconds,0 if never succeeded
+	UpdateTime   int64          `json:"update_time"`
+}
+
+//RouteHistoryKey returns key of the history of an edge, or the history of node `to` if `from` is empty
+func RouteHistoryKey(tokenAddress, from, to common.Address) string {
+	if from == (common.Address{}) {
+		return to.String()
+	}
+	return tokenAddress.String() + "-" + from.String() + "-" + to.String()
+}
+
+func init() {
+	gob.Register(&RouteHistory{})
+}
+
+//SaveRouteHistory create or update a route history
+func (model *ModelDB) SaveRouteHistory(h *RouteHistory) error {
+	h.Key = RouteHistoryKey(h.TokenAddress, h.From, h.To)
+	return model.db.Save(h)
+}
+
+//GetAllRouteHistories returns all the route histories
+func (model *ModelDB) GetAllRouteHistories() (hs []*RouteHistory, err error) {
+	err = model.db.All(&hs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//RemoveAllRouteHistories forgets all the route histories
+func (model *ModelDB) RemoveAllRouteHistories() error {
+	hs, err := model.GetAllRouteHistories()
+	if err != nil {
+		return err
+	}
+	for _, h := range hs {
+		err = model.db.DeleteStruct(h)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_RouteHistory(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	token := utils.NewRandomAddress()
+	from := utils.NewRandomAddress()
+	to := utils.NewRandomAddress()
+	hs := []*RouteHistory{
+		{TokenAddress: token, From: from, To: to, Failures: 1, LastFailure: 10, UpdateTime: 10},
+		{To: to, Failures: 1, LastFailure: 10, UpdateTime: 10},
+	}
+	for _, h := range hs {
+		err := model.SaveRouteHistory(h)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	assert.EqualValues(t, to.String(), hs[1].Key)
+	hs[0].Successes = 1
+	err := model.SaveRouteHistory(hs[0])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	hs2, err := model.GetAllRouteHistories()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 2, len(hs2))
+	for _, h := range hs2 {
+		if h.Key == hs[0].Key {
+			assert.EqualValues(t, hs[0], h)
+		}
+	}
+	err = model.RemoveAllRouteHistories()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	hs2, err = model.GetAllRouteHistories()
+	assert.EqualValues(t, nil, err)
+	assert.EqualValues(t, 0, len(hs2))
+}
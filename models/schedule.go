@@ -0,0 +1,147 @@
+package models
+
+import (
+	"encoding/gob"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//status of a payment schedule
+const (
+	ScheduleStatusActive   = "active"
+	ScheduleStatusPaused   = "paused"
+	ScheduleStatusFinished = "finished" //reached its end or max count
+)
+
+/*
+PaymentSchedule pays `Amount` of `Token` to `Target` every `Interval` seconds or at times matching `Cron`,
+from `StartAt` until `EndAt`, at most `MaxCount` times.
+*/
+type PaymentSchedule struct {
+	ID        string         `json:"id" storm:"id"`
+	Token     common.Address `json:"token_address"`
+	Target    common.Address `json:"target_address"`
+	Amount    *big.Int       `json:"amount"`
+	Fee       *big.Int       `json:"fee"`
+	Interval  int64          `json:"interval"` //seconds between two payments, 0 if Cron is used
+	Cron      string         `json:"cron"`     //cron expression in UTC
+	StartAt   int64          `json:"start_at"`
+	EndAt     int64          `json:"end_at"`    //0 means never end
+	MaxCount  int            `json:"max_count"` //0 means no limit
+	Count     int            `json:"count"`     //payments due so far
+	NextRunAt int64          `json:"next_run_at"`
+	Status    string         `json:"status"`
+	CreatedAt int64          `json:"created_at"`
+}
+
+//status of a run of a payment schedule
+const (
+	ScheduleRunWaiting     = "waiting"
+	ScheduleRunPending     = "pending" //transfer is going on
+	ScheduleRunSuccess     = "success"
+	ScheduleRunFailed      = "failed"      //will retry
+	ScheduleRunInterrupted = "interrupted" //node stopped while transfer is going on, never retried to avoid paying twice
+)
+
+//ScheduleRun is one payment of a payment schedule
+type ScheduleRun struct {
+	ID            string `json:"id" storm:"id"`
+	ScheduleID    string `json:"schedule_id" storm:"index"`
+	Seq           int    `json:"seq"`
+	DueAt         int64  `json:"due_at"`
+	Missed        bool   `json:"missed"` //the node was down when this payment is due
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	LastAttemptAt int64  `json:"last_attempt_at"`
+	Error         string `json:"error,omitempty"` //error of last attempt
+}
+
+func init() {
+	gob.Register(&PaymentSchedule{})
+	gob.Register(&ScheduleRun{})
+}
+
+//NewPaymentSchedule saves a new schedule
+func (model *ModelDB) NewPaymentSchedule(s *PaymentSchedule) error {
+	_, err := model.GetPaymentSchedule(s.ID)
+	if err == nil {
+		return fmt.Errorf("payment schedule %s already exists", s.ID)
+	}
+	return model.db.Save(s)
+}
+
+//UpdatePaymentSchedule saves changes of `s`
+func (model *ModelDB) UpdatePaymentSchedule(s *PaymentSchedule) error {
+	return model.db.Save(s)
+}
+
+//GetPaymentSchedule returns the schedule with `id`
+func (model *ModelDB) GetPaymentSchedule(id string) (s *PaymentSchedule, err error) {
+	s = new(PaymentSchedule)
+	err = model.db.One("ID", id, s)
+	return
+}
+
+//GetPaymentSchedules returns all the schedules
+func (model *ModelDB) GetPaymentSchedules() (ss []*PaymentSchedule, err error) {
+	err = model.db.All(&ss)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//RemovePaymentSchedule removes the schedule with `id` and all its runs
+func (model *ModelDB) RemovePaymentSchedule(id string) error {
+	s, err := model.GetPaymentSchedule(id)
+	if err != nil {
+		return err
+	}
+	runs, err := model.GetScheduleRuns(id)
+	if err != nil {
+		return err
+	}
+	tx, err := model.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, r := range runs {
+		err = tx.DeleteStruct(r)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.DeleteStruct(s)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//ScheduleRunID returns id of the `seq`th run of schedule `scheduleID`
+func ScheduleRunID(scheduleID string, seq int) string {
+	return fmt.Sprintf("%s-%d", scheduleID, seq)
+}
+
+//SaveScheduleRun create or update a run
+func (model *ModelDB) SaveScheduleRun(r *ScheduleRun) error {
+	r.ID = ScheduleRunID(r.ScheduleID, r.Seq)
+	return model.db.Save(r)
+}
+
+//GetScheduleRuns returns all the runs of schedule `scheduleID` ordered by seq
+func (model *ModelDB) GetScheduleRuns(scheduleID string) (runs []*ScheduleRun, err error) {
+	err = model.db.Find("ScheduleID", scheduleID, &runs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Seq < runs[j].Seq
+	})
+	return
+}
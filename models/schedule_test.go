@@ -0,0 +1,87 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_PaymentSchedule(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	s := &PaymentSchedule{
+		ID:        utils.RandomString(10),
+		Token:     utils.NewRandomAddress(),
+		Target:    utils.NewRandomAddress(),
+		Amount:    big.NewInt(10),
+		Fee:       big.NewInt(0),
+		Interval:  3600,
+		StartAt:   100,
+		NextRunAt: 100,
+		Status:    ScheduleStatusActive,
+	}
+	err := model.NewPaymentSchedule(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = model.NewPaymentSchedule(s)
+	if err == nil {
+		t.Error("should fail with duplicate id")
+		return
+	}
+	for seq := 12; seq > 0; seq-- {
+		err = model.SaveScheduleRun(&ScheduleRun{
+			ScheduleID: s.ID,
+			Seq:        seq,
+			DueAt:      s.StartAt + int64(seq-1)*s.Interval,
+			Status:     ScheduleRunWaiting,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	s.Count = 12
+	err = model.UpdatePaymentSchedule(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	s2, err := model.GetPaymentSchedule(s.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, s, s2)
+	runs, err := model.GetScheduleRuns(s.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 12, len(runs))
+	for i, r := range runs {
+		assert.EqualValues(t, i+1, r.Seq)
+	}
+	ss, err := model.GetPaymentSchedules()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 1, len(ss))
+	err = model.RemovePaymentSchedule(s.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.GetPaymentSchedule(s.ID)
+	assert.NotEqual(t, nil, err)
+	runs, err = model.GetScheduleRuns(s.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, 0, len(runs))
+}
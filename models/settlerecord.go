@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/gob"
+
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//status of auto settle
+const (
+	SettleStatusSettling = "settling" //settle tx has been sent,waiting for result
+	SettleStatusFailed   = "failed"   //settle tx failed,will retry later
+	SettleStatusSuccess  = "success"  //settle tx success
+)
+
+/*
+SettleRecord is the outcome of settling a closed channel automatically
+*/
+type SettleRecord struct {
+	Key               []byte         `storm:"id"`
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	OpenBlockNumber   int64          `json:"open_block_number"`
+	TokenAddress      common.Address `json:"token_address"`
+	PartnerAddress    common.Address `json:"partner_address"`
+	Status            string         `json:"status"`
+	Attempts          int            `json:"attempts"`
+	LastAttemptBlock  int64          `json:"last_attempt_block"`
+	Error             string         `json:"error"` //error of last failed attempt
+}
+
+func init() {
+	gob.Register(&SettleRecord{})
+}
+
+//SaveSettleRecord create or update the settle record of a channel
+func (model *ModelDB) SaveSettleRecord(r *SettleRecord) error {
+	r.Key = r.ChannelIdentifier[:]
+	return model.db.Save(r)
+}
+
+//GetSettleRecord returns the settle record of channel `channelIdentifier`
+func (model *ModelDB) GetSettleRecord(channelIdentifier common.Hash) (r *SettleRecord, err error) {
+	r = new(SettleRecord)
+	err = model.db.One("Key", channelIdentifier[:], r)
+	return
+}
+
+//GetAllSettleRecords returns all the settle records
+func (model *ModelDB) GetAllSettleRecords() (rs []*SettleRecord, err error) {
+	err = model.db.All(&rs)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
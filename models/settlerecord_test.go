@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_SettleRecord(t *testing.T) {
+	model := setupDb(t)
+	defer func() {
+		model.CloseDB()
+	}()
+	rs, err := model.GetAllSettleRecords()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(rs), 0)
+	ch := utils.NewRandomHash()
+	r := &SettleRecord{
+		ChannelIdentifier: ch,
+		Status:            SettleStatusSettling,
+		Attempts:          1,
+		LastAttemptBlock:  30,
+	}
+	err = model.SaveSettleRecord(r)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r.Status = SettleStatusFailed
+	r.Error = "tx failed"
+	err = model.SaveSettleRecord(r)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	r2, err := model.GetSettleRecord(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, r, r2)
+	_, err = model.GetSettleRecord(utils.NewRandomHash())
+	if err == nil {
+		t.Error("should not found")
+	}
+	rs, err = model.GetAllSettleRecords()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(rs), 1)
+}
@@ -22,6 +22,8 @@ type SentTransfer struct {
 	TokenAddress      common.Address `json:"token_address"`
 	Nonce             uint64         `json:"nonce"`
 	Amount            *big.Int       `json:"amount"`
+	Identifier        uint64         `json:"identifier" storm:"index"`
+	Memo              string         `json:"memo"`
 }
 
 //ReceivedTransfer tokens I have received and where it comes from
@@ -34,12 +36,14 @@ type ReceivedTransfer struct {
 	FromAddress       common.Address `json:"from_address"`
 	Nonce             uint64         `json:"nonce"`
 	Amount            *big.Int       `json:"amount"`
+	Identifier        uint64         `json:"identifier" storm:"index"`
+	Memo              string         `json:"memo"`
 }
 
 /*
 NewSentTransfer save a new sent transfer to db,this transfer must be success
 */
-func (model *ModelDB) NewSentTransfer(blockNumber int64, channelAddr common.Hash, tokenAddr, toAddr common.Address, nonce uint64, amount *big.Int) {
+func (model *ModelDB) NewSentTransfer(blockNumber int64, channelAddr common.Hash, tokenAddr, toAddr common.Address, nonce uint64, amount *big.Int, identifier uint64, memo string) {
 	key := fmt.Sprintf("%s-%d", channelAddr.String(), nonce)
 	st := &SentTransfer{
 		Key:               key,
@@ -49,6 +53,8 @@ func (model *ModelDB) NewSentTransfer(blockNumber int64, channelAddr common.Hash
 		ToAddress:         toAddr,
 		Nonce:             nonce,
 		Amount:            amount,
+		Identifier:        identifier,
+		Memo:              memo,
 	}
 	if ost, err := model.GetSentTransfer(key); err == nil {
 		log.Error(fmt.Sprintf("NewSentTransfer, but already exist, old=\n%s,new=\n%s",
@@ -67,7 +73,7 @@ func (model *ModelDB) NewSentTransfer(blockNumber int64, channelAddr common.Hash
 }
 
 //NewReceivedTransfer save a new received transfer to db
-func (model *ModelDB) NewReceivedTransfer(blockNumber int64, channelAddr common.Hash, tokenAddr, fromAddr common.Address, nonce uint64, amount *big.Int) {
+func (model *ModelDB) NewReceivedTransfer(blockNumber int64, channelAddr common.Hash, tokenAddr, fromAddr common.Address, nonce uint64, amount *big.Int, identifier uint64, memo string) {
 	key := fmt.Sprintf("%s-%d", channelAddr.String(), nonce)
 	st := &ReceivedTransfer{
 		Key:               key,
@@ -77,6 +83,8 @@ func (model *ModelDB) NewReceivedTransfer(blockNumber int64, channelAddr common.
 		FromAddress:       fromAddr,
 		Nonce:             nonce,
 		Amount:            amount,
+		Identifier:        identifier,
+		Memo:              memo,
 	}
 	if ost, err := model.GetReceivedTransfer(key); err == nil {
 		log.Error(fmt.Sprintf("NewReceivedTransfer, but already exist, old=\n%s,new=\n%s",
@@ -92,6 +100,22 @@ func (model *ModelDB) NewReceivedTransfer(blockNumber int64, channelAddr common.
 	default:
 		//never block
 	}
+	model.handleReceivedTransferCallback(st)
+}
+
+func (model *ModelDB) handleReceivedTransferCallback(t *ReceivedTransfer) {
+	var cbs []*ReceivedTransferCb
+	model.mlock.Lock()
+	for f := range model.receivedTransferCallbacks {
+		remove := (*f)(t)
+		if remove {
+			cbs = append(cbs, f)
+		}
+	}
+	for _, f := range cbs {
+		delete(model.receivedTransferCallbacks, f)
+	}
+	model.mlock.Unlock()
 }
 
 //GetSentTransfer return the sent transfer by key
@@ -137,3 +161,21 @@ func (model *ModelDB) GetReceivedTransferInBlockRange(fromBlock, toBlock int64)
 	}
 	return
 }
+
+//GetSentTransferByIdentifier returns the sent transfers with payment identifier `identifier`
+func (model *ModelDB) GetSentTransferByIdentifier(identifier uint64) (transfers []*SentTransfer, err error) {
+	err = model.db.Find("Identifier", identifier, &transfers)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//GetReceivedTransferByIdentifier returns the received transfers with payment identifier `identifier`
+func (model *ModelDB) GetReceivedTransferByIdentifier(identifier uint64) (transfers []*ReceivedTransfer, err error) {
+	err = model.db.Find("Identifier", identifier, &transfers)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
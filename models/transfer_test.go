@@ -13,7 +13,7 @@ func TestModelDB_NewReceivedTransfer(t *testing.T) {
 	m := setupDb(t)
 	taddr := utils.NewRandomAddress()
 	caddr := utils.NewRandomHash()
-	m.NewReceivedTransfer(2, caddr, taddr, taddr, 3, big.NewInt(10))
+	m.NewReceivedTransfer(2, caddr, taddr, taddr, 3, big.NewInt(10), 0, "")
 	key := fmt.Sprintf("%s-%d", caddr.String(), 3)
 	r, err := m.GetReceivedTransfer(key)
 	if err != nil {
@@ -25,8 +25,8 @@ func TestModelDB_NewReceivedTransfer(t *testing.T) {
 	assert.EqualValues(t, r.Nonce, 3)
 	assert.EqualValues(t, r.Amount, big.NewInt(10))
 
-	m.NewReceivedTransfer(3, caddr, taddr, taddr, 4, big.NewInt(10))
-	m.NewReceivedTransfer(5, caddr, taddr, taddr, 6, big.NewInt(10))
+	m.NewReceivedTransfer(3, caddr, taddr, taddr, 4, big.NewInt(10), 0, "")
+	m.NewReceivedTransfer(5, caddr, taddr, taddr, 6, big.NewInt(10), 0, "")
 
 	trs, err := m.GetReceivedTransferInBlockRange(0, 3)
 	if err != nil {
@@ -53,7 +53,7 @@ func TestModelDB_NewSentTransfer(t *testing.T) {
 	m := setupDb(t)
 	taddr := utils.NewRandomAddress()
 	caddr := utils.NewRandomHash()
-	m.NewSentTransfer(2, caddr, taddr, taddr, 3, big.NewInt(10))
+	m.NewSentTransfer(2, caddr, taddr, taddr, 3, big.NewInt(10), 0, "")
 	key := fmt.Sprintf("%s-%d", caddr.String(), 3)
 	r, err := m.GetSentTransfer(key)
 	if err != nil {
@@ -65,8 +65,8 @@ func TestModelDB_NewSentTransfer(t *testing.T) {
 	assert.EqualValues(t, r.Nonce, 3)
 	assert.EqualValues(t, r.Amount, big.NewInt(10))
 
-	m.NewSentTransfer(3, caddr, taddr, taddr, 4, big.NewInt(10))
-	m.NewSentTransfer(5, caddr, taddr, taddr, 6, big.NewInt(10))
+	m.NewSentTransfer(3, caddr, taddr, taddr, 4, big.NewInt(10), 0, "")
+	m.NewSentTransfer(5, caddr, taddr, taddr, 6, big.NewInt(10), 0, "")
 
 	trs, err := m.GetSentTransferInBlockRange(0, 3)
 	if err != nil {
@@ -88,3 +88,36 @@ func TestModelDB_NewSentTransfer(t *testing.T) {
 	}
 	assert.EqualValues(t, len(trs), 0)
 }
+
+func TestModelDB_GetTransferByIdentifier(t *testing.T) {
+	m := setupDb(t)
+	taddr := utils.NewRandomAddress()
+	caddr := utils.NewRandomHash()
+	m.NewSentTransfer(2, caddr, taddr, taddr, 3, big.NewInt(10), 33, "order 33")
+	m.NewSentTransfer(3, caddr, taddr, taddr, 4, big.NewInt(10), 34, "")
+	m.NewReceivedTransfer(2, caddr, taddr, taddr, 3, big.NewInt(10), 33, "order 33")
+
+	sts, err := m.GetSentTransferByIdentifier(33)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if assert.EqualValues(t, len(sts), 1) {
+		assert.EqualValues(t, sts[0].Nonce, 3)
+		assert.Equal(t, sts[0].Memo, "order 33")
+	}
+	rts, err := m.GetReceivedTransferByIdentifier(33)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if assert.EqualValues(t, len(rts), 1) {
+		assert.Equal(t, rts[0].Memo, "order 33")
+	}
+	rts, err = m.GetReceivedTransferByIdentifier(35)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(rts), 0)
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/gob"
+
+	"github.com/asdine/storm"
+	"github.com/asdine/storm/q"
+)
+
+/*
+WebhookTarget is an url which wants to be notified when events happen
+*/
+type WebhookTarget struct {
+	ID     int      `json:"id" storm:"id,increment"`
+	URL    string   `json:"url" storm:"unique"`
+	Secret string   `json:"-"`      //secret to sign payload with HMAC-SHA256
+	Events []string `json:"events"` //events this target interests, empty means all events
+}
+
+/*
+WebhookDelivery is a payload waiting to be delivered to a webhook target,
+it is removed once delivered.
+*/
+type WebhookDelivery struct {
+	ID          int    `json:"id" storm:"id,increment"`
+	TargetID    int    `json:"target_id" storm:"index"`
+	EventID     string `json:"event_id"`
+	EventType   string `json:"event_type"`
+	Payload     []byte `json:"payload"`
+	Attempts    int    `json:"attempts"`
+	NextAttempt int64  `json:"next_attempt" storm:"index"` //unix time of next attempt
+	LastError   string `json:"last_error"`
+}
+
+func init() {
+	gob.Register(&WebhookTarget{})
+	gob.Register(&WebhookDelivery{})
+}
+
+//Interests returns true if this target wants to be notified of `eventType`
+func (t *WebhookTarget) Interests(eventType string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+//AddWebhookTarget save a new webhook target, `t.ID` is assigned after saved
+func (model *ModelDB) AddWebhookTarget(t *WebhookTarget) error {
+	return model.db.Save(t)
+}
+
+//GetWebhookTargets returns all the webhook targets
+func (model *ModelDB) GetWebhookTargets() (ts []*WebhookTarget, err error) {
+	err = model.db.All(&ts)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
+
+//GetWebhookTarget returns the webhook target with `id`
+func (model *ModelDB) GetWebhookTarget(id int) (t *WebhookTarget, err error) {
+	t = new(WebhookTarget)
+	err = model.db.One("ID", id, t)
+	return
+}
+
+//RemoveWebhookTarget remove webhook target with `id` and all its pending deliveries
+func (model *ModelDB) RemoveWebhookTarget(id int) error {
+	t, err := model.GetWebhookTarget(id)
+	if err != nil {
+		return err
+	}
+	err = model.db.Select(q.Eq("TargetID", id)).Delete(new(WebhookDelivery))
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	return model.db.DeleteStruct(t)
+}
+
+//NewWebhookDelivery save a payload to be delivered
+func (model *ModelDB) NewWebhookDelivery(d *WebhookDelivery) error {
+	return model.db.Save(d)
+}
+
+//UpdateWebhookDelivery update a delivery after a failed attempt
+func (model *ModelDB) UpdateWebhookDelivery(d *WebhookDelivery) error {
+	return model.db.Save(d)
+}
+
+//RemoveWebhookDelivery remove a delivered payload
+func (model *ModelDB) RemoveWebhookDelivery(d *WebhookDelivery) error {
+	return model.db.DeleteStruct(d)
+}
+
+//GetDueWebhookDeliveries returns all the deliveries should be attempted before `now`
+func (model *ModelDB) GetDueWebhookDeliveries(now int64) (ds []*WebhookDelivery, err error) {
+	err = model.db.Range("NextAttempt", int64(0), now, &ds)
+	if err == storm.ErrNotFound { //ingore not found error
+		err = nil
+	}
+	return
+}
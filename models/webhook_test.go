@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_Webhook(t *testing.T) {
+	model := setupDb(t)
+	defer func() {
+		model.CloseDB()
+	}()
+	t1 := &WebhookTarget{URL: "http://127.0.0.1:8000/a", Secret: "123"}
+	err := model.AddWebhookTarget(t1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t2 := &WebhookTarget{URL: "http://127.0.0.1:8000/b", Events: []string{"received_transfer"}}
+	err = model.AddWebhookTarget(t2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.NotEqual(t, t1.ID, t2.ID)
+	err = model.AddWebhookTarget(&WebhookTarget{URL: t1.URL})
+	if err == nil {
+		t.Error("url should be unique")
+		return
+	}
+	ts, err := model.GetWebhookTargets()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(ts), 2)
+	for i := 0; i < 3; i++ {
+		err = model.NewWebhookDelivery(&WebhookDelivery{
+			TargetID:    t1.ID,
+			Payload:     []byte("{}"),
+			NextAttempt: int64(i*10 + 1),
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	err = model.NewWebhookDelivery(&WebhookDelivery{TargetID: t2.ID, NextAttempt: 5})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ds, err := model.GetDueWebhookDeliveries(10)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(ds), 2)
+	d := ds[0]
+	d.Attempts++
+	d.NextAttempt = 100
+	err = model.UpdateWebhookDelivery(d)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ds, err = model.GetDueWebhookDeliveries(10)
+	assert.EqualValues(t, len(ds), 1)
+	assert.EqualValues(t, ds[0].TargetID, t2.ID)
+	err = model.RemoveWebhookDelivery(ds[0])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ds, err = model.GetDueWebhookDeliveries(1000)
+	assert.EqualValues(t, len(ds), 3)
+	err = model.RemoveWebhookTarget(t1.ID)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ds, err = model.GetDueWebhookDeliveries(1000)
+	assert.EqualValues(t, len(ds), 0)
+	ts, err = model.GetWebhookTargets()
+	assert.EqualValues(t, len(ts), 1)
+	err = model.RemoveWebhookTarget(t1.ID)
+	if err == nil {
+		t.Error("should not found")
+	}
+}
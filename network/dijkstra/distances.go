@@ -0,0 +1,67 @@
+package dijkstra
+
+import (
+	"container/heap"
+	"math"
+)
+
+//Unreachable is the distance of a vertex which has no path to the destination
+const Unreachable = int64(math.MaxInt64)
+
+/*
+DistancesTo calculates the shortest distance from every vertex to dest, Unreachable if there is no path.
+the graph is searched backwards from dest, so every arc must have a reverse one, as arcs of a channel graph do,
+but distances of the two directions can differ: weight returns the distance of the arc from `from` to `to`,
+or false if the arc should not be used.
+the graph is only read, so DistancesTo can be called by several goroutines at the same time.
+*/
+func (g *Graph) DistancesTo(dest int, weight func(from, to int) (int64, bool)) []int64 {
+	distances := make([]int64, len(g.Verticies))
+	for i := range distances {
+		distances[i] = Unreachable
+	}
+	if dest < 0 || dest >= len(g.Verticies) {
+		return distances
+	}
+	distances[dest] = 0
+	q := &distanceQueue{{dest, 0}}
+	for q.Len() > 0 {
+		current := heap.Pop(q).(distanceItem)
+		if current.distance > distances[current.vertex] {
+			continue //visited with a shorter distance already
+		}
+		for from := range g.Verticies[current.vertex].arcs {
+			if _, ok := g.Verticies[from].arcs[current.vertex]; !ok {
+				continue
+			}
+			w, ok := weight(from, current.vertex)
+			if !ok {
+				continue
+			}
+			if d := current.distance + w; d < distances[from] {
+				distances[from] = d
+				heap.Push(q, distanceItem{from, d})
+			}
+		}
+	}
+	return distances
+}
+
+type distanceItem struct {
+	vertex   int
+	distance int64
+}
+
+type distanceQueue []distanceItem
+
+func (q distanceQueue) Len() int            { return len(q) }
+func (q distanceQueue) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q distanceQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *distanceQueue) Push(x interface{}) { *q = append(*q, x.(distanceItem)) }
+func (q *distanceQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
@@ -0,0 +1,69 @@
+package dijkstra
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDistancesTo(t *testing.T) {
+	g := NewGraph()
+	n := 200
+	for i := 0; i < n; i++ {
+		g.AddVertex(i)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < n*3; i++ {
+		a, b := r.Intn(n), r.Intn(n)
+		if a == b {
+			continue
+		}
+		g.AddArc(a, b, 1+r.Int63n(10))
+		g.AddArc(b, a, 1+r.Int63n(10))
+	}
+	weight := func(from, to int) (int64, bool) {
+		d, _ := g.Verticies[from].GetArc(to)
+		return d, true
+	}
+	dest := 7
+	distances := g.DistancesTo(dest, weight)
+	for i := 0; i < n; i++ {
+		if i == dest {
+			if distances[i] != 0 {
+				t.Errorf("distance to itself should be 0, got %d", distances[i])
+			}
+			continue
+		}
+		p, err := g.CloneGraph().Shortest(i, dest)
+		if err != nil {
+			if distances[i] != Unreachable {
+				t.Errorf("%d should be unreachable, got %d", i, distances[i])
+			}
+			continue
+		}
+		if p.Distance != distances[i] {
+			t.Errorf("distance from %d expect %d, got %d", i, p.Distance, distances[i])
+		}
+	}
+	//skipping arcs
+	distances = g.DistancesTo(dest, func(from, to int) (int64, bool) {
+		return 1, to != dest
+	})
+	for i := 0; i < n; i++ {
+		if i != dest && distances[i] != Unreachable {
+			t.Errorf("%d should be unreachable, got %d", i, distances[i])
+		}
+	}
+}
+
+func TestCloneGraph(t *testing.T) {
+	g := NewGraph()
+	g.AddVertex(0)
+	g.AddVertex(1)
+	g.AddArc(0, 1, 1)
+	g2 := g.CloneGraph()
+	g2.Verticies[0].SetWeight(5)
+	g2.DeleteArc(0, 1)
+	if d, ok := g.Verticies[0].GetArc(1); !ok || d != 1 {
+		t.Errorf("changing the clone should not change the graph, got %d %v", d, ok)
+	}
+}
@@ -31,12 +31,15 @@ func (g *Graph) CloneGraph() *Graph {
 	for k, v := range g.mapping {
 		new.mapping[k] = v
 	}
+	new.usingMap = g.usingMap
+	new.highestMapIndex = g.highestMapIndex
 	for _, v := range g.Verticies {
 		newv := v
-		new.Verticies = append(new.Verticies, newv)
+		newv.arcs = make(map[int]int64, len(v.arcs))
 		for k2, v2 := range v.arcs {
 			newv.arcs[k2] = v2
 		}
+		new.Verticies = append(new.Verticies, newv)
 	}
 	return new
 }
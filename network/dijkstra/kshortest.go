@@ -0,0 +1,143 @@
+package dijkstra
+
+import (
+	"container/heap"
+	"sort"
+)
+
+/*
+KShortest calculates at most k loopless paths from src to dest, the shortest first, by Yen's algorithm.
+paths may share any part, including the first arc. of paths with the same distance, the one with fewer verticies is first.
+weight returns the distance of the arc from `from` to `to`, or false if the arc should not be used.
+the graph is only read, so KShortest can be called by several goroutines at the same time.
+*/
+func (g *Graph) KShortest(src, dest, k int, weight func(from, to int) (int64, bool)) (paths []BestPath) {
+	if k <= 0 || src < 0 || dest < 0 || src >= len(g.Verticies) || dest >= len(g.Verticies) {
+		return
+	}
+	first, ok := g.shortestAvoiding(src, dest, weight, nil, nil)
+	if !ok {
+		return
+	}
+	paths = append(paths, first)
+	var candidates []BestPath
+	for len(paths) < k {
+		last := paths[len(paths)-1]
+		//deviate from every vertex of the last path but dest
+		for i := 0; i < len(last.Path)-1; i++ {
+			root := last.Path[:i+1]
+			removedArcs := make(map[arcKey]bool)
+			for _, p := range paths {
+				if len(p.Path) > i+1 && samePath(p.Path[:i+1], root) {
+					removedArcs[arcKey{p.Path[i], p.Path[i+1]}] = true
+				}
+			}
+			removedVerticies := make(map[int]bool)
+			for _, v := range root[:i] {
+				removedVerticies[v] = true
+			}
+			spur, ok := g.shortestAvoiding(root[i], dest, weight, removedVerticies, removedArcs)
+			if !ok {
+				continue
+			}
+			candidate := BestPath{
+				Distance: pathDistance(root, weight) + spur.Distance,
+				Path:     append(append([]int{}, root[:i]...), spur.Path...),
+			}
+			if !containsPath(paths, candidate.Path) && !containsPath(candidates, candidate.Path) {
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Distance != candidates[j].Distance {
+				return candidates[i].Distance < candidates[j].Distance
+			}
+			return len(candidates[i].Path) < len(candidates[j].Path)
+		})
+		paths = append(paths, candidates[0])
+		candidates = candidates[1:]
+	}
+	return
+}
+
+type arcKey struct {
+	from, to int
+}
+
+//shortestAvoiding is the shortest path from src to dest which passes none of `removedVerticies` and `removedArcs`
+func (g *Graph) shortestAvoiding(src, dest int, weight func(from, to int) (int64, bool), removedVerticies map[int]bool, removedArcs map[arcKey]bool) (BestPath, bool) {
+	distances := make([]int64, len(g.Verticies))
+	previous := make([]int, len(g.Verticies))
+	for i := range distances {
+		distances[i] = Unreachable
+		previous[i] = -1
+	}
+	distances[src] = 0
+	q := &distanceQueue{{src, 0}}
+	for q.Len() > 0 {
+		current := heap.Pop(q).(distanceItem)
+		if current.distance > distances[current.vertex] {
+			continue
+		}
+		if current.vertex == dest {
+			break
+		}
+		for to := range g.Verticies[current.vertex].arcs {
+			if removedVerticies[to] || removedArcs[arcKey{current.vertex, to}] {
+				continue
+			}
+			w, ok := weight(current.vertex, to)
+			if !ok {
+				continue
+			}
+			if d := current.distance + w; d < distances[to] {
+				distances[to] = d
+				previous[to] = current.vertex
+				heap.Push(q, distanceItem{to, d})
+			}
+		}
+	}
+	if distances[dest] == Unreachable {
+		return BestPath{}, false
+	}
+	var path []int
+	for v := dest; v != -1; v = previous[v] {
+		path = append(path, v)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return BestPath{distances[dest], path}, true
+}
+
+func pathDistance(path []int, weight func(from, to int) (int64, bool)) (distance int64) {
+	for i := 0; i < len(path)-1; i++ {
+		w, _ := weight(path[i], path[i+1])
+		distance += w
+	}
+	return
+}
+
+func samePath(p1, p2 []int) bool {
+	if len(p1) != len(p2) {
+		return false
+	}
+	for i := range p1 {
+		if p1[i] != p2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPath(paths []BestPath, path []int) bool {
+	for _, p := range paths {
+		if samePath(p.Path, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package dijkstra
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKShortest(t *testing.T) {
+	//the example of Yen's algorithm on wikipedia, C=0 D=1 E=2 F=3 G=4 H=5
+	g := NewGraph()
+	for i := 0; i < 6; i++ {
+		g.AddVertex(i)
+	}
+	arcs := [][3]int64{{0, 1, 3}, {0, 2, 2}, {1, 3, 4}, {2, 1, 1}, {2, 3, 2}, {2, 4, 3}, {3, 4, 2}, {3, 5, 1}, {4, 5, 2}}
+	for _, a := range arcs {
+		g.AddArc(int(a[0]), int(a[1]), a[2])
+	}
+	weight := func(from, to int) (int64, bool) {
+		d, ok := g.Verticies[from].GetArc(to)
+		return d, ok
+	}
+	paths := g.KShortest(0, 5, 3, weight)
+	expected := []BestPath{
+		{5, []int{0, 2, 3, 5}},
+		{7, []int{0, 2, 4, 5}},
+		{8, []int{0, 1, 3, 5}},
+	}
+	if !reflect.DeepEqual(expected, paths) {
+		t.Errorf("expect %v, got %v", expected, paths)
+	}
+	//there are only 7 loopless paths from C to H
+	paths = g.KShortest(0, 5, 100, weight)
+	if len(paths) != 7 {
+		t.Errorf("expect 7 paths, got %v", paths)
+	}
+	for i := 1; i < len(paths); i++ {
+		if paths[i].Distance < paths[i-1].Distance {
+			t.Errorf("paths are not ordered %v", paths)
+		}
+	}
+	//skip arc E->F
+	paths = g.KShortest(0, 5, 1, func(from, to int) (int64, bool) {
+		if from == 2 && to == 3 {
+			return 0, false
+		}
+		return weight(from, to)
+	})
+	if len(paths) != 1 || paths[0].Distance != 7 {
+		t.Errorf("expect C-E-G-H, got %v", paths)
+	}
+	if paths = g.KShortest(5, 0, 3, weight); len(paths) != 0 {
+		t.Errorf("expect no path, got %v", paths)
+	}
+}
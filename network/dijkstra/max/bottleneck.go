@@ -0,0 +1,38 @@
+package max
+
+import "math"
+
+//Bottleneck finds the path whose smallest arc is the largest (the widest path),
+// Distance of the result is that smallest arc. arcs are capacities here.
+func (g *Graph) Bottleneck(src, dest int) (BestPath, error) {
+	g.setup(src, math.MaxInt64)
+	var current *Vertex
+	for g.visiting.len > 0 {
+		current = g.visiting.popFront()
+		if current.ID == dest {
+			g.visitedDest = true
+			continue
+		}
+		for v, capacity := range current.arcs {
+			//the width can only shrink along a path, so there is no loop to worry about
+			vNewD := current.narrow(capacity)
+			if vNewD > g.Verticies[v].best {
+				g.Verticies[v].best = vNewD
+				g.Verticies[v].bestVertex = current.ID
+				if v == dest {
+					g.best = vNewD
+				}
+				g.visiting.push(&g.Verticies[v])
+			}
+		}
+	}
+	return g.finally(src, dest)
+}
+
+//narrow returns the width of the path to v extended with an arc of `capacity`
+func (v *Vertex) narrow(capacity int64) int64 {
+	if capacity < v.best {
+		return capacity
+	}
+	return v.best
+}
@@ -0,0 +1,31 @@
+package max
+
+import "testing"
+
+func TestBottleneck(t *testing.T) {
+	g := NewGraph()
+	for i := 0; i < 5; i++ {
+		g.AddVertex(i)
+	}
+	//0-1-4 is the shortest but narrow, 0-2-3-4 is the widest
+	g.Verticies[0].AddArc(1, 100)
+	g.Verticies[1].AddArc(4, 5)
+	g.Verticies[0].AddArc(2, 50)
+	g.Verticies[2].AddArc(3, 30)
+	g.Verticies[3].AddArc(4, 40)
+	g.Verticies[2].AddArc(0, 50)
+	p, err := g.Bottleneck(0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Distance != 30 {
+		t.Errorf("expect width 30, got %d", p.Distance)
+	}
+	if len(p.Path) != 4 || p.Path[1] != 2 || p.Path[2] != 3 {
+		t.Errorf("wrong path %v", p.Path)
+	}
+	_, err = g.Bottleneck(4, 0)
+	if err == nil {
+		t.Error("expect no path")
+	}
+}
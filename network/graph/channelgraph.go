@@ -9,13 +9,18 @@ import (
 
 	"strings"
 
+	"math"
 	"math/big"
 
+	"sync"
+
 	"github.com/SmartMeshFoundation/SmartRaiden/channel"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/dijkstra"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/dijkstra/max"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/fee"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/xmpptransport"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -45,10 +50,20 @@ type NodesStatusGetter interface {
 	GetNetworkStatus(addr common.Address) (deviceType string, isOnline bool)
 }
 
-//ChannelGraph is a Graph based on the channels and can find path between participants.
-//整个 ChannelGraph 只能单线程访问
-// The whole ChannelGraph can only be accessed by a single process.
+//RouteHistory tells how reliable a hop has been when routing transfers
+type RouteHistory interface {
+	//Penalty returns how likely a transfer from `from` to `to` fails, between 0 and 1, and whether `to` should not be tried unless nothing else can be
+	Penalty(tokenAddress, from, to common.Address) (penalty float64, exclude bool)
+}
+
+/*
+ChannelGraph is a Graph based on the channels and can find path between participants.
+it's safe for concurrent use, searching paths only reads the graph,
+the shortest distances to a target are cached until the graph, the fees or the capacities change.
+read channels through Channels, GetPartenerAddress2Channel and GetChannelAddress2Channel instead of the maps.
+*/
 type ChannelGraph struct {
+	lock                    sync.RWMutex
 	g                       *dijkstra.Graph
 	OurAddress              common.Address
 	TokenAddress            common.Address
@@ -56,6 +71,30 @@ type ChannelGraph struct {
 	ChannelAddress2Channel  map[common.Hash]*channel.Channel
 	address2index           map[common.Address]int
 	index2address           map[int]common.Address
+	capacities              map[edge]*big.Int //observed capacities of channels I'm not a participant
+	routingMode             string
+	history                 RouteHistory
+	cacheLock               sync.Mutex
+	distances               map[distanceKey][]int64 //shortest distances from every node to a target,protected by cacheLock
+}
+
+/*
+distanceKey is what shortest distances to a target depend on besides the graph,
+if routing mode is params.RoutingModeCombined, channels of mine too small for the amount are skipped,
+their balances change with every transfer, so they are part of the key.
+*/
+type distanceKey struct {
+	target   int
+	amount   string
+	charger  fee.Charger
+	tooSmall string
+}
+
+//maxCachedDistances at most this many targets and amounts are cached
+const maxCachedDistances = 64
+
+type edge struct {
+	from, to int
 }
 
 /*
@@ -69,13 +108,93 @@ func NewChannelGraph(ourAddress, tokenAddress common.Address, edges []common.Add
 		ChannelAddress2Channel:  make(map[common.Hash]*channel.Channel),
 		address2index:           make(map[common.Address]int),
 		index2address:           make(map[int]common.Address),
+		capacities:              make(map[edge]*big.Int),
+		routingMode:             params.RoutingModeFee,
+		distances:               make(map[distanceKey][]int64),
 		g:                       dijkstra.NewGraph(),
 	}
 	cg.makeGraph(edges)
 	cg.printGraph()
 	return cg
 }
+/*
+SetRoutingMode chooses how paths are preferred, one of params.RoutingModeFee, params.RoutingModeCapacity and params.RoutingModeCombined
+*/
+func (cg *ChannelGraph) SetRoutingMode(mode string) {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	cg.routingMode = mode
+	cg.clearDistances()
+}
+
+/*
+SetRouteHistory lets routes avoid hops that failed recently,
+they are tried after the others, and not tried at all if `history` says so.
+*/
+func (cg *ChannelGraph) SetRouteHistory(history RouteHistory) {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	cg.history = history
+}
+
+//ClearPathCache drops the cached distances, call it when fees charged by nodes change
+func (cg *ChannelGraph) ClearPathCache() {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	cg.clearDistances()
+}
+
+func (cg *ChannelGraph) clearDistances() {
+	cg.cacheLock.Lock()
+	cg.distances = make(map[distanceKey][]int64)
+	cg.cacheLock.Unlock()
+}
+
+/*
+SetCapacity records capacity of the channel between `participant1` and `participant2`, I'm not a participant of this channel.
+balances of the channel change off-chain, so its total deposit is used in both directions,
+it's the most either participant can send.
+*/
+func (cg *ChannelGraph) SetCapacity(participant1, participant2 common.Address, capacity *big.Int) {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	index1, ok := cg.address2index[participant1]
+	if !ok {
+		return
+	}
+	index2, ok := cg.address2index[participant2]
+	if !ok {
+		return
+	}
+	cg.capacities[edge{index1, index2}] = capacity
+	cg.capacities[edge{index2, index1}] = capacity
+	cg.clearDistances()
+}
+
+/*
+capacity returns the most tokens node `from` can send to node `to`,
+it's exact for my channels, and nil if unknown.
+*/
+func (cg *ChannelGraph) capacity(from, to int) *big.Int {
+	fromAddress := cg.index2address[from]
+	toAddress := cg.index2address[to]
+	if fromAddress == cg.OurAddress {
+		if c := cg.PartenerAddress2Channel[toAddress]; c != nil {
+			return c.Distributable()
+		}
+	}
+	if toAddress == cg.OurAddress {
+		if c := cg.PartenerAddress2Channel[fromAddress]; c != nil {
+			return c.PartnerState.Distributable(c.OurState)
+		}
+	}
+	return cg.capacities[edge{from, to}]
+}
+
 func (cg *ChannelGraph) printGraph() {
+	if len(cg.index2address) > maxPrintNodes {
+		return
+	}
 	fmt.Printf("%s channel graph", utils.APex2(cg.TokenAddress))
 	rowheader := fmt.Sprintf("%s", strings.Repeat(" ", 14))
 	for i := 0; i < len(cg.index2address); i++ {
@@ -100,14 +219,24 @@ func (cg *ChannelGraph) printGraph() {
 	}
 }
 
+//maxPrintNodes graphs larger than this are too large to print
+const maxPrintNodes = 32
+
 func (cg *ChannelGraph) makeGraph(edges []common.Address) {
 	for i := 0; i < len(edges); i += 2 {
-		cg.AddPath(edges[i], edges[i+1])
+		cg.addPath(edges[i], edges[i+1])
 	}
 }
 
 //AddPath Add a new edge into the network.
 func (cg *ChannelGraph) AddPath(source, target common.Address) {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	cg.addPath(source, target)
+	cg.printGraph()
+}
+
+func (cg *ChannelGraph) addPath(source, target common.Address) {
 	addr1 := source
 	addr2 := target
 	if index1, ok := cg.address2index[addr1]; !ok {
@@ -140,7 +269,7 @@ func (cg *ChannelGraph) AddPath(source, target common.Address) {
 	if err != nil {
 		log.Error(fmt.Sprintf("add path err%s", err))
 	}
-	cg.printGraph()
+	cg.clearDistances()
 }
 
 /*
@@ -152,11 +281,14 @@ func (cg *ChannelGraph) AddChannel(ch *channel.Channel) error {
 	if ch.OurState.Address != cg.OurAddress {
 		return errors.New("Address mismatch, our_address doesn't match the channel details")
 	}
-	if ch2 := cg.GetChannelAddress2Channel(ch.ChannelIdentifier.ChannelIdentifier); ch2 == nil {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	if ch2 := cg.ChannelAddress2Channel[ch.ChannelIdentifier.ChannelIdentifier]; ch2 == nil {
 
 		cg.PartenerAddress2Channel[ch.PartnerState.Address] = ch
 		cg.ChannelAddress2Channel[ch.ChannelIdentifier.ChannelIdentifier] = ch
-		cg.AddPath(ch.OurState.Address, ch.PartnerState.Address)
+		cg.addPath(ch.OurState.Address, ch.PartnerState.Address)
+		cg.printGraph()
 	} else {
 		log.Info(fmt.Sprintf("add channel %s,but channel already exist", ch.ChannelIdentifier.String()))
 	}
@@ -167,6 +299,8 @@ func (cg *ChannelGraph) AddChannel(ch *channel.Channel) error {
 HasChannel return  True if there is a connecting path regardless of the number of hops.
 */
 func (cg *ChannelGraph) HasChannel(source, target common.Address) bool {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
 	sourceIndex, ok := cg.address2index[source]
 	if !ok {
 		return false
@@ -175,16 +309,21 @@ func (cg *ChannelGraph) HasChannel(source, target common.Address) bool {
 	if !ok {
 		return false
 	}
-	_, err := cg.g.Shortest(sourceIndex, targetIndex)
-	return err == nil
+	return cg.distancesTo(targetIndex, nil, nil)[sourceIndex] != dijkstra.Unreachable
 }
 
 var errAddressNotFoundInGraph = errors.New("address not found in channelgraph")
 
 /*
-ShortestPath returns the shortestpath weight from source to target.  make sure only be called in one thread.
+ShortestPath returns the shortestpath weight from source to target.
 */
 func (cg *ChannelGraph) ShortestPath(source, target common.Address, amount *big.Int, feeCharger fee.Charger) (totalWeight int64, err error) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
+	return cg.shortestPath(source, target, amount, feeCharger)
+}
+
+func (cg *ChannelGraph) shortestPath(source, target common.Address, amount *big.Int, feeCharger fee.Charger) (totalWeight int64, err error) {
 	sourceIndex, ok := cg.address2index[source]
 	if !ok {
 		err = errAddressNotFoundInGraph
@@ -198,34 +337,173 @@ func (cg *ChannelGraph) ShortestPath(source, target common.Address, amount *big.
 	if sourceIndex == targetIndex {
 		return 0, nil
 	}
-	var g2 *dijkstra.Graph
-	if false { //make sure only be called in one thread.
-		g2 = cg.g.CloneGraph()
-	} else {
-		g2 = cg.g
+	totalWeight = cg.distancesTo(targetIndex, amount, feeCharger)[sourceIndex]
+	if totalWeight == dijkstra.Unreachable {
+		return 0, dijkstra.ErrNoPath
 	}
-	for _, v := range g2.Verticies {
-		w := feeCharger.GetNodeChargeFee(cg.index2address[v.ID], cg.TokenAddress, amount).Int64()
-		if w > 0 { //for no fee policy, all nodes charge 0 ,so use the shortest path first.
-			v.SetWeight(w) // from v's fee is w.
+	return
+}
+
+/*
+distancesTo returns the shortest distances from every node to `target`, cached until the graph changes.
+without `feeCharger` every hop weighs 1.
+*/
+func (cg *ChannelGraph) distancesTo(target int, amount *big.Int, feeCharger fee.Charger) []int64 {
+	key := distanceKey{
+		target:  target,
+		charger: feeCharger,
+	}
+	if amount != nil {
+		key.amount = amount.String()
+		if cg.routingMode == params.RoutingModeCombined {
+			key.tooSmall = cg.ourChannelsTooSmall(amount)
+		}
+	}
+	cg.cacheLock.Lock()
+	distances, ok := cg.distances[key]
+	cg.cacheLock.Unlock()
+	if ok {
+		return distances
+	}
+	distances = cg.g.DistancesTo(target, cg.arcWeight(amount, feeCharger, nil))
+	cg.cacheLock.Lock()
+	if len(cg.distances) >= maxCachedDistances {
+		cg.distances = make(map[distanceKey][]int64)
+	}
+	cg.distances[key] = distances
+	cg.cacheLock.Unlock()
+	return distances
+}
+
+/*
+arcWeight returns the weight of arc from `from` to `to`, the fee charged by `from`, or 1 if it charges no fee,
+false if the arc cannot be used: `skip` says so, or it is known to be too small for `amount` in params.RoutingModeCombined.
+*/
+func (cg *ChannelGraph) arcWeight(amount *big.Int, feeCharger fee.Charger, skip func(from, to int) bool) func(from, to int) (int64, bool) {
+	weights := make([]int64, len(cg.g.Verticies)) //0 if not charged yet
+	return func(from, to int) (int64, bool) {
+		if skip != nil && skip(from, to) {
+			return 0, false
+		}
+		if amount != nil && cg.routingMode == params.RoutingModeCombined {
+			//total deposit is the most a channel can carry, so no usable path is lost.
+			c := cg.capacity(from, to)
+			if c != nil && c.Cmp(amount) < 0 {
+				return 0, false
+			}
 		}
+		if weights[from] == 0 {
+			weights[from] = 1 //for no fee policy, all nodes charge 0 ,so use the shortest path first.
+			if feeCharger != nil {
+				if w := feeCharger.GetNodeChargeFee(cg.index2address[from], cg.TokenAddress, amount).Int64(); w > 0 {
+					weights[from] = w
+				}
+			}
+		}
+		return weights[from], true
 	}
-	path, err := g2.Shortest(sourceIndex, targetIndex)
+}
+
+//ourChannelsTooSmall tells which directions of my channels cannot carry `amount`
+func (cg *ChannelGraph) ourChannelsTooSmall(amount *big.Int) string {
+	ourIndex, ok := cg.address2index[cg.OurAddress]
+	if !ok {
+		return ""
+	}
+	neighbors, err := cg.g.GetAllNeighbors(ourIndex)
+	if err != nil {
+		return ""
+	}
+	sort.Ints(neighbors)
+	var b []byte
+	for _, n := range neighbors {
+		if c := cg.capacity(ourIndex, n); c != nil && c.Cmp(amount) < 0 {
+			b = append(b, fmt.Sprintf("%d>", n)...)
+		}
+		if c := cg.capacity(n, ourIndex); c != nil && c.Cmp(amount) < 0 {
+			b = append(b, fmt.Sprintf("%d<", n)...)
+		}
+	}
+	return string(b)
+}
+
+/*
+Bottleneck returns the largest amount that can be sent from `source` to `target` along one path,
+by the capacities known, unknown capacities are taken as unlimited.
+*/
+func (cg *ChannelGraph) Bottleneck(source, target common.Address) (width int64, err error) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
+	return cg.bottleneck(cg.capacityGraph(target), source, target)
+}
+
+func (cg *ChannelGraph) bottleneck(mg *max.Graph, source, target common.Address) (width int64, err error) {
+	sourceIndex, ok := cg.address2index[source]
+	if !ok {
+		err = errAddressNotFoundInGraph
+		return
+	}
+	targetIndex, ok := cg.address2index[target]
+	if !ok {
+		err = errAddressNotFoundInGraph
+		return
+	}
+	if sourceIndex == targetIndex {
+		return math.MaxInt64, nil
+	}
+	path, err := mg.Bottleneck(sourceIndex, targetIndex)
 	if err != nil {
 		return
 	}
 	return path.Distance, nil
 }
 
+//capacityGraph converts the graph to arcs of capacities, paths to `target` never pass me in the middle.
+func (cg *ChannelGraph) capacityGraph(target common.Address) *max.Graph {
+	mg := max.NewGraph()
+	for i := range cg.g.Verticies {
+		mg.AddVertex(i)
+	}
+	ourIndex := cg.address2index[cg.OurAddress]
+	for i := range cg.g.Verticies {
+		neighbors, err := cg.g.GetAllNeighbors(i)
+		if err != nil {
+			continue
+		}
+		for _, j := range neighbors {
+			if j == ourIndex && target != cg.OurAddress {
+				continue
+			}
+			mg.Verticies[i].AddArc(j, capacityToInt64(cg.capacity(i, j)))
+		}
+	}
+	return mg
+}
+
+func capacityToInt64(c *big.Int) int64 {
+	if c == nil || !c.IsInt64() {
+		return math.MaxInt64
+	}
+	return c.Int64()
+}
+
 //RemoveChannel remove a channel from graph,and i'm a participant of this channel
 func (cg *ChannelGraph) RemoveChannel(ch *channel.Channel) {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
 	delete(cg.ChannelAddress2Channel, ch.ChannelIdentifier.ChannelIdentifier)
 	delete(cg.PartenerAddress2Channel, ch.PartnerState.Address)
-	cg.RemovePath(ch.OurState.Address, ch.PartnerState.Address)
+	cg.removePath(ch.OurState.Address, ch.PartnerState.Address)
 }
 
 //RemovePath Remove an edge from the network.  this edge may  not exist
 func (cg *ChannelGraph) RemovePath(source, target common.Address) {
+	cg.lock.Lock()
+	defer cg.lock.Unlock()
+	cg.removePath(source, target)
+}
+
+func (cg *ChannelGraph) removePath(source, target common.Address) {
 	sourceIndex, ok := cg.address2index[source]
 	if !ok {
 		return
@@ -242,6 +520,9 @@ func (cg *ChannelGraph) RemovePath(source, target common.Address) {
 	if err != nil {
 		log.Error(fmt.Sprintf("remove arc %d-%d err %s", sourceIndex, targetIndex, err))
 	}
+	delete(cg.capacities, edge{sourceIndex, targetIndex})
+	delete(cg.capacities, edge{targetIndex, sourceIndex})
+	cg.clearDistances()
 }
 
 /*
@@ -252,7 +533,7 @@ func (cg *ChannelGraph) ChannelCanTransfer(partenerAddress common.Address) bool
 	return cg.GetPartenerAddress2Channel(partenerAddress).CanTransfer()
 }
 
-//getNeighbours Get all neighbours adjacent to self.our_address. g is not thread safe
+//getNeighbours Get all neighbours adjacent to self.our_address. caller must hold the lock
 func (cg *ChannelGraph) getNeighbours() []common.Address {
 	neighboursIndex, err := cg.g.GetAllNeighbors(cg.address2index[cg.OurAddress])
 	if err != nil {
@@ -267,7 +548,10 @@ func (cg *ChannelGraph) getNeighbours() []common.Address {
 
 type neighborWeight struct {
 	neighbor common.Address
-	weight   int64 //nerghbor to target's hops
+	weight   int64   //nerghbor to target's hops
+	width    int64   //bottleneck capacity from me to target through neighbor, only for params.RoutingModeCapacity
+	penalty  float64 //how likely the neighbor fails, from route history
+	excluded bool    //the neighbor failed too many times recently, used only if no other neighbor can be
 }
 type neighborWeightList []*neighborWeight
 
@@ -286,20 +570,63 @@ func (nw neighborWeightList) Swap(i, j int) {
 
 /*
 all the neighbors that can reach target
-they are ordered by hops to the target
+they are ordered by hops to the target,
+or by the bottleneck capacity of the widest path through them if routing mode is params.RoutingModeCapacity.
+neighbors that failed recently come after the others, the less reliable the later,
+neighbors failing too many times recently are the last, marked as excluded.
+distances of all the neighbors come from one search backwards from the target.
 */
 func (cg *ChannelGraph) orderedNeighbours(ourAddress, targetAddress common.Address, amount *big.Int, charger fee.Charger) neighborWeightList {
-
+	targetIndex, ok := cg.address2index[targetAddress]
+	if !ok {
+		return nil
+	}
+	distances := cg.distancesTo(targetIndex, amount, charger)
 	neighbors := cg.getNeighbours()
 	var nws neighborWeightList
+	var mg *max.Graph
+	if cg.routingMode == params.RoutingModeCapacity {
+		mg = cg.capacityGraph(targetAddress)
+	}
 	for _, n := range neighbors {
-		w, err := cg.ShortestPath(n, targetAddress, amount, charger)
-		if err != nil {
+		w := distances[cg.address2index[n]]
+		if w == dijkstra.Unreachable {
 			continue
 		}
-		nws = append(nws, &neighborWeight{n, w})
+		var err error
+		nw := &neighborWeight{neighbor: n, weight: w}
+		if cg.history != nil {
+			nw.penalty, nw.excluded = cg.history.Penalty(cg.TokenAddress, ourAddress, n)
+		}
+		if mg != nil {
+			nw.width, err = cg.bottleneck(mg, n, targetAddress)
+			if err != nil {
+				continue
+			}
+			if c := capacityToInt64(cg.capacity(cg.address2index[ourAddress], cg.address2index[n])); c < nw.width {
+				nw.width = c
+			}
+		}
+		nws = append(nws, nw)
+	}
+	if mg != nil {
+		sort.SliceStable(nws, func(i, j int) bool {
+			if nws[i].width != nws[j].width {
+				return nws[i].width > nws[j].width
+			}
+			return nws[i].weight < nws[j].weight
+		})
+	} else {
+		sort.Sort(nws)
+	}
+	if cg.history != nil {
+		sort.SliceStable(nws, func(i, j int) bool {
+			if nws[i].excluded != nws[j].excluded {
+				return nws[j].excluded
+			}
+			return nws[i].penalty < nws[j].penalty
+		})
 	}
-	sort.Sort(nws)
 	return nws
 }
 
@@ -313,27 +640,24 @@ GetBestRoutes returns all neighbor nodes order by weight from it to target.
  *
  *	Note that the routing algorithm we currently use should be the shortest-path/minimized-fee algorithm with history record,
  *	which circumvents all routes that have been iterated.
+ *	neighbors failing too many times recently are returned only if there is no other route.
  */
 func (cg *ChannelGraph) GetBestRoutes(nodesStatus NodesStatusGetter, ourAddress common.Address,
 	targetAdress common.Address, amount *big.Int, excludeAddresses map[common.Address]bool, feeCharger fee.Charger) (onlineNodes []*route.State) {
 	/*
-
-	   XXX: consider using multiple channels for a single transfer. Useful
-	   for cases were the `amount` is larger than what is available
-	   individually in any of the channels.
-
-	   One possible approach is to _not_ filter these channels based on the
-	   distributable amount, but to sort them based on available balance and
-	   let the task use as many as required to finish the transfer.
-
+		if `amount` is larger than what is available individually in any of the channels,
+		use GetMultiPartRoutes to split the transfer across several channels.
 	*/
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
 	nws := cg.orderedNeighbours(ourAddress, targetAdress, amount, feeCharger)
 	if len(nws) == 0 {
 		log.Warn(fmt.Sprintf("no routes avaiable from %s to %s", utils.APex(ourAddress), utils.APex(targetAdress)))
 		return
 	}
+	var excluded []*route.State
 	for _, nw := range nws {
-		c := cg.GetPartenerAddress2Channel(nw.neighbor)
+		c := cg.PartenerAddress2Channel[nw.neighbor]
 		//don't send the message backwards
 		if excludeAddresses[nw.neighbor] {
 			continue
@@ -357,17 +681,203 @@ func (cg *ChannelGraph) GetBestRoutes(nodesStatus NodesStatusGetter, ourAddress
 		} else { //no fee policy,
 			routeState.TotalFee = utils.BigInt0
 		}
-
+		if nw.excluded {
+			log.Debug(fmt.Sprintf("%s failed too many times recently,only used if nothing else works", utils.APex(nw.neighbor)))
+			excluded = append(excluded, routeState)
+			continue
+		}
 		onlineNodes = append(onlineNodes, routeState)
 	}
+	//a hop failing again and again is still better than no route at all
+	if len(onlineNodes) == 0 {
+		onlineNodes = excluded
+	}
+	return
+}
+/*
+GetMultiPartRoutes splits `amount` across several neighbours for a multi-part payment.
+channels are not filtered by the distributable amount, they are used in the same order as GetBestRoutes,
+each one carries as much as it can until the whole `amount` is covered, so neighbors failing too many times recently are used last,
+at most params.MaxPaymentParts routes are used.
+returns nil if all the usable channels together cannot carry `amount`.
+`amounts[i]` is what the target should receive from `routes[i]`, the fee of that part is `routes[i].TotalFee`.
+*/
+func (cg *ChannelGraph) GetMultiPartRoutes(nodesStatus NodesStatusGetter, ourAddress common.Address,
+	targetAdress common.Address, amount *big.Int, excludeAddresses map[common.Address]bool, feeCharger fee.Charger) (routes []*route.State, amounts []*big.Int) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
+	nws := cg.orderedNeighbours(ourAddress, targetAdress, amount, feeCharger)
+	left := new(big.Int).Set(amount)
+	for _, nw := range nws {
+		if left.Cmp(utils.BigInt0) <= 0 || len(routes) >= params.MaxPaymentParts {
+			break
+		}
+		c := cg.PartenerAddress2Channel[nw.neighbor]
+		if excludeAddresses[nw.neighbor] || !c.CanTransfer() {
+			continue
+		}
+		deviceType, isOnline := nodesStatus.GetNetworkStatus(nw.neighbor)
+		if !isOnline || (deviceType == xmpptransport.TypeMobile && nw.neighbor != targetAdress) {
+			continue
+		}
+		distributable := c.Distributable()
+		part := new(big.Int).Set(left)
+		if part.Cmp(distributable) > 0 {
+			part.Set(distributable)
+		}
+		if part.Cmp(utils.BigInt0) <= 0 {
+			continue
+		}
+		routeState := Channel2RouteState(c, nw.neighbor, part, feeCharger)
+		routeState.TotalFee = utils.BigInt0
+		if routeState.Fee.Cmp(utils.BigInt0) > 0 {
+			w, err := cg.shortestPath(nw.neighbor, targetAdress, part, feeCharger)
+			if err != nil {
+				continue
+			}
+			routeState.TotalFee = big.NewInt(w)
+		}
+		//fee of this part is paid through the same channel
+		if new(big.Int).Add(part, routeState.TotalFee).Cmp(distributable) > 0 {
+			part.Sub(distributable, routeState.TotalFee)
+			if part.Cmp(utils.BigInt0) <= 0 {
+				continue
+			}
+		}
+		routes = append(routes, routeState)
+		amounts = append(amounts, part)
+		left.Sub(left, part)
+	}
+	if left.Cmp(utils.BigInt0) > 0 {
+		log.Warn(fmt.Sprintf("not enough capacity from %s to %s for %s, %s left", utils.APex(ourAddress), utils.APex(targetAdress), amount, left))
+		return nil, nil
+	}
+	return
+}
+
+//Path is a full path from me to the target
+type Path struct {
+	Hops     []common.Address `json:"hops"`      //nodes after me, the last one is the target
+	TotalFee *big.Int         `json:"total_fee"` //fee charged by all the nodes after me
+}
+
+/*
+GetKShortestPaths returns at most `k` loopless paths from `ourAddress` to `targetAdress`, the cheapest first.
+unlike GetBestRoutes, paths can share the first hop, so it tells the alternatives behind each neighbor.
+it is for route preview only and never chooses routes of a transfer, hops after the first one choose their next hop by themselves.
+the first hop must be able to transfer and not be in `excludeAddresses`, no path passes any node of `excludeAddresses`,
+and neighbors that failed too many times recently are skipped.
+*/
+func (cg *ChannelGraph) GetKShortestPaths(ourAddress, targetAdress common.Address, amount *big.Int, k int,
+	excludeAddresses map[common.Address]bool, feeCharger fee.Charger) (paths []*Path) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
+	ourIndex, ok := cg.address2index[ourAddress]
+	if !ok {
+		return
+	}
+	targetIndex, ok := cg.address2index[targetAdress]
+	if !ok || targetIndex == ourIndex {
+		return
+	}
+	skip := func(from, to int) bool {
+		toAddress := cg.index2address[to]
+		if excludeAddresses[toAddress] {
+			return true
+		}
+		if from != ourIndex {
+			return false
+		}
+		if c := cg.PartenerAddress2Channel[toAddress]; c == nil || !c.CanTransfer() {
+			return true
+		}
+		if cg.history != nil {
+			_, exclude := cg.history.Penalty(cg.TokenAddress, ourAddress, toAddress)
+			return exclude
+		}
+		return false
+	}
+	weight := cg.arcWeight(amount, feeCharger, skip)
+	for _, bp := range cg.g.KShortest(ourIndex, targetIndex, k, weight) {
+		p := &Path{TotalFee: utils.BigInt0}
+		for _, v := range bp.Path[1:] {
+			p.Hops = append(p.Hops, cg.index2address[v])
+		}
+		//same as GetBestRoutes, no fee if the first hop charges nothing
+		if feeCharger != nil && feeCharger.GetNodeChargeFee(p.Hops[0], cg.TokenAddress, amount).Cmp(utils.BigInt0) > 0 {
+			w, _ := weight(ourIndex, bp.Path[1])
+			p.TotalFee = big.NewInt(bp.Distance - w)
+		}
+		paths = append(paths, p)
+	}
 	return
 }
+
+/*
+GetRebalanceRoute returns the route of a payment from me back to me, out through the channel with `outPartner`
+and back through the channel with `inPartner`.
+the path must not pass me in the middle, so all my other channels are excluded when searching it.
+TotalFee of the route is the fee charged by all the nodes of the circle but me.
+*/
+func (cg *ChannelGraph) GetRebalanceRoute(nodesStatus NodesStatusGetter, outPartner, inPartner common.Address,
+	amount *big.Int, feeCharger fee.Charger) (r *route.State, err error) {
+	if outPartner == inPartner {
+		err = errors.New("rebalance needs two different channels")
+		return
+	}
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
+	out := cg.PartenerAddress2Channel[outPartner]
+	in := cg.PartenerAddress2Channel[inPartner]
+	if out == nil || in == nil {
+		err = errors.New("channel not found")
+		return
+	}
+	if !out.CanTransfer() || !in.CanTransfer() {
+		err = errors.New("channel cannot transfer")
+		return
+	}
+	if amount.Cmp(out.Distributable()) > 0 {
+		err = fmt.Errorf("channel with %s doesn't have enough balance", utils.APex(outPartner))
+		return
+	}
+	if amount.Cmp(in.PartnerState.Distributable(in.OurState)) > 0 {
+		err = fmt.Errorf("%s doesn't have enough balance in its channel with me", utils.APex(inPartner))
+		return
+	}
+	if _, isOnline := nodesStatus.GetNetworkStatus(outPartner); !isOnline {
+		err = fmt.Errorf("%s is offline", utils.APex(outPartner))
+		return
+	}
+	//exclude my channels except the arc from `inPartner` to me, this search is not cached.
+	ourIndex := cg.address2index[cg.OurAddress]
+	inIndex := cg.address2index[inPartner]
+	paths := cg.g.KShortest(cg.address2index[outPartner], ourIndex, 1, cg.arcWeight(amount, feeCharger, func(from, to int) bool {
+		return from == ourIndex || (to == ourIndex && from != inIndex)
+	}))
+	if len(paths) == 0 {
+		err = fmt.Errorf("no path from %s back to me through %s", utils.APex(outPartner), utils.APex(inPartner))
+		return
+	}
+	r = Channel2RouteState(out, outPartner, amount, feeCharger)
+	//a node charging no fee weighs 1 in the search, so the fee is summed from the nodes of the circle
+	r.TotalFee = new(big.Int)
+	for _, v := range paths[0].Path[:len(paths[0].Path)-1] {
+		r.TotalFee.Add(r.TotalFee, feeCharger.GetNodeChargeFee(cg.index2address[v], cg.TokenAddress, amount))
+	}
+	return
+}
+
 func (cg *ChannelGraph) haveNodes() bool {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
 	return len(cg.g.Verticies) > 0
 }
 
 //AllNodes returns all neighbor nodes
 func (cg *ChannelGraph) AllNodes() (nodes []common.Address) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
 	for n := range cg.address2index {
 		nodes = append(nodes, n)
 	}
@@ -376,6 +886,8 @@ func (cg *ChannelGraph) AllNodes() (nodes []common.Address) {
 
 //GetPartenerAddress2Channel returns a channel between me and address
 func (cg *ChannelGraph) GetPartenerAddress2Channel(address common.Address) (c *channel.Channel) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
 	c = cg.PartenerAddress2Channel[address]
 	if c == nil {
 		//log.Error(fmt.Sprintf("no channel with %s on token %s", utils.APex(address), utils.APex(cg.TokenAddress)))
@@ -385,10 +897,22 @@ func (cg *ChannelGraph) GetPartenerAddress2Channel(address common.Address) (c *c
 
 //GetChannelAddress2Channel return a channel by address,maybe nil if not exist
 func (cg *ChannelGraph) GetChannelAddress2Channel(address common.Hash) (c *channel.Channel) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
 	c = cg.ChannelAddress2Channel[address]
 	return
 }
 
+//Channels returns all the channels I participate on this token
+func (cg *ChannelGraph) Channels() (cs []*channel.Channel) {
+	cg.lock.RLock()
+	defer cg.lock.RUnlock()
+	for _, c := range cg.ChannelAddress2Channel {
+		cs = append(cs, c)
+	}
+	return
+}
+
 //Channel2RouteState create a routeState from a channel
 func Channel2RouteState(c *channel.Channel, partenerAddress common.Address, amount *big.Int, charger fee.Charger) *route.State {
 	rs := route.NewState(c)
@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type proportionalCharger struct{}
+
+func (p *proportionalCharger) GetNodeChargeFee(nodeAddress, tokenAddress common.Address, amount *big.Int) *big.Int {
+	f := new(big.Int).Div(amount, big.NewInt(1000))
+	return f.Add(f, big.NewInt(int64(nodeAddress[0]%5)))
+}
+
+//newBenchGraph makes a graph of `nodes` nodes, each one opens `degree` channels with random nodes
+func newBenchGraph(nodes, degree int) (cg *ChannelGraph, targets []common.Address) {
+	r := rand.New(rand.NewSource(1))
+	addrs := make([]common.Address, nodes)
+	for i := range addrs {
+		addrs[i] = utils.NewRandomAddress()
+	}
+	var edges []common.Address
+	for i := range addrs {
+		for j := 0; j < degree; j++ {
+			k := r.Intn(nodes)
+			if k != i {
+				edges = append(edges, addrs[i], addrs[k])
+			}
+		}
+	}
+	cg = NewChannelGraph(addrs[0], utils.NewRandomAddress(), edges)
+	for i := 0; i < 16; i++ {
+		targets = append(targets, addrs[1+r.Intn(nodes-1)])
+	}
+	return
+}
+
+//benchmarkPerNeighbour is how routes were searched before distances were cached: one dijkstra from every neighbour
+func benchmarkPerNeighbour(b *testing.B, nodes int) {
+	cg, targets := newBenchGraph(nodes, 4)
+	charger := &proportionalCharger{}
+	amount := big.NewInt(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		target := cg.address2index[targets[i%len(targets)]]
+		for _, n := range cg.getNeighbours() {
+			for _, v := range cg.g.Verticies {
+				v.SetWeight(charger.GetNodeChargeFee(cg.index2address[v.ID], cg.TokenAddress, amount).Int64())
+			}
+			cg.g.Shortest(cg.address2index[n], target)
+		}
+	}
+}
+
+func benchmarkOrderedNeighbours(b *testing.B, nodes int, cached bool) {
+	cg, targets := newBenchGraph(nodes, 4)
+	charger := &proportionalCharger{}
+	amount := big.NewInt(10000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !cached {
+			cg.clearDistances()
+		}
+		cg.orderedNeighbours(cg.OurAddress, targets[i%len(targets)], amount, charger)
+	}
+}
+
+func BenchmarkRoutesPerNeighbour10k(b *testing.B) { benchmarkPerNeighbour(b, 10000) }
+func BenchmarkRoutesUncached10k(b *testing.B)     { benchmarkOrderedNeighbours(b, 10000, false) }
+func BenchmarkRoutesCached10k(b *testing.B)       { benchmarkOrderedNeighbours(b, 10000, true) }
+func BenchmarkRoutesPerNeighbour20k(b *testing.B) { benchmarkPerNeighbour(b, 20000) }
+func BenchmarkRoutesUncached20k(b *testing.B)     { benchmarkOrderedNeighbours(b, 20000, false) }
+func BenchmarkRoutesCached20k(b *testing.B)       { benchmarkOrderedNeighbours(b, 20000, true) }
+
+//BenchmarkShortestPathParallel10k searches paths from many goroutines while channels come and go
+func BenchmarkShortestPathParallel10k(b *testing.B) {
+	cg, targets := newBenchGraph(10000, 4)
+	charger := &proportionalCharger{}
+	amount := big.NewInt(10000)
+	nodes := cg.AllNodes()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for i := 0; pb.Next(); i++ {
+			if i%100 == 0 {
+				a, c := nodes[r.Intn(len(nodes))], nodes[r.Intn(len(nodes))]
+				cg.AddPath(a, c)
+				cg.RemovePath(a, c)
+				continue
+			}
+			cg.ShortestPath(nodes[r.Intn(len(nodes))], targets[r.Intn(len(targets))], amount, charger)
+		}
+	})
+}
+
+//mapCharger must be a pointer to be a key of the cached distances
+type mapCharger struct {
+	fees map[common.Address]int64
+}
+
+func (m *mapCharger) GetNodeChargeFee(nodeAddress, tokenAddress common.Address, amount *big.Int) *big.Int {
+	return big.NewInt(m.fees[nodeAddress])
+}
+
+type allOnline struct{}
+
+func (allOnline) GetNetworkStatus(addr common.Address) (deviceType string, isOnline bool) {
+	return "", true
+}
+
+func newTestChannel(ourAddress, partnerAddress, tokenAddress common.Address, ourBalance, partnerBalance int64) *channel.Channel {
+	ourState := channel.NewChannelEndState(ourAddress, big.NewInt(ourBalance), nil, mtree.EmptyTree)
+	partnerState := channel.NewChannelEndState(partnerAddress, big.NewInt(partnerBalance), nil, mtree.EmptyTree)
+	c, err := channel.NewChannel(ourState, partnerState, &channel.ExternalState{}, tokenAddress,
+		&contracts.ChannelUniqueID{ChannelIdentifier: utils.NewRandomHash()}, 5, 30)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestGetRebalanceRoute(t *testing.T) {
+	a, b, c, d, e, f := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	token := utils.NewRandomAddress()
+	//a has channels with b,c,d and f, b-e-c is the long way back to c, b-d is a short way back to a but not through c
+	cg := NewChannelGraph(a, token, []common.Address{b, e, e, c, b, d})
+	for _, ch := range []*channel.Channel{
+		newTestChannel(a, b, token, 100, 100),
+		newTestChannel(a, c, token, 100, 10),
+		newTestChannel(a, d, token, 100, 100),
+		newTestChannel(a, f, token, 100, 100),
+	} {
+		if err := cg.AddChannel(ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	//b charges nothing, the fee of the circle is still summed
+	charger := &mapCharger{map[common.Address]int64{e: 3, c: 2, d: 1}}
+	r, err := cg.GetRebalanceRoute(allOnline{}, b, c, big.NewInt(10), charger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.HopNode() != b || r.TotalFee.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("expect route through %s with fee 5, got %s with fee %s", utils.APex(b), utils.APex(r.HopNode()), r.TotalFee)
+	}
+	//d goes back through b
+	r, err = cg.GetRebalanceRoute(allOnline{}, d, c, big.NewInt(10), charger)
+	if err != nil || r.TotalFee.Cmp(big.NewInt(6)) != 0 {
+		t.Errorf("expect route through %s with fee 6, got %v, err %v", utils.APex(d), r, err)
+	}
+	//f only comes back to a directly, which is not the channel with c
+	if _, err = cg.GetRebalanceRoute(allOnline{}, f, c, big.NewInt(10), charger); err == nil {
+		t.Error("expect no path from f back through c")
+	}
+	if _, err = cg.GetRebalanceRoute(allOnline{}, b, b, big.NewInt(10), charger); err == nil {
+		t.Error("expect error for the same channel")
+	}
+	if _, err = cg.GetRebalanceRoute(allOnline{}, b, c, big.NewInt(101), charger); err == nil {
+		t.Error("expect error for not enough balance with b")
+	}
+	if _, err = cg.GetRebalanceRoute(allOnline{}, b, c, big.NewInt(11), charger); err == nil {
+		t.Error("expect error for not enough balance of c")
+	}
+	//the arcs of a are not changed by the search, every channel of a is still a route
+	routes := cg.GetBestRoutes(allOnline{}, a, e, big.NewInt(10), nil, charger)
+	if len(routes) != 4 {
+		t.Errorf("expect 4 routes from a to e, got %d", len(routes))
+	}
+}
+
+type excludeHistory struct {
+	excluded map[common.Address]bool
+}
+
+func (h *excludeHistory) Penalty(tokenAddress, from, to common.Address) (penalty float64, exclude bool) {
+	if h.excluded[to] {
+		return 1, true
+	}
+	return 0, false
+}
+
+func TestGetBestRoutesExcluded(t *testing.T) {
+	a, b, c, d := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	token := utils.NewRandomAddress()
+	cg := NewChannelGraph(a, token, []common.Address{b, d, c, d})
+	chb := newTestChannel(a, b, token, 100, 100)
+	chc := newTestChannel(a, c, token, 5, 100)
+	for _, ch := range []*channel.Channel{chb, chc} {
+		if err := cg.AddChannel(ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cg.SetRouteHistory(&excludeHistory{map[common.Address]bool{b: true}})
+	charger := &mapCharger{}
+	routes := cg.GetBestRoutes(allOnline{}, a, d, big.NewInt(5), nil, charger)
+	if len(routes) != 1 || routes[0].HopNode() != c {
+		t.Errorf("expect only route through c, got %v", routes)
+	}
+	//c cannot carry 10, b is the only route left
+	routes = cg.GetBestRoutes(allOnline{}, a, d, big.NewInt(10), nil, charger)
+	if len(routes) != 1 || routes[0].HopNode() != b {
+		t.Errorf("expect route through b, got %v", routes)
+	}
+	//b comes last when splitting a payment
+	routes, amounts := cg.GetMultiPartRoutes(allOnline{}, a, d, big.NewInt(10), nil, charger)
+	if len(routes) != 2 || routes[0].HopNode() != c || routes[1].HopNode() != b || amounts[1].Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("expect c before b, got %v %v", routes, amounts)
+	}
+}
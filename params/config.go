@@ -66,7 +66,15 @@ type Config struct {
 	IgnoreMediatedNodeRequest bool // true: this node will ignore any mediated transfer who's target is not me.
 	EnableHealthCheck         bool //send ping periodically?
 	XMPPServer                string
-	IsMeshNetwork             bool //is mesh now?
+	IsMeshNetwork             bool          //is mesh now?
+	EnableAutoSettle          bool          //settle closed channels automatically once settle timeout expires
+	WebhookURL                string        //notify events to this url
+	WebhookSecret             string        //secret to sign payload posted to WebhookURL
+	EnableDebugAPI            bool          //bind /api/1/debug/ routes
+	ReapIdleTimeout           time.Duration //settle channels without transfers for this long, 0 means never
+	ReapCloseTimeout          time.Duration //close idle channels whose partner is unreachable for this long
+	ReapDryRun                bool          //only report idle channels, never settle them
+	RoutingMode               string        //how paths are preferred, see RoutingModeFee
 }
 
 //DefaultConfig default config
@@ -87,6 +95,7 @@ var DefaultConfig = Config{
 	MsgTimeout:        100 * time.Second,
 	EnableHealthCheck: false,
 	XMPPServer:        DefaultXMPPServer,
+	RoutingMode:       RoutingModeFee,
 }
 
 //ConditionQuit is for test
@@ -60,6 +60,36 @@ ChannelSettleTimeoutMax The maximum settle timeout is chosen as something above
 */
 const ChannelSettleTimeoutMax = 2700000
 
+//MaxMemoLength max bytes of memo a transfer can carry
+const MaxMemoLength = 64
+
+//MaxPaymentParts max number of routes a multi-part payment can be split across
+const MaxPaymentParts = 4
+
+//RoutePreviewPaths max number of full paths a route preview shows
+const RoutePreviewPaths = 8
+
+//AutoSettleRetryInterval blocks to wait before retry a failed auto settle
+const AutoSettleRetryInterval = 10
+
+//WebhookTimeout timeout of posting to a webhook target
+const WebhookTimeout = 10 * time.Second
+
+//WebhookRetryInterval interval before the first retry of a failed webhook delivery,it doubles for each retry
+const WebhookRetryInterval = 5 * time.Second
+
+//WebhookMaxRetryBackoff longest interval between two retries of a webhook delivery
+const WebhookMaxRetryBackoff = time.Hour
+
+//DefaultBatchTransferConcurrency number of transfers of a batch running at the same time if not specified
+const DefaultBatchTransferConcurrency = 5
+
+//MaxBatchTransferConcurrency max number of transfers of a batch running at the same time
+const MaxBatchTransferConcurrency = 50
+
+//MaxBatchTransferItems max number of transfers in one batch
+const MaxBatchTransferItems = 1000
+
 //UDPMaxMessageSize message size
 const UDPMaxMessageSize = 1200
 
@@ -130,3 +160,40 @@ var MatrixDiscoveryRoomConfig = [][]string{
 
 //NETWORKNAME Specify the network name of the Ethereum network to run SmartRaiden on
 var NETWORKNAME = "ropsten"
+
+//how routes of a mediated transfer are preferred
+const (
+	RoutingModeFee      = "fee"      //the least fee first, hops if no fee is charged
+	RoutingModeCapacity = "capacity" //the highest bottleneck capacity first
+	RoutingModeCombined = "combined" //the least fee first, skipping channels known to be too small for the amount
+)
+
+//MissionControlHalfLife a failure or success of a hop counts half after this time
+const MissionControlHalfLife = time.Hour
+
+//MissionControlMaxFailures a hop is not used when its recent failures reach this and it hasn't succeeded since the last failure
+const MissionControlMaxFailures = 3
+
+//FeeRateDenominator proportional rate of mediation fee is in parts per million
+const FeeRateDenominator = 1000000
+
+//DefaultBalancePolicyInterval blocks to wait between two deposits or withdraws of a channel made by its balance policy
+const DefaultBalancePolicyInterval = 100
+
+//ReapCheckInterval blocks between two checks of idle channels
+const ReapCheckInterval = 20
+
+//DefaultReapCloseTimeout how long an idle channel waits for its partner to come back before it's closed
+const DefaultReapCloseTimeout = 72 * time.Hour
+
+//ScheduleCheckInterval how often payment schedules are checked for due payments
+const ScheduleCheckInterval = 10 * time.Second
+
+//ScheduleRetryInterval time to wait before retry a failed scheduled payment
+const ScheduleRetryInterval = time.Minute
+
+//ScheduleMaxAttempts a scheduled payment is given up after failed so many times
+const ScheduleMaxAttempts = 5
+
+//MinScheduleInterval shortest interval in seconds between two scheduled payments
+const MinScheduleInterval = 60
@@ -8,6 +8,7 @@ import (
 
 	"time"
 
+	"sync"
 	"sync/atomic"
 
 	"math/big"
@@ -34,6 +35,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/fee"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/initiator"
@@ -109,6 +111,7 @@ type RaidenService struct {
 	UserReqChan                 chan *apiReq
 	ProtocolMessageSendComplete chan *protocolMessage
 	FeePolicy                   fee.Charger //Mediation fee
+	MissionControl              *MissionControl
 	/*
 		these four maps designed for token swap,but it can be extended for purpose usage.
 		for example:
@@ -128,6 +131,17 @@ type RaidenService struct {
 	StopCreateNewTransfers                bool // 是否停止接收新交易,默认false,目前仅在用户调用prepare-update接口的时候,会被置为true,直到重启		// boolean to check whether stop receiving new transfers, default to false. Currently it sets to true when clients invoke prepare-update, till it reconnects.
 	EthConnectionStatus                   chan netshare.Status
 	ChanHistoryContractEventsDealComplete chan struct{}
+	Token2ConnectionManager               map[common.Address]*ConnectionManager //protected by connectionManagerLock
+	connectionManagerLock                 sync.Mutex
+	autoSettlingChannels                  map[common.Hash]bool //channels whose settle tx is in flight,protected by autoSettleLock
+	autoSettleLock                        sync.Mutex
+	channelClosingAddress                 map[common.Hash]common.Address //closing participant of channels just closed on chain,only accessed in the main loop
+	rebalances                            map[common.Hash]*rebalance     //lock secret hash -> rebalance started by me,removed with its initiator,only accessed in the main loop
+	balancePolicyChannels                 map[common.Hash]bool           //channels whose balance policy action is in flight,protected by balancePolicyLock
+	balancePolicyLock                     sync.Mutex
+	partnerLastSeen                       map[common.Address]int64 //last time a partner is known to be online,protected by partnerLastSeenLock
+	partnerLastSeenLock                   sync.Mutex
+	scheduler                             *schedulerService
 }
 
 //NewRaidenService create raiden service
@@ -160,13 +174,18 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 		RevealSecretListenerMap:               make(map[common.Hash]RevealSecretListener),
 		ReceivedMediatedTrasnferListenerMap:   make(map[*ReceivedMediatedTrasnferListener]bool),
 		SentMediatedTransferListenerMap:       make(map[*SentMediatedTransferListener]bool),
-		FeePolicy:                             &ConstantFeePolicy{},
 		HealthCheckMap:                        make(map[common.Address]bool),
 		quitChan:                              make(chan struct{}),
 		isStarting:                            true,
 		StopCreateNewTransfers:                false,
 		EthConnectionStatus:                   make(chan netshare.Status, 10),
 		ChanHistoryContractEventsDealComplete: make(chan struct{}),
+		Token2ConnectionManager:               make(map[common.Address]*ConnectionManager),
+		autoSettlingChannels:                  make(map[common.Hash]bool),
+		channelClosingAddress:                 make(map[common.Hash]common.Address),
+		rebalances:                            make(map[common.Hash]*rebalance),
+		balancePolicyChannels:                 make(map[common.Hash]bool),
+		partnerLastSeen:                       make(map[common.Address]int64),
 	}
 	rs.BlockNumber.Store(int64(0))
 	rs.MessageHandler = newRaidenMessageHandler(rs)
@@ -178,6 +197,16 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 		return
 	}
 	rs.Protocol.SetReceivedMessageSaver(NewAckHelper(rs.db))
+	rs.FeePolicy, err = NewFeePolicy(rs.db)
+	if err != nil {
+		err = fmt.Errorf("load fee rates error %s", err)
+		return
+	}
+	rs.MissionControl, err = NewMissionControl(rs.db)
+	if err != nil {
+		err = fmt.Errorf("load route histories error %s", err)
+		return
+	}
 	/*
 		only one instance for one data directory
 	*/
@@ -228,6 +257,8 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 	for t, tn := range rs.Token2TokenNetwork {
 		rs.TokenNetwork2Token[tn] = t
 	}
+	rs.loadPartnerActivities()
+	rs.scheduler = newSchedulerService(rs.db, rs.quitChan, rs.payScheduled)
 	rs.BlockChainEvents = blockchain.NewBlockChainEvents(chain.Client, chain.RegistryAddress, rs.SecretRegistryAddress, rs.Token2TokenNetwork)
 	return rs, nil
 }
@@ -235,6 +266,9 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 // Start the node.
 func (rs *RaidenService) Start() (err error) {
 
+	rs.interruptIdempotencyRecords()
+	rs.interruptBatchTransfers()
+	rs.startWebhook()
 	rs.registerRegistry()
 	rs.Protocol.Start()
 	rs.restore()
@@ -282,6 +316,7 @@ func (rs *RaidenService) Start() (err error) {
 	}
 	rs.isStarting = false
 	rs.startNeighboursHealthCheck()
+	rs.scheduler.start()
 	// 只有在混合模式下启动时,才订阅其他节点的在线状态
 	// Only when starting under MixUDPXMPP, we can subscribe online status of other nodes.
 	if rs.Config.NetworkMode == params.MixUDPXMPP || rs.Config.NetworkMode == params.MixUDPMatrix {
@@ -335,6 +370,7 @@ func (rs *RaidenService) loop() {
 		//message from other nodes
 		case m, ok = <-rs.Protocol.ReceivedMessageChan:
 			if ok {
+				rs.markPartnerSeen(m.Msg.GetSender())
 				err = rs.MessageHandler.onMessage(m.Msg, m.EchoHash)
 				if err != nil {
 					log.Error(fmt.Sprintf("MessageHandler.onMessage %v", err))
@@ -480,13 +516,21 @@ func (rs *RaidenService) handleBlockNumber(blocknumber int64) {
 	*/
 	rs.StateMachineEventHandler.dispatchToAllTasks(statechange)
 	for _, cg := range rs.Token2ChannelGraph {
-		for _, c := range cg.ChannelAddress2Channel {
+		for _, c := range cg.Channels() {
 			err := rs.StateMachineEventHandler.ChannelStateTransition(c, statechange)
 			if err != nil {
 				log.Error(fmt.Sprintf("ChannelStateTransition err %s", err))
 			}
 		}
 	}
+	if rs.Config.EnableAutoSettle {
+		rs.autoSettle(blocknumber)
+	}
+	rs.rejectExpiredHoldPayments()
+	rs.applyBalancePolicies(blocknumber)
+	if blocknumber%params.ReapCheckInterval == 0 {
+		rs.checkIdleChannels(blocknumber)
+	}
 	rs.db.SaveLatestBlockNumber(blocknumber)
 	return
 }
@@ -660,6 +704,15 @@ func (rs *RaidenService) registerTokenNetwork(tokenAddress, tokenNetworkAddress
 		return
 	}
 	g := graph.NewChannelGraph(rs.NodeAddress, tokenAddress, edges)
+	g.SetRoutingMode(rs.Config.RoutingMode)
+	g.SetRouteHistory(rs.MissionControl)
+	deposits, err := rs.db.GetAllNonParticipantChannelDeposit(tokenAddress)
+	if err != nil {
+		return
+	}
+	for _, c := range deposits {
+		g.SetCapacity(c.Participant1, c.Participant2, c.Capacity())
+	}
 	rs.TokenNetwork2Token[tokenNetworkAddress] = tokenAddress
 	rs.Token2TokenNetwork[tokenAddress] = tokenNetworkAddress
 	rs.Token2ChannelGraph[tokenAddress] = g
@@ -709,7 +762,7 @@ func (rs *RaidenService) registerChannel(tokenNetworkAddress common.Address, par
 		log.Error(err.Error())
 		return
 	}
-	err = rs.db.NewChannel(channel.NewChannelSerialization(g.ChannelAddress2Channel[ch.ChannelIdentifier.ChannelIdentifier]))
+	err = rs.db.NewChannel(channel.NewChannelSerialization(g.GetChannelAddress2Channel(ch.ChannelIdentifier.ChannelIdentifier)))
 	if err != nil {
 		log.Error(err.Error())
 		return
@@ -738,7 +791,7 @@ Do a direct tranfer with target.
        are required to complete the transfer (from the payer's perspective),
        whereas the mediated transfer requires 6 messages.
 */
-func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address, amount *big.Int) (result *utils.AsyncResult) {
+func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address, amount *big.Int, data *encoding.PaymentData) (result *utils.AsyncResult) {
 	g := rs.getToken2ChannelGraph(tokenAddress)
 	directChannel := g.GetPartenerAddress2Channel(target)
 	result = utils.NewAsyncResult()
@@ -751,6 +804,7 @@ func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address
 		result.Result <- err
 		return
 	}
+	tr.PaymentData = *data
 	err = tr.Sign(rs.PrivateKey, tr)
 	err = directChannel.RegisterTransfer(rs.GetBlockNumber(), tr)
 	if err != nil {
@@ -764,6 +818,7 @@ func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address
 		Target:            target,
 		ChannelIdentifier: directChannel.ChannelIdentifier.ChannelIdentifier,
 		Token:             tokenAddress,
+		PaymentData:       *data,
 	}
 	err = rs.sendAsync(directChannel.PartnerState.Address, tr)
 	if err != nil {
@@ -787,6 +842,7 @@ lauch a new mediated trasfer
 Args:
  hashlock: caller can specify a hashlock or use empty ,when empty, will generate a random secret.
  expiration: caller can specify a valid blocknumber or 0, when 0 ,will calculate based on settle timeout of channel.
+ allowMultiPart: 没有单条路径余额足够时,是否拆分到多条路径上
 
 Calls:
 	//1. mediatedTransfer
@@ -801,6 +857,7 @@ Calls:
  *	Args :
  *		hashlock : caller can specify a hashlock or use empty, when empty, will generate a random secret.
  *		expiration : caller can specify a valid blocknumber or 0, when 0, will calculate based on settle timeout of channel.
+ *		allowMultiPart : split the transfer across several routes when no single route has enough capacity, token swap cannot be split.
  *	Calls :
  *		1. mediatedTransfer
  *			1.1 if it has lockSecretHash and Secret, then it is a transfer with specific secret.
@@ -809,10 +866,18 @@ Calls:
  *			2.1 taker should contain lockSecretHash, but no secret.
  *			2.2 maker should contain lockSecretHash and secret.
  */
-func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, expiration int64, secret common.Hash) (result *utils.AsyncResult, stateManager *transfer.StateManager) {
+func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, expiration int64, secret common.Hash, data *encoding.PaymentData, allowMultiPart bool) (result *utils.AsyncResult, stateManager *transfer.StateManager) {
 	g := rs.getToken2ChannelGraph(tokenAddress)
 	availableRoutes := g.GetBestRoutes(rs.Protocol, rs.NodeAddress, target, amount, graph.EmptyExlude, rs)
 	result = utils.NewAsyncResult()
+	if len(availableRoutes) <= 0 && allowMultiPart && !rs.Config.IsMeshNetwork {
+		/*
+			没有任何一条路径有足够的余额,尝试拆分到多条路径上
+		*/
+		// No single route has enough capacity, try to split this transfer across several routes.
+		stateManager = rs.startMultiPartTransfer(result, g, target, amount, fee, lockSecretHash, secret, data)
+		return
+	}
 	if len(availableRoutes) <= 0 {
 		result.Result <- errors.New("no available route")
 		return
@@ -829,6 +894,14 @@ func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target comm
 			r.TotalFee = fee //use the user's fee to replace algorithm's
 		}
 	}
+	stateManager = rs.startMediatedTransferOnRoutes(result, tokenAddress, target, amount, lockSecretHash, expiration, secret, data, availableRoutes)
+	return
+}
+
+/*
+startMediatedTransferOnRoutes creates the initiator of a transfer, it tries `availableRoutes` one by one.
+*/
+func (rs *RaidenService) startMediatedTransferOnRoutes(result *utils.AsyncResult, tokenAddress, target common.Address, amount *big.Int, lockSecretHash common.Hash, expiration int64, secret common.Hash, data *encoding.PaymentData, availableRoutes []*route.State) (stateManager *transfer.StateManager) {
 	routesState := route.NewRoutesState(availableRoutes)
 	transferState := &mediatedtransfer.LockedTransferState{
 		TargetAmount:   new(big.Int).Set(amount),
@@ -840,6 +913,7 @@ func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target comm
 		LockSecretHash: lockSecretHash,
 		Secret:         secret,
 		Fee:            utils.BigInt0,
+		PaymentData:    *data,
 	}
 	/*
 		发起方每次切换路径不再切换密码,不切换依然可以保证安全
@@ -867,11 +941,65 @@ func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target comm
 	return
 }
 
+/*
+startMultiPartTransfer splits `amount` across several routes, all the parts use the same lock secret hash.
+every part pays the fee of its own route, user specified `fee` is the most all the parts can pay together.
+returns nil if all the routes together don't have enough capacity.
+*/
+func (rs *RaidenService) startMultiPartTransfer(result *utils.AsyncResult, g *graph.ChannelGraph, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, secret common.Hash, data *encoding.PaymentData) (stateManager *transfer.StateManager) {
+	routes, amounts := g.GetMultiPartRoutes(rs.Protocol, rs.NodeAddress, target, amount, graph.EmptyExlude, rs)
+	if len(routes) <= 0 {
+		result.Result <- errors.New("no available route")
+		return
+	}
+	if fee != nil && fee.Cmp(utils.BigInt0) > 0 {
+		totalFee := big.NewInt(0)
+		for _, r := range routes {
+			totalFee.Add(totalFee, r.TotalFee)
+		}
+		if totalFee.Cmp(fee) > 0 {
+			result.Result <- fmt.Errorf("fees of all the parts %s exceed the fee %s", totalFee, fee)
+			return
+		}
+	}
+	transferState := &mediatedtransfer.LockedTransferState{
+		TargetAmount:   new(big.Int).Set(amount),
+		Amount:         new(big.Int).Set(amount),
+		Token:          g.TokenAddress,
+		Initiator:      rs.NodeAddress,
+		Target:         target,
+		LockSecretHash: lockSecretHash,
+		Secret:         secret,
+		Fee:            utils.BigInt0,
+		PaymentData:    *data,
+	}
+	initInitiator := &mediatedtransfer.ActionInitMultiPartInitiatorStateChange{
+		OurAddress:     rs.NodeAddress,
+		Tranfer:        transferState,
+		Routes:         routes,
+		Amounts:        amounts,
+		BlockNumber:    rs.GetBlockNumber(),
+		Db:             rs.db,
+		LockSecretHash: lockSecretHash,
+		Secret:         secret,
+	}
+	log.Info(fmt.Sprintf("split transfer %s to %s into %d parts", amount, utils.APex(target), len(routes)))
+	stateManager = transfer.NewStateManager(initiator.MultiPartStateTransition, nil, initiator.NameMultiPartInitiatorTransition, lockSecretHash, g.TokenAddress)
+	smkey := utils.Sha3(lockSecretHash[:], g.TokenAddress[:])
+	if rs.Transfer2StateManager[smkey] != nil {
+		panic(fmt.Sprintf("manager must be never exist"))
+	}
+	rs.Transfer2StateManager[smkey] = stateManager
+	rs.Transfer2Result[smkey] = result
+	rs.StateMachineEventHandler.dispatch(stateManager, initInitiator)
+	return
+}
+
 /*
 1. user start a mediated transfer
 2. user start a mediated transfer with secret
 */
-func (rs *RaidenService) startMediatedTransfer(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, secret common.Hash) (result *utils.AsyncResult) {
+func (rs *RaidenService) startMediatedTransfer(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, secret common.Hash, data *encoding.PaymentData) (result *utils.AsyncResult) {
 	lockSecretHash := utils.EmptyHash
 	if secret != utils.EmptyHash {
 		lockSecretHash = utils.ShaSecret(secret.Bytes())
@@ -897,10 +1025,71 @@ func (rs *RaidenService) startMediatedTransfer(tokenAddress, target common.Addre
 		secret = utils.NewRandomHash()
 		lockSecretHash = utils.ShaSecret(secret[:])
 	}
-	result, _ = rs.startMediatedTransferInternal(tokenAddress, target, amount, fee, lockSecretHash, 0, secret)
+	result, _ = rs.startMediatedTransferInternal(tokenAddress, target, amount, fee, lockSecretHash, 0, secret, data, true)
+	result.Tag = lockSecretHash
 	return
 }
 
+/*
+startMediatedTransferWithLockSecretHash user start a mediated transfer with `lockSecretHash` given by the target,
+I don't know the secret, so the secret request is ignored, the transfer finishes when the target reveals the secret.
+*/
+func (rs *RaidenService) startMediatedTransferWithLockSecretHash(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, data *encoding.PaymentData) (result *utils.AsyncResult) {
+	smkey := utils.Sha3(lockSecretHash[:], tokenAddress[:])
+	if rs.Transfer2StateManager[smkey] != nil {
+		result = utils.NewAsyncResult()
+		result.Result <- fmt.Errorf("transfer with lock secret hash %s is pending", lockSecretHash.String())
+		return
+	}
+	var secretRequestHook SecretRequestPredictor = func(msg *encoding.SecretRequest) (ignore bool) {
+		return true
+	}
+	rs.SecretRequestPredictorMap[lockSecretHash] = secretRequestHook
+	result, stateManager := rs.startMediatedTransferInternal(tokenAddress, target, amount, fee, lockSecretHash, 0, utils.EmptyHash, data, true)
+	result.Tag = lockSecretHash
+	if stateManager == nil {
+		//failed at once, otherwise it's removed when the transfer finishes
+		delete(rs.SecretRequestPredictorMap, lockSecretHash)
+	}
+	return
+}
+
+/*
+cancelTransfer cancels the transfer with `lockSecretHash` initiated by me.
+it's possible only before the secret is revealed, after that the lock stays until it's removed or expired.
+*/
+func (rs *RaidenService) cancelTransfer(lockSecretHash common.Hash) error {
+	var mgrs []*transfer.StateManager
+	for _, mgr := range rs.Transfer2StateManager {
+		if mgr.Identifier != lockSecretHash {
+			continue
+		}
+		switch st := mgr.CurrentState.(type) {
+		//without the secret, e.g. paying an invoice, the target may reveal it at any time
+		case *mediatedtransfer.InitiatorState:
+			if st.RevealSecret != nil || st.Transfer.Secret == utils.EmptyHash {
+				return rerr.ErrTransferCannotCancel
+			}
+		case *mediatedtransfer.MultiPartInitiatorState:
+			if st.RevealSecret != nil || st.Secret == utils.EmptyHash {
+				return rerr.ErrTransferCannotCancel
+			}
+		default:
+			continue
+		}
+		mgrs = append(mgrs, mgr)
+	}
+	if len(mgrs) == 0 {
+		return rerr.ErrTransferNotFound
+	}
+	for _, mgr := range mgrs {
+		rs.StateMachineEventHandler.dispatch(mgr, &transfer.ActionCancelTransferStateChange{
+			LockSecretHash: lockSecretHash,
+		})
+	}
+	return nil
+}
+
 //receive a MediatedTransfer, i'm a hop node
 func (rs *RaidenService) mediateMediatedTransfer(msg *encoding.MediatedTransfer, ch *channel.Channel) {
 	tokenAddress := ch.TokenAddress
@@ -943,7 +1132,15 @@ func (rs *RaidenService) mediateMediatedTransfer(msg *encoding.MediatedTransfer,
 	} else {
 		ourAddress := rs.NodeAddress
 		exclude := graph.MakeExclude(msg.Sender, msg.Initiator)
+		if msg.Initiator == msg.Target {
+			//a rebalance comes back to its initiator
+			exclude = graph.MakeExclude(msg.Sender)
+		}
 		avaiableRoutes := g.GetBestRoutes(rs.Protocol, rs.NodeAddress, targetAddr, amount, exclude, rs)
+		//fee of a route is estimated for the next hop, what I keep is by my own rates
+		for _, r := range avaiableRoutes {
+			r.Fee = rs.getOwnChargeFee(r.HopNode(), tokenAddress, amount)
+		}
 		routesState := route.NewRoutesState(avaiableRoutes)
 		blockNumber := rs.GetBlockNumber()
 		initMediator := &mediatedtransfer.ActionInitMediatorStateChange{
@@ -982,13 +1179,32 @@ func (rs *RaidenService) targetMediatedTransfer(msg *encoding.MediatedTransfer,
 		// do nothing
 		return
 	}
+	if msg.Initiator == rs.NodeAddress {
+		rs.rebalanceTargetMediatedTransfer(msg, ch)
+		return
+	}
 	if stateManager != nil {
 		if stateManager.Name != target.NameTargetTransition {
 			log.Error(fmt.Sprintf("receive mediator transfer,but i'm not a target,msg=%s,stateManager=%s", msg, utils.StringInterface(stateManager, 3)))
 			return
 		}
-		log.Error(fmt.Sprintf("receive mediator transfer msg=%s,duplicate? attack?,i'm a target,and has received mediator message. statemanager=%s",
-			msg, utils.StringInterface(stateManager, 3)))
+		if !msg.IsMultiPart() {
+			log.Error(fmt.Sprintf("receive mediator transfer msg=%s,duplicate? attack?,i'm a target,and has received mediator message. statemanager=%s",
+				msg, utils.StringInterface(stateManager, 3)))
+			return
+		}
+		//another part of a multi-part payment
+		stateChange := &mediatedtransfer.ReceiveTransferPartStateChange{
+			FromRoute:    graph.Channel2RouteState(ch, msg.Sender, msg.PaymentAmount, rs),
+			FromTransfer: mediatedtransfer.LockedTransferFromMessage(msg, ch.TokenAddress),
+			BlockNumber:  rs.GetBlockNumber(),
+			Message:      msg,
+		}
+		rs.StateMachineEventHandler.dispatch(stateManager, stateChange)
+		if h := rs.getHoldPayment(msg.LockSecretHash); h != nil {
+			rs.holdTransfer(stateManager, h)
+		}
+		rs.saveTargetAck(stateManager, ch)
 		return
 	}
 	g := rs.getToken2ChannelGraph(ch.TokenAddress)
@@ -1003,10 +1219,29 @@ func (rs *RaidenService) targetMediatedTransfer(msg *encoding.MediatedTransfer,
 		Message:     msg,
 		Db:          rs.db,
 	}
+	hold := rs.getHoldPayment(msg.LockSecretHash)
+	initTarget.Hold = hold != nil
 	stateManager = transfer.NewStateManager(target.StateTransiton, nil, target.NameTargetTransition, fromTransfer.LockSecretHash, fromTransfer.Token)
 	//rs.db.AddStateManager(stateManager)
 	rs.Transfer2StateManager[smkey] = stateManager
 	rs.StateMachineEventHandler.dispatch(stateManager, initTarget)
+	if hold != nil {
+		rs.holdTransfer(stateManager, hold)
+	}
+	rs.saveTargetAck(stateManager, ch)
+}
+
+/*
+saveTargetAck saves the channel and ack of a transfer which triggers no event,
+the target doesn't request the secret until it receives the last part of a multi-part payment,
+and never requests the secret of a held payment.
+*/
+func (rs *RaidenService) saveTargetAck(stateManager *transfer.StateManager, ch *channel.Channel) {
+	if stateManager.LastReceivedMessage == nil {
+		return
+	}
+	rs.updateChannelAndSaveAck(ch, stateManager.LastReceivedMessage.Tag())
+	stateManager.LastReceivedMessage = nil
 }
 
 func (rs *RaidenService) startHealthCheckFor(address common.Address) {
@@ -1025,6 +1260,8 @@ func (rs *RaidenService) startHealthCheckFor(address common.Address) {
 			err := rs.Protocol.SendPing(address)
 			if err != nil {
 				log.Info(fmt.Sprintf("health check ping %s err %s", utils.APex(address), err))
+			} else {
+				rs.markPartnerSeen(address)
 			}
 			time.Sleep(time.Second * 10)
 		}
@@ -1033,8 +1270,8 @@ func (rs *RaidenService) startHealthCheckFor(address common.Address) {
 
 func (rs *RaidenService) startNeighboursHealthCheck() {
 	for _, g := range rs.Token2ChannelGraph {
-		for addr := range g.PartenerAddress2Channel {
-			rs.startHealthCheckFor(addr)
+		for _, c := range g.Channels() {
+			rs.startHealthCheckFor(c.PartnerState.Address)
 		}
 	}
 }
@@ -1318,7 +1555,7 @@ func (rs *RaidenService) tokenSwapMaker(tokenswap *TokenSwap) (result *utils.Asy
 	}
 	rs.SentMediatedTransferListenerMap[&sentMtrHook] = true
 	rs.ReceivedMediatedTrasnferListenerMap[&receiveMtrHook] = true
-	result, _ = rs.startMediatedTransferInternal(tokenswap.FromToken, tokenswap.ToNodeAddress, tokenswap.FromAmount, utils.BigInt0, tokenswap.LockSecretHash, 0, tokenswap.Secret)
+	result, _ = rs.startMediatedTransferInternal(tokenswap.FromToken, tokenswap.ToNodeAddress, tokenswap.FromAmount, utils.BigInt0, tokenswap.LockSecretHash, 0, tokenswap.Secret, &encoding.PaymentData{}, false)
 	return
 }
 
@@ -1372,7 +1609,7 @@ func (rs *RaidenService) messageTokenSwapTaker(msg *encoding.MediatedTransfer, t
 		taker and maker may have direct channels on these two tokens.
 	*/
 	takerExpiration := msg.Expiration - params.DefaultRevealTimeout
-	result, stateManager := rs.startMediatedTransferInternal(tokenswap.ToToken, tokenswap.FromNodeAddress, tokenswap.ToAmount, utils.BigInt0, tokenswap.LockSecretHash, takerExpiration, utils.EmptyHash)
+	result, stateManager := rs.startMediatedTransferInternal(tokenswap.ToToken, tokenswap.FromNodeAddress, tokenswap.ToAmount, utils.BigInt0, tokenswap.LockSecretHash, takerExpiration, utils.EmptyHash, &encoding.PaymentData{}, false)
 	if stateManager == nil {
 		log.Error(fmt.Sprintf("taker tokenwap error %s", <-result.Result))
 		return false
@@ -1467,9 +1704,11 @@ func (rs *RaidenService) handleReq(req *apiReq) {
 	case transferReqName: //mediated transfer only
 		r := req.Req.(*transferReq)
 		if r.IsDirectTransfer {
-			result = rs.directTransferAsync(r.TokenAddress, r.Target, r.Amount)
+			result = rs.directTransferAsync(r.TokenAddress, r.Target, r.Amount, &r.Data)
+		} else if r.LockSecretHash != utils.EmptyHash {
+			result = rs.startMediatedTransferWithLockSecretHash(r.TokenAddress, r.Target, r.Amount, r.Fee, r.LockSecretHash, &r.Data)
 		} else {
-			result = rs.startMediatedTransfer(r.TokenAddress, r.Target, r.Amount, r.Fee, r.Secret)
+			result = rs.startMediatedTransfer(r.TokenAddress, r.Target, r.Amount, r.Fee, r.Secret, &r.Data)
 		}
 	case newChannelReqName:
 		r := req.Req.(*newChannelReq)
@@ -1511,6 +1750,40 @@ func (rs *RaidenService) handleReq(req *apiReq) {
 	case cancelPrepareWithdrawReqName:
 		r := req.Req.(*closeSettleChannelReq)
 		result = rs.cancelPrepareForCooperativeSettleChannelOrWithdraw(r.addr)
+	case transferStatusReqName:
+		r := req.Req.(*transferStatusReq)
+		result = utils.NewAsyncResult()
+		result.Tag = rs.getTransferStatus(r.lockSecretHash)
+		result.Result <- nil
+	case routesReqName:
+		r := req.Req.(*routesReq)
+		result = utils.NewAsyncResult()
+		routes, err := rs.getRoutes(r.tokenAddress, r.target, r.amount)
+		result.Tag = routes
+		result.Result <- err
+	case reapCandidatesReqName:
+		result = utils.NewAsyncResult()
+		result.Tag = rs.getReapCandidates()
+		result.Result <- nil
+	case clearPathCachesReqName:
+		result = utils.NewAsyncResult()
+		rs.clearPathCaches()
+		result.Result <- nil
+	case rebalanceReqName:
+		r := req.Req.(*rebalanceReq)
+		result = rs.startRebalance(r.fromChannel, r.toChannel, r.amount, r.maxFee)
+	case cancelTransferReqName:
+		r := req.Req.(*cancelTransferReq)
+		result = utils.NewAsyncResult()
+		result.Result <- rs.cancelTransfer(r.lockSecretHash)
+	case settleHoldPaymentReqName:
+		r := req.Req.(*holdPaymentReq)
+		result = utils.NewAsyncResult()
+		result.Result <- rs.settleHoldPayment(r.secret)
+	case rejectHoldPaymentReqName:
+		r := req.Req.(*holdPaymentReq)
+		result = utils.NewAsyncResult()
+		result.Result <- rs.rejectHoldPayment(r.lockSecretHash, "rejected by user")
 	default:
 		panic("unkown req")
 	}
@@ -22,6 +22,7 @@ import (
 	"crypto/ecdsa"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
@@ -339,9 +340,12 @@ func (r *RaidenAPI) GetTokenTokenNetorks() (tokens []string) {
 	return
 }
 
-//TransferAndWait Do a transfer with `target` with the given `amount` of `token_address`.
-func (r *RaidenAPI) TransferAndWait(token common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, timeout time.Duration, isDirectTransfer bool) (err error) {
-	result, err := r.transferAsync(token, amount, fee, target, secret, isDirectTransfer)
+/*
+TransferAndWait Do a transfer with `target` with the given `amount` of `token_address`.
+identifier and memo are optional and delivered to the target, each hop signs them again, so a mediator can change them.
+*/
+func (r *RaidenAPI) TransferAndWait(token common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, timeout time.Duration, isDirectTransfer bool, identifier uint64, memo string) (err error) {
+	result, err := r.transferAsync(token, amount, fee, target, secret, isDirectTransfer, identifier, memo)
 	if err != nil {
 		return err
 	}
@@ -360,11 +364,11 @@ func (r *RaidenAPI) TransferAndWait(token common.Address, amount *big.Int, fee *
 
 //Transfer transfer and wait
 func (r *RaidenAPI) Transfer(token common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, timeout time.Duration, isDirectTransfer bool) error {
-	return r.TransferAndWait(token, amount, fee, target, secret, timeout, isDirectTransfer)
+	return r.TransferAndWait(token, amount, fee, target, secret, timeout, isDirectTransfer, 0, "")
 }
 
 //transferAsync
-func (r *RaidenAPI) transferAsync(tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, isDirectTransfer bool) (result *utils.AsyncResult, err error) {
+func (r *RaidenAPI) transferAsync(tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, isDirectTransfer bool, identifier uint64, memo string) (result *utils.AsyncResult, err error) {
 	tokens := r.Tokens()
 	found := false
 	for _, t := range tokens {
@@ -393,9 +397,17 @@ func (r *RaidenAPI) transferAsync(tokenAddress common.Address, amount *big.Int,
 		err = rerr.ErrInvalidAmount
 		return
 	}
-	log.Debug(fmt.Sprintf("initiating transfer initiator=%s target=%s token=%s amount=%d secret=%s",
-		r.Raiden.NodeAddress.String(), target.String(), tokenAddress.String(), amount, secret.String()))
-	result = r.Raiden.transferAsyncClient(tokenAddress, amount, fee, target, secret, isDirectTransfer)
+	if len(memo) > params.MaxMemoLength {
+		err = fmt.Errorf("memo too long, max %d bytes", params.MaxMemoLength)
+		return
+	}
+	log.Debug(fmt.Sprintf("initiating transfer initiator=%s target=%s token=%s amount=%d secret=%s identifier=%d",
+		r.Raiden.NodeAddress.String(), target.String(), tokenAddress.String(), amount, secret.String(), identifier))
+	data := &encoding.PaymentData{
+		PaymentIdentifier: identifier,
+		Memo:              memo,
+	}
+	result = r.Raiden.transferAsyncClient(tokenAddress, amount, fee, target, secret, isDirectTransfer, data)
 	return
 }
 
@@ -409,11 +421,16 @@ func (r *RaidenAPI) AllowRevealSecret(lockSecretHash common.Hash, tokenAddress c
 	if manager == nil {
 		return rerr.InvalidState("can not find transfer by lock_secret_hash and token_address")
 	}
-	state, ok := manager.CurrentState.(*mediatedtransfer.InitiatorState)
-	if !ok {
+	var secret common.Hash
+	switch state := manager.CurrentState.(type) {
+	case *mediatedtransfer.InitiatorState:
+		secret = state.Secret
+	case *mediatedtransfer.MultiPartInitiatorState:
+		secret = state.Secret
+	default:
 		return rerr.InvalidState("wrong state")
 	}
-	if lockSecretHash != state.LockSecretHash || lockSecretHash != utils.ShaSecret(state.Secret.Bytes()) {
+	if lockSecretHash != utils.ShaSecret(secret.Bytes()) {
 		return rerr.InvalidState("wrong lock_secret_hash")
 	}
 	delete(r.Raiden.SecretRequestPredictorMap, lockSecretHash)
@@ -421,6 +438,21 @@ func (r *RaidenAPI) AllowRevealSecret(lockSecretHash common.Hash, tokenAddress c
 	return
 }
 
+/*
+CancelTransfer stops the transfer with `lockSecretHash` initiated by this node,
+no other route will be tried and the secret will never be revealed.
+returns rerr.ErrTransferCannotCancel if the secret is already revealed or only the target knows it, like paying an invoice,
+rerr.ErrTransferNotFound if there is no such pending transfer.
+*/
+func (r *RaidenAPI) CancelTransfer(lockSecretHash common.Hash) (err error) {
+	if lockSecretHash == utils.EmptyHash {
+		return errors.New("lock secret hash is empty")
+	}
+	result := r.Raiden.cancelTransferClient(lockSecretHash)
+	err = <-result.Result
+	return
+}
+
 // RegisterSecret :
 func (r *RaidenAPI) RegisterSecret(secret common.Hash, tokenAddress common.Address) (err error) {
 	lockSecretHash := utils.ShaSecret(secret.Bytes())
@@ -733,80 +765,6 @@ func (r *RaidenAPI) GetNetworkEvents(fromBlock, toBlock int64) ([]interface{}, e
 	return nil, nil
 }
 
-//GetChannelEvents events of this channel
-func (r *RaidenAPI) GetChannelEvents(channelAddress common.Hash, fromBlock, toBlock int64) (data []transfer.Event, err error) {
-
-	//var events []transfer.Event
-	//events, err = r.Raiden.BlockChainEvents.GetAllNettingChannelEvents(channelAddress, fromBlock, toBlock)
-	//if err != nil {
-	//	return
-	//}
-	//for _, e := range events {
-	//	m := make(map[string]interface{})
-	//	switch e2 := e.(type) {
-	//	case *blockchain.EventChannelNewBalance:
-	//		m["event_type"] = e2.EventName
-	//		m["participant"] = e2.ParticipantAddress.String()
-	//		m["balance"] = e2.Balance
-	//		m["block_number"] = e2.BlockNumber
-	//		data = append(data, m)
-	//	case *blockchain.EventChannelClosed:
-	//		m["event_type"] = e2.EventName
-	//		m["netting_channel_address"] = e2.ContractAddress.String()
-	//		m["closing_address"] = e2.ClosingAddress.String()
-	//		data = append(data, m)
-	//	case *blockchain.EventChannelSettled:
-	//		m["event_type"] = e2.EventName
-	//		m["netting_channel_address"] = e2.ContractAddress.String()
-	//		m["block_number"] = e2.BlockNumber
-	//		data = append(data, m)
-	//	case *blockchain.EventSecretRevealed:
-	//		m["event_type"] = e2.EventName
-	//		m["netting_channel_address"] = e2.ContractAddress.String()
-	//		m["secret"] = e2.Secret.String()
-	//		data = append(data, m)
-	//		//case *blockchain.EventNonClosingBalanceProofUpdated:
-	//		//	m["event_type"] = e2.EventName
-	//		//	m["token_address"] = t.String()
-	//		//	m["channel_manager_address"] = graph.TokenAddress.String()
-	//	}
-	//
-	//}
-	//
-	//var raidenEvents []*models.InternalEvent
-	//raidenEvents, err = r.Raiden.db.GetEventsInBlockRange(fromBlock, toBlock)
-	//if err != nil {
-	//	return
-	//}
-	////Here choose which raiden internal events we want to expose to the end user
-	//for _, ev := range raidenEvents {
-	//	m := make(map[string]interface{})
-	//	switch e2 := ev.EventObject.(type) {
-	//	case *transfer.EventTransferSentSuccess:
-	//		m["event_type"] = "EventTransferSentSuccess"
-	//		m["identifier"] = e2.LockSecretHash
-	//		m["block_number"] = ev.BlockNumber
-	//		m["amount"] = e2.Amount
-	//		m["target"] = e2.Target
-	//		data = append(data, m)
-	//	case *transfer.EventTransferSentFailed:
-	//		m["event_type"] = "EventTransferSentFailed"
-	//		m["identifier"] = e2.LockSecretHash
-	//		m["block_number"] = ev.BlockNumber
-	//		m["reason"] = e2.Reason
-	//		data = append(data, m)
-	//	case *transfer.EventTransferReceivedSuccess:
-	//		m["event_type"] = "EventTransferReceivedSuccess"
-	//		m["identifier"] = e2.LockSecretHash
-	//		m["block_number"] = ev.BlockNumber
-	//		m["amount"] = e2.Amount
-	//		m["initiator"] = e2.Initiator.String()
-	//		data = append(data, m)
-	//	}
-	//}
-	return
-}
-
 /*
 GetSentTransfers query sent transfers from db
 */
@@ -821,6 +779,20 @@ func (r *RaidenAPI) GetReceivedTransfers(from, to int64) ([]*models.ReceivedTran
 	return r.Raiden.db.GetReceivedTransferInBlockRange(from, to)
 }
 
+/*
+GetSentTransfersByIdentifier query sent transfers with payment identifier `identifier` from db
+*/
+func (r *RaidenAPI) GetSentTransfersByIdentifier(identifier uint64) ([]*models.SentTransfer, error) {
+	return r.Raiden.db.GetSentTransferByIdentifier(identifier)
+}
+
+/*
+GetReceivedTransfersByIdentifier query received transfers with payment identifier `identifier` from db
+*/
+func (r *RaidenAPI) GetReceivedTransfersByIdentifier(identifier uint64) ([]*models.ReceivedTransfer, error) {
+	return r.Raiden.db.GetReceivedTransferByIdentifier(identifier)
+}
+
 //Stop stop for mobile app
 func (r *RaidenAPI) Stop() {
 	log.Info("calling api stop..")
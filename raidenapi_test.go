@@ -78,7 +78,7 @@ func testRaidenAPIGetNetworkEvents(t *testing.T, api *RaidenAPI) {
 
 func testRaidenAPIGetChannelEvents(t *testing.T, api *RaidenAPI) {
 	addr := getAChannel(api)
-	events, err := api.GetChannelEvents(addr, -1, -1)
+	events, err := api.GetChannelEvents(addr, -1, -1, true)
 	if err != nil {
 		t.Error(err)
 		return
@@ -87,7 +87,7 @@ func testRaidenAPIGetChannelEvents(t *testing.T, api *RaidenAPI) {
 	wg.Add(repeatCount)
 	for i := 0; i < repeatCount; i++ {
 		go func() {
-			ev2, err := api.GetChannelEvents(addr, -1, -1)
+			ev2, err := api.GetChannelEvents(addr, -1, -1, true)
 			if err != nil {
 				t.Error(err)
 			}
@@ -0,0 +1,260 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//what the reaper does with an idle channel
+const (
+	reapActionNone              = ""
+	reapActionCooperativeSettle = "cooperative settle"
+	reapActionWait              = "wait for partner"
+	reapActionClose             = "close"
+)
+
+//ReapCandidate is a channel without transfers for longer than the reaper's idle timeout
+type ReapCandidate struct {
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	TokenAddress      common.Address `json:"token_address"`
+	PartnerAddress    common.Address `json:"partner_address"`
+	PartnerOnline     bool           `json:"partner_online"`
+	LastSeen          int64          `json:"last_seen"` //last time partner is known to be online,0 if never
+	LastTransferAt    int64          `json:"last_transfer_at"`
+	ReapStatus        string         `json:"reap_status"`
+	Action            string         `json:"action"` //what the reaper does next
+}
+
+//GetReapCandidates returns all the idle channels and what the reaper does with them
+func (r *RaidenAPI) GetReapCandidates() (candidates []*ReapCandidate, err error) {
+	if r.Raiden.Config.ReapIdleTimeout <= 0 {
+		err = errors.New("idle channel reaper is not enabled")
+		return
+	}
+	result := r.Raiden.reapCandidatesClient()
+	err = <-result.Result
+	if err != nil {
+		return
+	}
+	candidates, _ = result.Tag.([]*ReapCandidate)
+	return
+}
+
+//loadPartnerActivities restores last seen time of partners from db
+func (rs *RaidenService) loadPartnerActivities() {
+	as, err := rs.db.GetAllPartnerActivities()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetAllPartnerActivities err %s", err))
+		return
+	}
+	rs.partnerLastSeenLock.Lock()
+	defer rs.partnerLastSeenLock.Unlock()
+	for _, a := range as {
+		rs.partnerLastSeen[a.Address] = a.LastSeen
+	}
+}
+
+//markPartnerSeen records that `addr` is online now, it may be called from any goroutine.
+func (rs *RaidenService) markPartnerSeen(addr common.Address) {
+	rs.partnerLastSeenLock.Lock()
+	rs.partnerLastSeen[addr] = time.Now().Unix()
+	rs.partnerLastSeenLock.Unlock()
+}
+
+func (rs *RaidenService) getPartnerLastSeen(addr common.Address) int64 {
+	rs.partnerLastSeenLock.Lock()
+	defer rs.partnerLastSeenLock.Unlock()
+	return rs.partnerLastSeen[addr]
+}
+
+/*
+checkIdleChannels updates activities of partners and channels,
+and settles channels idle for longer than `ReapIdleTimeout` if the reaper is enabled.
+must be called in the main loop.
+*/
+func (rs *RaidenService) checkIdleChannels(blockNumber int64) {
+	now := time.Now().Unix()
+	for _, g := range rs.Token2ChannelGraph {
+		for _, c := range g.Channels() {
+			if _, isOnline := rs.Protocol.GetNetworkStatus(c.PartnerState.Address); isOnline {
+				rs.markPartnerSeen(c.PartnerState.Address)
+			}
+		}
+	}
+	rs.savePartnerActivities()
+	for _, g := range rs.Token2ChannelGraph {
+		for _, c := range g.Channels() {
+			a := rs.updateChannelActivity(c, now)
+			if a == nil || rs.Config.ReapIdleTimeout <= 0 {
+				continue
+			}
+			rs.reapChannel(c, a, now, blockNumber)
+		}
+	}
+}
+
+func (rs *RaidenService) savePartnerActivities() {
+	rs.partnerLastSeenLock.Lock()
+	defer rs.partnerLastSeenLock.Unlock()
+	for addr, lastSeen := range rs.partnerLastSeen {
+		err := rs.db.SavePartnerActivity(&models.PartnerActivity{
+			Address:  addr,
+			LastSeen: lastSeen,
+		})
+		if err != nil {
+			log.Error(fmt.Sprintf("SavePartnerActivity %s err %s", utils.APex(addr), err))
+		}
+	}
+}
+
+/*
+updateChannelActivity records the time of the last transfer of channel `c`,
+a transfer in either direction increases the nonce of a balance proof.
+*/
+func (rs *RaidenService) updateChannelActivity(c *channel.Channel, now int64) *models.ChannelActivity {
+	channelIdentifier := c.ChannelIdentifier.ChannelIdentifier
+	nonce := c.OurState.BalanceProofState.Nonce + c.PartnerState.BalanceProofState.Nonce
+	a, err := rs.db.GetChannelActivity(channelIdentifier)
+	if err != nil || a.OpenBlockNumber != c.ChannelIdentifier.OpenBlockNumber {
+		a = &models.ChannelActivity{
+			ChannelIdentifier: channelIdentifier,
+			OpenBlockNumber:   c.ChannelIdentifier.OpenBlockNumber,
+			Nonce:             nonce,
+			LastTransferAt:    now,
+		}
+	} else if a.Nonce != nonce {
+		a.Nonce = nonce
+		a.LastTransferAt = now
+		if c.State == channeltype.StateOpened {
+			a.ReapStatus = models.ReapStatusNone
+			a.ReapStartedAt = 0
+			a.Error = ""
+		}
+	} else {
+		return a
+	}
+	err = rs.db.SaveChannelActivity(a)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveChannelActivity %s err %s", utils.HPex(channelIdentifier), err))
+		return nil
+	}
+	return a
+}
+
+/*
+reapAction decides what to do with channel `c`,
+an idle channel is cooperative settled if its partner is online,
+otherwise it's closed after the partner is unreachable for `ReapCloseTimeout`.
+*/
+func (rs *RaidenService) reapAction(c *channel.Channel, a *models.ChannelActivity, now int64) (action string, isOnline bool) {
+	_, isOnline = rs.Protocol.GetNetworkStatus(c.PartnerState.Address)
+	if c.State != channeltype.StateOpened && c.State != channeltype.StateCooprativeSettle {
+		return reapActionNone, isOnline
+	}
+	if now-a.LastTransferAt <= int64(rs.Config.ReapIdleTimeout/time.Second) {
+		return reapActionNone, isOnline
+	}
+	if isOnline && c.State == channeltype.StateOpened {
+		return reapActionCooperativeSettle, isOnline
+	}
+	started := a.ReapStartedAt
+	if started == 0 {
+		started = now
+	}
+	lastSeen := rs.getPartnerLastSeen(c.PartnerState.Address)
+	if lastSeen < started {
+		lastSeen = started
+	}
+	closeTimeout := int64(rs.Config.ReapCloseTimeout / time.Second)
+	//partner is unreachable, or doesn't answer the cooperative settle request
+	if now-lastSeen > closeTimeout || (c.State == channeltype.StateCooprativeSettle && now-started > closeTimeout) {
+		return reapActionClose, isOnline
+	}
+	return reapActionWait, isOnline
+}
+
+func (rs *RaidenService) reapChannel(c *channel.Channel, a *models.ChannelActivity, now int64, blockNumber int64) {
+	channelIdentifier := c.ChannelIdentifier.ChannelIdentifier
+	if c.State == channeltype.StateClosed && a.ReapStatus == models.ReapStatusClosing {
+		if !rs.Config.ReapDryRun && blockNumber > c.GetSettleExpiration(blockNumber) {
+			rs.autoSettleChannel(c, blockNumber)
+		}
+		return
+	}
+	action, _ := rs.reapAction(c, a, now)
+	if action == reapActionNone {
+		return
+	}
+	if rs.Config.ReapDryRun {
+		log.Info(fmt.Sprintf("idle channel %s with %s, reaper would %s", utils.HPex(channelIdentifier), utils.APex(c.PartnerState.Address), action))
+		return
+	}
+	if a.ReapStartedAt == 0 {
+		a.ReapStartedAt = now
+	}
+	switch action {
+	case reapActionCooperativeSettle:
+		log.Info(fmt.Sprintf("reaper cooperative settle idle channel %s", utils.HPex(channelIdentifier)))
+		a.ReapStatus = models.ReapStatusCooperativeSettling
+		a.Error = ""
+		err := <-rs.cooperativeSettleChannel(channelIdentifier).Result
+		if err != nil {
+			a.Error = err.Error()
+		}
+	case reapActionClose:
+		log.Info(fmt.Sprintf("reaper close idle channel %s, partner %s is unreachable", utils.HPex(channelIdentifier), utils.APex(c.PartnerState.Address)))
+		a.ReapStatus = models.ReapStatusClosing
+		a.Error = ""
+		result := rs.closeOrSettleChannel(channelIdentifier, closeChannelReqName)
+		go func() {
+			err := <-result.Result
+			if err != nil {
+				log.Error(fmt.Sprintf("reaper close channel %s err %s", utils.HPex(channelIdentifier), err))
+			}
+		}()
+	}
+	err := rs.db.SaveChannelActivity(a)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveChannelActivity %s err %s", utils.HPex(channelIdentifier), err))
+	}
+}
+
+/*
+getReapCandidates returns all the idle channels.
+must be called in the main loop.
+*/
+func (rs *RaidenService) getReapCandidates() (candidates []*ReapCandidate) {
+	now := time.Now().Unix()
+	for _, g := range rs.Token2ChannelGraph {
+		for _, c := range g.Channels() {
+			a, err := rs.db.GetChannelActivity(c.ChannelIdentifier.ChannelIdentifier)
+			if err != nil || a.OpenBlockNumber != c.ChannelIdentifier.OpenBlockNumber {
+				//not checked yet
+				continue
+			}
+			action, isOnline := rs.reapAction(c, a, now)
+			if action == reapActionNone && a.ReapStatus == models.ReapStatusNone {
+				continue
+			}
+			candidates = append(candidates, &ReapCandidate{
+				ChannelIdentifier: c.ChannelIdentifier.ChannelIdentifier,
+				TokenAddress:      c.TokenAddress,
+				PartnerAddress:    c.PartnerState.Address,
+				PartnerOnline:     isOnline,
+				LastSeen:          rs.getPartnerLastSeen(c.PartnerState.Address),
+				LastTransferAt:    a.LastTransferAt,
+				ReapStatus:        a.ReapStatus,
+				Action:            action,
+			})
+		}
+	}
+	return
+}
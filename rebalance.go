@@ -0,0 +1,190 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/target"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RebalanceResult is the result of a finished rebalance
+type RebalanceResult struct {
+	LockSecretHash common.Hash `json:"lock_secret_hash"`
+	Amount         *big.Int    `json:"amount"`
+	Fee            *big.Int    `json:"fee"` //fee paid to the mediators of the circle
+}
+
+// rebalance is what a rebalance payment started by me must look like when it comes back
+type rebalance struct {
+	partner common.Address //partner of the channel it must come back through
+	amount  *big.Int       //amount it must bring back
+}
+
+/*
+Rebalance moves `amount` of my balance from channel `fromChannel` to channel `toChannel` by paying myself,
+the payment goes out through `fromChannel`, around the network and comes back through `toChannel`.
+fee of the circle must not exceed `maxFee`.
+*/
+func (r *RaidenAPI) Rebalance(fromChannel, toChannel common.Hash, amount, maxFee *big.Int, timeout time.Duration) (res *RebalanceResult, err error) {
+	if r.Raiden.StopCreateNewTransfers {
+		err = rerr.ErrStopCreateNewTransfer
+		return
+	}
+	if amount == nil || amount.Cmp(utils.BigInt0) <= 0 {
+		err = rerr.ErrInvalidAmount
+		return
+	}
+	if maxFee == nil {
+		maxFee = utils.BigInt0
+	}
+	if maxFee.Cmp(utils.BigInt0) < 0 {
+		err = errors.New("invalid max fee")
+		return
+	}
+	if fromChannel == toChannel {
+		err = errors.New("cannot rebalance a channel with itself")
+		return
+	}
+	result := r.Raiden.rebalanceClient(fromChannel, toChannel, amount, maxFee)
+	if timeout > 0 {
+		select {
+		case <-time.After(timeout):
+			err = rerr.ErrTransferTimeout
+			return
+		case err = <-result.Result:
+		}
+	} else {
+		err = <-result.Result
+	}
+	if err != nil {
+		return
+	}
+	res, _ = result.Tag.(*RebalanceResult)
+	return
+}
+
+/*
+startRebalance starts a payment from me to me on the route out through `fromChannel` and back through `toChannel`.
+must be called in the main loop.
+*/
+func (rs *RaidenService) startRebalance(fromChannel, toChannel common.Hash, amount, maxFee *big.Int) (result *utils.AsyncResult) {
+	out := rs.getChannelWithAddr(fromChannel)
+	if out == nil {
+		return utils.NewAsyncResultWithError(fmt.Errorf("channel %s not found", utils.HPex(fromChannel)))
+	}
+	in := rs.getChannelWithAddr(toChannel)
+	if in == nil {
+		return utils.NewAsyncResultWithError(fmt.Errorf("channel %s not found", utils.HPex(toChannel)))
+	}
+	if out.TokenAddress != in.TokenAddress {
+		return utils.NewAsyncResultWithError(errors.New("channels must be of the same token"))
+	}
+	g := rs.getToken2ChannelGraph(out.TokenAddress)
+	r, err := g.GetRebalanceRoute(rs.Protocol, out.PartnerState.Address, in.PartnerState.Address, amount, rs)
+	if err != nil {
+		return utils.NewAsyncResultWithError(err)
+	}
+	if r.TotalFee.Cmp(maxFee) > 0 {
+		return utils.NewAsyncResultWithError(fmt.Errorf("fee %s exceeds max fee %s", r.TotalFee, maxFee))
+	}
+	secret := utils.NewRandomHash()
+	lockSecretHash := utils.ShaSecret(secret[:])
+	result = utils.NewAsyncResult()
+	result.Tag = &RebalanceResult{
+		LockSecretHash: lockSecretHash,
+		Amount:         new(big.Int).Set(amount),
+		Fee:            new(big.Int).Set(r.TotalFee),
+	}
+	rs.rebalances[lockSecretHash] = &rebalance{
+		partner: in.PartnerState.Address,
+		amount:  new(big.Int).Set(amount),
+	}
+	log.Info(fmt.Sprintf("rebalance %s from %s to %s, lockSecretHash=%s fee=%s", amount,
+		utils.APex(out.PartnerState.Address), utils.APex(in.PartnerState.Address), utils.HPex(lockSecretHash), r.TotalFee))
+	rs.startMediatedTransferOnRoutes(result, out.TokenAddress, rs.NodeAddress, amount, lockSecretHash, 0, secret, &encoding.PaymentData{}, []*route.State{r})
+	return
+}
+
+/*
+rebalanceRejectReason tells why a mediated transfer from me to me must not be settled, empty if it can be.
+only a rebalance I started coming back through the expected channel with the whole amount can be settled,
+anything else would reveal my secret to somebody who never paid me.
+*/
+func (rs *RaidenService) rebalanceRejectReason(msg *encoding.MediatedTransfer) string {
+	rb := rs.rebalances[msg.LockSecretHash]
+	if rb == nil {
+		return "not a rebalance started by me"
+	}
+	if rb.partner != msg.Sender {
+		return fmt.Sprintf("rebalance must come back through channel with %s", utils.APex(rb.partner))
+	}
+	//the amount of the lock is PaymentAmount too
+	if msg.PaymentAmount == nil || msg.PaymentAmount.Cmp(rb.amount) != 0 {
+		return fmt.Sprintf("rebalance must bring back %s, but got %s", rb.amount, msg.PaymentAmount)
+	}
+	return ""
+}
+
+/*
+rebalanceTargetMediatedTransfer receives a rebalance payment started by myself.
+I know the secret, so the transfer is settled at once if it's the rebalance I expect,
+otherwise it's rejected without revealing anything and the initiator can try another route.
+*/
+func (rs *RaidenService) rebalanceTargetMediatedTransfer(msg *encoding.MediatedTransfer, ch *channel.Channel) {
+	initiatorManager := rs.Transfer2StateManager[utils.Sha3(msg.LockSecretHash[:], ch.TokenAddress[:])]
+	if initiatorManager == nil {
+		log.Error(fmt.Sprintf("receive a mediated transfer from myself, but no such transfer is started, msg=%s", msg))
+		return
+	}
+	initiatorState, ok := initiatorManager.CurrentState.(*mediatedtransfer.InitiatorState)
+	if !ok {
+		log.Error(fmt.Sprintf("receive a mediated transfer from myself, but it's not started by me, msg=%s", msg))
+		return
+	}
+	//the initiator's key is used, the target needs its own key, which is given to the state machine to remove it
+	smkey := utils.Sha3(msg.LockSecretHash[:], ch.TokenAddress[:], rs.NodeAddress[:])
+	if rs.Transfer2StateManager[smkey] != nil {
+		log.Error(fmt.Sprintf("receive rebalance mediated transfer msg=%s,duplicate? attack?", msg))
+		return
+	}
+	g := rs.getToken2ChannelGraph(ch.TokenAddress)
+	fromChannel := g.GetPartenerAddress2Channel(msg.Sender)
+	fromTransfer := mediatedtransfer.LockedTransferFromMessage(msg, ch.TokenAddress)
+	initTarget := &mediatedtransfer.ActionInitTargetStateChange{
+		OurAddress:  rs.NodeAddress,
+		FromRoute:   graph.Channel2RouteState(fromChannel, msg.Sender, msg.PaymentAmount, rs),
+		FromTranfer: fromTransfer,
+		BlockNumber: rs.GetBlockNumber(),
+		Message:     msg,
+		Db:          rs.db,
+		Hold:        true,
+		Key:         smkey,
+	}
+	stateManager := transfer.NewStateManager(target.StateTransiton, nil, target.NameTargetTransition, fromTransfer.LockSecretHash, fromTransfer.Token)
+	rs.Transfer2StateManager[smkey] = stateManager
+	rs.StateMachineEventHandler.dispatch(stateManager, initTarget)
+	if reason := rs.rebalanceRejectReason(msg); reason != "" {
+		log.Warn(fmt.Sprintf("reject mediated transfer from myself, %s, msg=%s", reason, msg))
+		rs.StateMachineEventHandler.dispatch(stateManager, &mediatedtransfer.ActionRejectHoldStateChange{
+			LockSecretHash: msg.LockSecretHash,
+			Reason:         reason,
+		})
+	} else {
+		rs.StateMachineEventHandler.dispatch(stateManager, &mediatedtransfer.ActionSettleHoldStateChange{
+			Secret: initiatorState.Transfer.Secret,
+		})
+	}
+	rs.saveTargetAck(stateManager, ch)
+}
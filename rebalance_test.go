@@ -0,0 +1,78 @@
+package smartraiden
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRebalance(t *testing.T) {
+	reinit()
+	ra, rb, rc, rd := makeTestRaidenAPIs()
+	defer ra.Stop()
+	defer rb.Stop()
+	defer rc.Stop()
+	defer rd.Stop()
+	var contractBalance = big.NewInt(100)
+	var amount = big.NewInt(10)
+	tokenAddr, _ := newEnv(t, ra, rb, rc, rd)
+	//close the circle a-b-c-a, only c deposits in channel c-a
+	_, err := rc.Open(tokenAddr, ra.Raiden.NodeAddress, rc.Raiden.Config.SettleTimeout, rc.Raiden.Config.RevealTimeout, contractBalance)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(time.Second * 3) //let ra,rb,rc update channel info
+	ab := ra.Raiden.getChannel(tokenAddr, rb.Raiden.NodeAddress)
+	ac := ra.Raiden.getChannel(tokenAddr, rc.Raiden.NodeAddress)
+	res, err := ra.Rebalance(ab.ChannelIdentifier.ChannelIdentifier, ac.ChannelIdentifier.ChannelIdentifier, amount, utils.BigInt0, time.Minute)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert(t, res.Amount, amount)
+	assert(t, res.Fee, utils.BigInt0)
+	time.Sleep(time.Second * 3) //let the unlocks arrive
+	assert(t, ra.Raiden.getChannel(tokenAddr, rb.Raiden.NodeAddress).Balance(), x.Sub(contractBalance, amount))
+	assert(t, ra.Raiden.getChannel(tokenAddr, rc.Raiden.NodeAddress).Balance(), amount)
+	assert(t, rc.Raiden.getChannel(tokenAddr, ra.Raiden.NodeAddress).Balance(), x.Sub(contractBalance, amount))
+	//both the initiator and the target of the rebalance are finished
+	smkey := utils.Sha3(res.LockSecretHash[:], tokenAddr[:])
+	assert(t, ra.Raiden.Transfer2StateManager[smkey] == nil, true)
+	assert(t, ra.Raiden.Transfer2StateManager[utils.Sha3(res.LockSecretHash[:], tokenAddr[:], ra.Raiden.NodeAddress[:])] == nil, true)
+	assert(t, len(ra.Raiden.rebalances), 0)
+
+	//a has only `amount` in channel a-c
+	_, err = ra.Rebalance(ac.ChannelIdentifier.ChannelIdentifier, ab.ChannelIdentifier.ChannelIdentifier, x.Add(contractBalance, amount), utils.BigInt0, time.Minute)
+	if err == nil {
+		t.Error("rebalance should fail for not enough balance")
+	}
+}
+
+func TestRebalanceRejectReason(t *testing.T) {
+	partner := utils.NewRandomAddress()
+	lockSecretHash := utils.NewRandomHash()
+	rs := &RaidenService{rebalances: make(map[common.Hash]*rebalance)}
+	msg := &encoding.MediatedTransfer{LockSecretHash: lockSecretHash, PaymentAmount: big.NewInt(10)}
+	msg.Sender = partner
+	if rs.rebalanceRejectReason(msg) == "" {
+		t.Error("transfer to myself which is not a rebalance must be rejected")
+	}
+	rs.rebalances[lockSecretHash] = &rebalance{partner: partner, amount: big.NewInt(10)}
+	if reason := rs.rebalanceRejectReason(msg); reason != "" {
+		t.Errorf("rebalance should be settled, but %s", reason)
+	}
+	msg.Sender = utils.NewRandomAddress()
+	if rs.rebalanceRejectReason(msg) == "" {
+		t.Error("rebalance from another channel must be rejected")
+	}
+	msg.Sender = partner
+	msg.PaymentAmount = big.NewInt(1)
+	if rs.rebalanceRejectReason(msg) == "" {
+		t.Error("rebalance bringing back less must be rejected")
+	}
+}
@@ -3,6 +3,7 @@ package smartraiden
 import (
 	"math/big"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -43,6 +44,14 @@ const cancelPrepareWithdrawReqName = "cancel mark withdraw"
 const depositChannelReqName = "deposit"
 const tokenSwapMakerReqName = "tokenswapmaker"
 const tokenSwapTakerReqName = "tokenswaptaker"
+const transferStatusReqName = "transfer status"
+const routesReqName = "routes"
+const cancelTransferReqName = "cancel transfer"
+const settleHoldPaymentReqName = "settle hold payment"
+const rejectHoldPaymentReqName = "reject hold payment"
+const rebalanceReqName = "rebalance"
+const reapCandidatesReqName = "reap candidates"
+const clearPathCachesReqName = "clear path caches"
 
 /*
 transfer api
@@ -53,7 +62,9 @@ type transferReq struct {
 	Target           common.Address
 	Fee              *big.Int
 	Secret           common.Hash
+	LockSecretHash   common.Hash //secret is unknown, only for mediated transfer
 	IsDirectTransfer bool
+	Data             encoding.PaymentData
 }
 
 /*
@@ -101,6 +112,47 @@ type tokenSwapTakerReq struct {
 	tokenSwap *TokenSwap
 }
 
+/*
+query status of pending transfers
+*/
+type transferStatusReq struct {
+	lockSecretHash common.Hash //empty means all
+}
+
+/*
+preview routes of a transfer
+*/
+type routesReq struct {
+	tokenAddress common.Address
+	target       common.Address
+	amount       *big.Int
+}
+
+/*
+cancel a transfer initiated by me
+*/
+type cancelTransferReq struct {
+	lockSecretHash common.Hash
+}
+
+/*
+settle or reject a hold payment
+*/
+type holdPaymentReq struct {
+	secret         common.Hash //for settle
+	lockSecretHash common.Hash //for reject
+}
+
+/*
+move `amount` from channel `fromChannel` to channel `toChannel` by paying myself
+*/
+type rebalanceReq struct {
+	fromChannel common.Hash
+	toChannel   common.Hash
+	amount      *big.Int
+	maxFee      *big.Int
+}
+
 /*
 general req's wraper
 */
@@ -122,7 +174,7 @@ Transfer `amount` between this node and `target`.
            - Network speed, making the transfer sufficiently fast so it doesn't
              expire.
 */
-func (rs *RaidenService) transferAsyncClient(tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, isDirectTransfer bool) *utils.AsyncResult {
+func (rs *RaidenService) transferAsyncClient(tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, secret common.Hash, isDirectTransfer bool, data *encoding.PaymentData) *utils.AsyncResult {
 	req := &apiReq{
 		ReqID: utils.RandomString(10),
 		Name:  transferReqName,
@@ -133,6 +185,7 @@ func (rs *RaidenService) transferAsyncClient(tokenAddress common.Address, amount
 			Secret:           secret,
 			Fee:              fee,
 			IsDirectTransfer: isDirectTransfer,
+			Data:             *data,
 		},
 	}
 	return rs.sendReqClient(req)
@@ -265,3 +318,125 @@ func (rs *RaidenService) tokenSwapTakerClient(tokenswap *TokenSwap) *utils.Async
 	}
 	return rs.sendReqClient(req)
 }
+
+/*
+transferStatusClient returns status of pending transfers, result's Tag is []*TransferStatus
+*/
+func (rs *RaidenService) transferStatusClient(lockSecretHash common.Hash) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  transferStatusReqName,
+		Req: &transferStatusReq{
+			lockSecretHash: lockSecretHash,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+/*
+routesClient returns routes a transfer would try, result's Tag is []*RouteInfo
+*/
+func (rs *RaidenService) routesClient(tokenAddress, target common.Address, amount *big.Int) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  routesReqName,
+		Req: &routesReq{
+			tokenAddress: tokenAddress,
+			target:       target,
+			amount:       amount,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+/*
+cancelTransferClient cancels the transfer with `lockSecretHash` initiated by me, if the secret is not revealed yet.
+*/
+func (rs *RaidenService) cancelTransferClient(lockSecretHash common.Hash) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  cancelTransferReqName,
+		Req: &cancelTransferReq{
+			lockSecretHash: lockSecretHash,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+/*
+transferWithLockSecretHashClient starts a mediated transfer whose secret is kept by the target
+*/
+func (rs *RaidenService) transferWithLockSecretHashClient(tokenAddress common.Address, amount *big.Int, target common.Address, lockSecretHash common.Hash, data *encoding.PaymentData) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  transferReqName,
+		Req: &transferReq{
+			TokenAddress:   tokenAddress,
+			Amount:         amount,
+			Target:         target,
+			Fee:            utils.BigInt0,
+			LockSecretHash: lockSecretHash,
+			Data:           *data,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+//settleHoldPaymentClient settles the hold payment locked with `secret`
+func (rs *RaidenService) settleHoldPaymentClient(secret common.Hash) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  settleHoldPaymentReqName,
+		Req: &holdPaymentReq{
+			secret: secret,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+//rejectHoldPaymentClient rejects the hold payment with `lockSecretHash`
+func (rs *RaidenService) rejectHoldPaymentClient(lockSecretHash common.Hash) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  rejectHoldPaymentReqName,
+		Req: &holdPaymentReq{
+			lockSecretHash: lockSecretHash,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+//rebalanceClient pays myself out through `fromChannel` and back through `toChannel`
+func (rs *RaidenService) rebalanceClient(fromChannel, toChannel common.Hash, amount, maxFee *big.Int) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  rebalanceReqName,
+		Req: &rebalanceReq{
+			fromChannel: fromChannel,
+			toChannel:   toChannel,
+			amount:      amount,
+			maxFee:      maxFee,
+		},
+	}
+	return rs.sendReqClient(req)
+}
+
+/*
+reapCandidatesClient returns idle channels, result's Tag is []*ReapCandidate
+*/
+func (rs *RaidenService) reapCandidatesClient() *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  reapCandidatesReqName,
+	}
+	return rs.sendReqClient(req)
+}
+
+//clearPathCachesClient makes paths of all the tokens weighed again in the main loop
+func (rs *RaidenService) clearPathCachesClient() *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  clearPathCachesReqName,
+	}
+	return rs.sendReqClient(req)
+}
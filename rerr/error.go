@@ -162,3 +162,18 @@ var ErrTransferTimeout = errors.New("TransferTimeout")
 
 // ErrStopCreateNewTransfer reject new transactions
 var ErrStopCreateNewTransfer = errors.New("new transactions are not allowed")
+
+//ErrTransferNotFound no pending transfer initiated by me
+var ErrTransferNotFound = errors.New("TransferNotFound")
+
+//ErrTransferCannotCancel secret of the transfer is already revealed or unknown to me, it cannot be canceled
+var ErrTransferCannotCancel = errors.New("secret already revealed, transfer cannot be canceled")
+
+//ErrIdempotencyKeyReused the idempotency key is already used by a request with different parameters
+var ErrIdempotencyKeyReused = errors.New("idempotency key already used with different parameters")
+
+//ErrRequestInProgress the first request with this idempotency key has not finished yet
+var ErrRequestInProgress = errors.New("request with this idempotency key is still in progress")
+
+//ErrRequestInterrupted the node stopped before the first request with this idempotency key finished, its result is unknown
+var ErrRequestInterrupted = errors.New("request with this idempotency key was interrupted by a restart, its result is unknown")
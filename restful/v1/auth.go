@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+//APIKeyHeader is the header carrying api key, `Authorization: Bearer <key>` is also accepted.
+const APIKeyHeader = "X-API-Key"
+
+//routes which change state of this node even though they are GET
+var operatorGetPrefixes = []string{
+	"/api/1/debug/",
+	"/api/1/stop",
+	"/api/1/switch/",
+}
+
+/*
+requiredRole returns the role needed to access `r`,
+queries and events need read-only, others need operator.
+*/
+func requiredRole(r *rest.Request) string {
+	if r.Method != http.MethodGet {
+		return models.APIKeyRoleOperator
+	}
+	for _, p := range operatorGetPrefixes {
+		if strings.HasPrefix(r.URL.Path, p) {
+			return models.APIKeyRoleOperator
+		}
+	}
+	return models.APIKeyRoleReadOnly
+}
+
+func apiKeyOfRequest(r *rest.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(auth[len("Bearer "):])
+	}
+	return ""
+}
+
+/*
+authMiddleware checks api key of every request once any api key is created,
+without any api key, the api is open to everyone who can reach it, just as before.
+*/
+type authMiddleware struct {
+	db *models.ModelDB
+}
+
+//MiddlewareFunc implements rest.Middleware
+func (mw *authMiddleware) MiddlewareFunc(handler rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		key := apiKeyOfRequest(r)
+		if key == "" {
+			if !mw.db.HasAPIKeys() {
+				handler(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			rest.Error(w, "api key required", http.StatusUnauthorized)
+			return
+		}
+		k, err := mw.db.GetAPIKeyByKey(key)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			rest.Error(w, "invalid api key", http.StatusUnauthorized)
+			return
+		}
+		if requiredRole(r) == models.APIKeyRoleOperator && k.Role != models.APIKeyRoleOperator {
+			log.Warn(fmt.Sprintf("api key %s with role %s denied %s %s", k.Name, k.Role, r.Method, r.URL.Path))
+			rest.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		r.Env["REMOTE_USER"] = k.Name
+		handler(w, r)
+	}
+}
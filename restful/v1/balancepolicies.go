@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+SetBalancePolicy creates or updates the balance policy of a token, or of a channel if `channel_identifier` is given.
+{"token_address":"0x...","channel_identifier":"0x...","min_balance":10,"max_balance":100,"target_balance":50,"min_amount":5,"max_gas_price":20000000000,"interval":100}
+*/
+func SetBalancePolicy(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		Token             string   `json:"token_address"`
+		ChannelIdentifier string   `json:"channel_identifier"`
+		MinBalance        *big.Int `json:"min_balance"`
+		MaxBalance        *big.Int `json:"max_balance"`
+		TargetBalance     *big.Int `json:"target_balance"`
+		MinAmount         *big.Int `json:"min_amount"`
+		MaxGasPrice       *big.Int `json:"max_gas_price"`
+		Interval          int64    `json:"interval"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token, err := utils.HexToAddress(req.Token)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p := &models.BalancePolicy{
+		TokenAddress:  token,
+		MinBalance:    req.MinBalance,
+		MaxBalance:    req.MaxBalance,
+		TargetBalance: req.TargetBalance,
+		MinAmount:     req.MinAmount,
+		MaxGasPrice:   req.MaxGasPrice,
+		Interval:      req.Interval,
+	}
+	if req.ChannelIdentifier != "" {
+		p.ChannelIdentifier = common.HexToHash(req.ChannelIdentifier)
+	}
+	err = RaidenAPI.SetBalancePolicy(p)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.WriteJson(p)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetBalancePolicies returns all the balance policies
+*/
+func GetBalancePolicies(w rest.ResponseWriter, r *rest.Request) {
+	ps, err := RaidenAPI.GetBalancePolicies()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(ps)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetBalancePolicy returns the policy of a token address or a channel identifier
+*/
+func GetBalancePolicy(w rest.ResponseWriter, r *rest.Request) {
+	p, err := RaidenAPI.GetBalancePolicy(balancePolicyKey(r.PathParam("key")))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(p)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+RemoveBalancePolicy removes the policy of a token address or a channel identifier
+*/
+func RemoveBalancePolicy(w rest.ResponseWriter, r *rest.Request) {
+	err := RaidenAPI.RemoveBalancePolicy(balancePolicyKey(r.PathParam("key")))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+GetBalancePolicyActions returns the last deposit or withdraw of every channel made by balance policies
+*/
+func GetBalancePolicyActions(w rest.ResponseWriter, r *rest.Request) {
+	as, err := RaidenAPI.GetBalancePolicyActions()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(as)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+//balancePolicyKey accepts a token address or a channel identifier
+func balancePolicyKey(key string) string {
+	if len(key) == len(common.Hash{}.String()) {
+		return common.HexToHash(key).String()
+	}
+	return common.HexToAddress(key).String()
+}
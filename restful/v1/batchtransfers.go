@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+/*
+BatchTransfer starts a list of transfers, they are sent `concurrency` at a time in the background.
+{"concurrency":5,"transfers":[{"token_address":"0x...","target_address":"0x...","amount":10,"fee":0,"identifier":1}]}
+*/
+func BatchTransfer(w rest.ResponseWriter, r *rest.Request) {
+	if RaidenAPI.Raiden.StopCreateNewTransfers {
+		rest.Error(w, "Stop create new transfers, please restart smartraiden", http.StatusBadRequest)
+		return
+	}
+	type Item struct {
+		Token      string   `json:"token_address"`
+		Target     string   `json:"target_address"`
+		Amount     *big.Int `json:"amount"`
+		Fee        *big.Int `json:"fee"`
+		Identifier uint64   `json:"identifier"`
+	}
+	type Req struct {
+		Concurrency int     `json:"concurrency"`
+		Transfers   []*Item `json:"transfers"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var items []*models.BatchTransferItem
+	for i, t := range req.Transfers {
+		token, err := utils.HexToAddress(t.Token)
+		if err != nil {
+			rest.Error(w, fmt.Sprintf("transfer %d: %s", i, err), http.StatusBadRequest)
+			return
+		}
+		target, err := utils.HexToAddress(t.Target)
+		if err != nil {
+			rest.Error(w, fmt.Sprintf("transfer %d: %s", i, err), http.StatusBadRequest)
+			return
+		}
+		items = append(items, &models.BatchTransferItem{
+			Token:      token,
+			Target:     target,
+			Amount:     t.Amount,
+			Fee:        t.Fee,
+			Identifier: t.Identifier,
+		})
+	}
+	b, err := RaidenAPI.BatchTransfer(items, req.Concurrency)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	err = w.WriteJson(b)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetBatchTransfer returns status of each transfer of the batch with `id` and the totals
+*/
+func GetBatchTransfer(w rest.ResponseWriter, r *rest.Request) {
+	b, err := RaidenAPI.GetBatchTransfer(r.PathParam("id"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(b)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetBatchTransfers returns all the batches
+*/
+func GetBatchTransfers(w rest.ResponseWriter, r *rest.Request) {
+	bs, err := RaidenAPI.GetBatchTransfers()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(bs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
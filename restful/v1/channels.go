@@ -9,6 +9,7 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
@@ -31,8 +32,9 @@ type ChannelData struct {
 	TokenAddress        string            `json:"token_address"`
 	State               channeltype.State `json:"state"`
 	StateString         string
-	SettleTimeout       int `json:"settle_timeout"`
-	RevealTimeout       int `json:"reveal_timeout"`
+	SettleTimeout       int    `json:"settle_timeout"`
+	RevealTimeout       int    `json:"reveal_timeout"`
+	IdempotencyKey      string `json:"idempotency_key,omitempty"` //a retry with the same key never opens a second channel
 }
 
 //ChannelDataDetail more info
@@ -193,7 +195,17 @@ func OpenChannel(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	if req.State == 0 { //open channel
-		c, err := RaidenAPI.Open(tokenAddr, partnerAddr, req.SettleTimeout, params.DefaultRevealTimeout, req.Balance)
+		var c *channeltype.Serialization
+		key := idempotencyKey(r, req.IdempotencyKey)
+		if key == "" {
+			c, err = RaidenAPI.Open(tokenAddr, partnerAddr, req.SettleTimeout, params.DefaultRevealTimeout, req.Balance)
+		} else {
+			var rec *models.IdempotencyRecord
+			rec, c, err = RaidenAPI.OpenIdempotent(key, tokenAddr, partnerAddr, req.SettleTimeout, params.DefaultRevealTimeout, req.Balance)
+			if writeIdempotencyError(w, rec, err) {
+				return
+			}
+		}
 		if err != nil {
 			log.Error(err.Error())
 			rest.Error(w, err.Error(), http.StatusConflict)
@@ -212,6 +224,7 @@ func OpenChannel(w rest.ResponseWriter, r *rest.Request) {
 			LockedAmount:        c.OurAmountLocked(),
 			PartnerLockedAmount: c.PartnerAmountLocked(),
 			RevealTimeout:       c.RevealTimeout,
+			IdempotencyKey:      key,
 		}
 		err = w.WriteJson(d)
 		if err != nil {
@@ -241,6 +254,8 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 		StateInt channeltype.State
 		Balance  *big.Int
 		Force    bool
+		//only for deposit, a retry with the same key never deposits again
+		IdempotencyKey string `json:"idempotency_key"`
 	}
 	req := &Req{}
 	err := r.DecodeJsonPayload(req)
@@ -255,7 +270,16 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	if req.Balance != nil && req.Balance.Cmp(utils.BigInt0) > 0 { //deposit
-		c, err = RaidenAPI.Deposit(c.TokenAddress(), c.PartnerAddress(), req.Balance, params.DefaultPollTimeout)
+		key := idempotencyKey(r, req.IdempotencyKey)
+		if key == "" {
+			c, err = RaidenAPI.Deposit(c.TokenAddress(), c.PartnerAddress(), req.Balance, params.DefaultPollTimeout)
+		} else {
+			var rec *models.IdempotencyRecord
+			rec, c, err = RaidenAPI.DepositIdempotent(key, c.TokenAddress(), c.PartnerAddress(), req.Balance, params.DefaultPollTimeout)
+			if writeIdempotencyError(w, rec, err) {
+				return
+			}
+		}
 		if err != nil {
 			rest.Error(w, err.Error(), http.StatusRequestTimeout)
 			return
@@ -384,3 +408,106 @@ func withdraw(w rest.ResponseWriter, r *rest.Request) {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
 	}
 }
+
+/*
+settle settle a channel.
+if the channel is closed, settle it on chain, otherwise cooperative settle it with partner.
+{"op":"preparesettle"} mark this channel prepared for cooperative settle,
+{"op":"cancelprepare"} cancel the mark.
+*/
+func settle(w rest.ResponseWriter, r *rest.Request) {
+	chstr := r.PathParam("channel")
+	if len(chstr) != len(utils.EmptyHash.String()) {
+		rest.Error(w, "argument error", http.StatusBadRequest)
+		return
+	}
+	chAddr := common.HexToHash(chstr)
+	type Req struct {
+		Op string
+	}
+	const OpPrepareSettle = "preparesettle"
+	const OpCancelPrepare = "cancelprepare"
+
+	req := &Req{}
+	if r.ContentLength > 0 {
+		err := r.DecodeJsonPayload(req)
+		if err != nil {
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	c, err := RaidenAPI.GetChannel(chAddr)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if req.Op == OpPrepareSettle {
+		c, err = RaidenAPI.PrepareForCooperativeSettle(c.TokenAddress(), c.PartnerAddress())
+	} else if req.Op == OpCancelPrepare {
+		c, err = RaidenAPI.CancelPrepareForCooperativeSettle(c.TokenAddress(), c.PartnerAddress())
+	} else if req.Op != "" {
+		err = fmt.Errorf("unkown operation %s", req.Op)
+	} else if c.State == channeltype.StateClosed {
+		c, err = RaidenAPI.Settle(c.TokenAddress(), c.PartnerAddress())
+	} else {
+		c, err = RaidenAPI.CooperativeSettle(c.TokenAddress(), c.PartnerAddress())
+	}
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d := &ChannelData{
+		ChannelAddress:      c.ChannelIdentifier.ChannelIdentifier.String(),
+		OpenBlockNumber:     c.ChannelIdentifier.OpenBlockNumber,
+		PartnerAddrses:      c.PartnerAddress().String(),
+		Balance:             c.OurBalance(),
+		PartnerBalance:      c.PartnerBalance(),
+		State:               c.State,
+		StateString:         c.State.String(),
+		SettleTimeout:       c.SettleTimeout,
+		TokenAddress:        c.TokenAddress().String(),
+		LockedAmount:        c.OurAmountLocked(),
+		PartnerLockedAmount: c.PartnerAmountLocked(),
+		RevealTimeout:       c.RevealTimeout,
+	}
+	err = w.WriteJson(d)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetSettleRecords returns outcomes of all the channels settled automatically
+*/
+func GetSettleRecords(w rest.ResponseWriter, r *rest.Request) {
+	records, err := RaidenAPI.GetSettleRecords()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(records)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetSettleRecord returns outcome of the channel settled automatically
+*/
+func GetSettleRecord(w rest.ResponseWriter, r *rest.Request) {
+	chstr := r.PathParam("channel")
+	if len(chstr) != len(utils.EmptyHash.String()) {
+		rest.Error(w, "argument error", http.StatusBadRequest)
+		return
+	}
+	record, err := RaidenAPI.GetSettleRecord(common.HexToHash(chstr))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(record)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
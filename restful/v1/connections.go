@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+/*
+GetConnections returns details of all joined token networks
+*/
+func GetConnections(w rest.ResponseWriter, r *rest.Request) {
+	infos, err := RaidenAPI.GetConnections()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(infos)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+ConnectTokenNetwork automatically join a token network.
+return once all channels have been opened and deposited.
+*/
+func ConnectTokenNetwork(w rest.ResponseWriter, r *rest.Request) {
+	tokenAddr, err := utils.HexToAddress(r.PathParam("token"))
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	type Req struct {
+		Funds *big.Int `json:"funds"`
+	}
+	req := &Req{}
+	err = r.DecodeJsonPayload(req)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Funds == nil || req.Funds.Cmp(utils.BigInt0) <= 0 {
+		rest.Error(w, "Invalid funds", http.StatusBadRequest)
+		return
+	}
+	err = RaidenAPI.Connect(tokenAddr, req.Funds)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+/*
+LeaveTokenNetwork leave a token network,
+return the addresses of all the channels cooperative settled or closed.
+*/
+func LeaveTokenNetwork(w rest.ResponseWriter, r *rest.Request) {
+	tokenAddr, err := utils.HexToAddress(r.PathParam("token"))
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	type Req struct {
+		OnlyReceivingChannels bool `json:"only_receiving_channels"`
+	}
+	req := &Req{}
+	if r.ContentLength > 0 {
+		err = r.DecodeJsonPayload(req)
+		if err != nil {
+			log.Error(err.Error())
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	chs, err := RaidenAPI.Leave(tokenAddr, req.OnlyReceivingChannels)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var addrs []string
+	for _, c := range chs {
+		addrs = append(addrs, c.ChannelIdentifier.ChannelIdentifier.String())
+	}
+	err = w.WriteJson(addrs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
@@ -160,3 +160,25 @@ func ForceUnlock(w rest.ResponseWriter, r *rest.Request) {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
 	}
 }
+
+/*
+GetMissionControl returns failures and successes of hops remembered when routing transfers
+*/
+func GetMissionControl(w rest.ResponseWriter, r *rest.Request) {
+	err := w.WriteJson(RaidenAPI.GetRouteHistories())
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+ResetMissionControl forgets all failures and successes of hops
+*/
+func ResetMissionControl(w rest.ResponseWriter, r *rest.Request) {
+	err := RaidenAPI.ResetRouteHistories()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -61,7 +61,8 @@ func EventTokens(w rest.ResponseWriter, r *rest.Request) {
 }
 
 /*
-EventChannels returns all events about the channel specified
+EventChannels returns all events about the channel specified,
+transfers sent and received on this channel are included if query `transfers` is true.
 */
 func EventChannels(w rest.ResponseWriter, r *rest.Request) {
 	fromBlock, toBlock := getFromTo(r)
@@ -74,7 +75,8 @@ func EventChannels(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	channel = common.HexToHash(channelstr)
-	events, err := RaidenAPI.GetChannelEvents(channel, fromBlock, toBlock)
+	withTransfers := r.URL.Query().Get("transfers") == "true"
+	events, err := RaidenAPI.GetChannelEvents(channel, fromBlock, toBlock, withTransfers)
 	if err != nil {
 		log.Error(err.Error())
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
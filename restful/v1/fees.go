@@ -0,0 +1,218 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+SetFeeRate creates or updates the default fee rate, the rate of a token, or the rate of a partner or a channel of the token.
+fee of a transfer is `base_fee + amount * proportional_rate / 1000000`.
+{"token_address":"0x...","partner_address":"0x...","channel_identifier":"0x...","base_fee":1,"proportional_rate":100}
+*/
+func SetFeeRate(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		Token             string   `json:"token_address"`
+		PartnerAddress    string   `json:"partner_address"`
+		ChannelIdentifier string   `json:"channel_identifier"`
+		BaseFee           *big.Int `json:"base_fee"`
+		ProportionalRate  int64    `json:"proportional_rate"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rate := &models.FeeRate{
+		BaseFee:          req.BaseFee,
+		ProportionalRate: req.ProportionalRate,
+	}
+	if req.Token != "" {
+		rate.TokenAddress, err = utils.HexToAddress(req.Token)
+		if err != nil {
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.PartnerAddress != "" {
+		rate.PartnerAddress, err = utils.HexToAddress(req.PartnerAddress)
+		if err != nil {
+			rest.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ChannelIdentifier != "" {
+		rate.ChannelIdentifier = common.HexToHash(req.ChannelIdentifier)
+	}
+	err = RaidenAPI.SetFeeRate(rate)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.WriteJson(rate)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetFeeRates returns all the fee rates
+*/
+func GetFeeRates(w rest.ResponseWriter, r *rest.Request) {
+	rates, err := RaidenAPI.GetFeeRates()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(rates)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetFeeRate returns the rate with `key`
+*/
+func GetFeeRate(w rest.ResponseWriter, r *rest.Request) {
+	rate, err := RaidenAPI.GetFeeRate(feeRateKey(r.PathParam("key")))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(rate)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+RemoveFeeRate removes the rate with `key`
+*/
+func RemoveFeeRate(w rest.ResponseWriter, r *rest.Request) {
+	err := RaidenAPI.RemoveFeeRate(feeRateKey(r.PathParam("key")))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+GetChargeFee returns the fee this node charges for mediating a transfer of `amount` to `partner`
+*/
+func GetChargeFee(w rest.ResponseWriter, r *rest.Request) {
+	tokenAddr, err := utils.HexToAddress(r.PathParam("token"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	partnerAddr, err := utils.HexToAddress(r.PathParam("partner"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	amount, ok := new(big.Int).SetString(r.URL.Query().Get("amount"), 0)
+	if !ok || amount.Cmp(utils.BigInt0) <= 0 {
+		rest.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	type Resp struct {
+		Fee *big.Int `json:"fee"`
+	}
+	err = w.WriteJson(&Resp{RaidenAPI.GetChargeFee(tokenAddr, partnerAddr, amount)})
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+//feeRateKey accepts `default`, a token address, `token-partner` or a channel identifier
+func feeRateKey(key string) string {
+	if key == models.FeeRateDefaultKey {
+		return key
+	}
+	if ss := strings.Split(key, "-"); len(ss) == 2 {
+		return models.FeeRateKey(common.HexToAddress(ss[0]), utils.EmptyHash, common.HexToAddress(ss[1]))
+	}
+	if len(key) == len(common.Hash{}.String()) {
+		return common.HexToHash(key).String()
+	}
+	return common.HexToAddress(key).String()
+}
+
+/*
+GetFeeEarnings returns the fee earned by mediating, `from` and `to` are unix seconds.
+group_by is `token`, `channel` or `period`, `period` is seconds of a period, default one day.
+*/
+func GetFeeEarnings(w rest.ResponseWriter, r *rest.Request) {
+	from, to, err := getTimeRange(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var period int64
+	if s := r.URL.Query().Get("period"); s != "" {
+		period, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			rest.Error(w, "invalid period", http.StatusBadRequest)
+			return
+		}
+	}
+	earnings, err := RaidenAPI.GetFeeEarnings(from, to, r.URL.Query().Get("group_by"), period)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.WriteJson(earnings)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetMediationRecords returns the transfers mediated between `from` and `to`
+*/
+func GetMediationRecords(w rest.ResponseWriter, r *rest.Request) {
+	from, to, err := getTimeRange(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	records, err := RaidenAPI.GetMediationRecords(from, to)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(records)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+//getTimeRange parses `from` and `to` of query, -1 if absent
+func getTimeRange(r *rest.Request) (from, to int64, err error) {
+	from, to = -1, -1
+	if s := r.URL.Query().Get("from"); s != "" {
+		from, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid from %s", s)
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		to, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid to %s", s)
+		}
+	}
+	return
+}
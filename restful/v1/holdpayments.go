@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+RegisterHoldPayment registers a payment this node expects,
+transfers with `lock_secret_hash` are held until settled or rejected.
+{"lock_secret_hash":"0x...","token_address":"0x...","amount":10,"deadline":1540000000}
+*/
+func RegisterHoldPayment(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		LockSecretHash string   `json:"lock_secret_hash"`
+		Token          string   `json:"token_address"`
+		Amount         *big.Int `json:"amount"`
+		Deadline       int64    `json:"deadline"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token, err := utils.HexToAddress(req.Token)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h, err := RaidenAPI.RegisterHoldPayment(common.HexToHash(req.LockSecretHash), token, req.Amount, req.Deadline)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	err = w.WriteJson(h)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+SettleHoldPayment reveals the secret of a held payment to the payer
+{"secret":"0x..."}
+*/
+func SettleHoldPayment(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		Secret string `json:"secret"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secret := common.HexToHash(req.Secret)
+	if secret == utils.EmptyHash {
+		rest.Error(w, "Invalid secret", http.StatusBadRequest)
+		return
+	}
+	err = RaidenAPI.SettleHoldPayment(secret)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	h, err := RaidenAPI.GetHoldPayment(utils.ShaSecret(secret[:]))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(h)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+RejectHoldPayment gives back the transfers held to the payer by AnnounceDisposed
+*/
+func RejectHoldPayment(w rest.ResponseWriter, r *rest.Request) {
+	lockSecretHash := common.HexToHash(r.PathParam("locksecrethash"))
+	if lockSecretHash == utils.EmptyHash {
+		rest.Error(w, "Invalid lock secret hash", http.StatusBadRequest)
+		return
+	}
+	err := RaidenAPI.RejectHoldPayment(lockSecretHash)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	h, err := RaidenAPI.GetHoldPayment(lockSecretHash)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(h)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetHoldPayments returns all the hold payments registered
+*/
+func GetHoldPayments(w rest.ResponseWriter, r *rest.Request) {
+	hs, err := RaidenAPI.GetHoldPayments()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(hs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetHoldPayment returns the hold payment with `locksecrethash`
+*/
+func GetHoldPayment(w rest.ResponseWriter, r *rest.Request) {
+	lockSecretHash := common.HexToHash(r.PathParam("locksecrethash"))
+	if lockSecretHash == utils.EmptyHash {
+		rest.Error(w, "Invalid lock secret hash", http.StatusBadRequest)
+		return
+	}
+	h, err := RaidenAPI.GetHoldPayment(lockSecretHash)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(h)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
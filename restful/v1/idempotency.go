@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+//idempotencyKey returns the key of header `Idempotency-Key`, or `key` from the request body if there is no such header
+func idempotencyKey(r *rest.Request, key string) string {
+	if k := r.Header.Get("Idempotency-Key"); k != "" {
+		return k
+	}
+	return key
+}
+
+/*
+writeIdempotencyError writes the response if the request with an idempotency key cannot go on,
+202 and the record when the first request is still in progress,
+409 and the record when the first request was interrupted by a restart,
+422 when the key is used by a different request.
+*/
+func writeIdempotencyError(w rest.ResponseWriter, rec *models.IdempotencyRecord, err error) bool {
+	switch err {
+	case rerr.ErrRequestInProgress:
+		w.WriteHeader(http.StatusAccepted)
+		err = w.WriteJson(rec)
+		if err != nil {
+			log.Warn(fmt.Sprintf("writejson err %s", err))
+		}
+		return true
+	case rerr.ErrRequestInterrupted:
+		w.WriteHeader(http.StatusConflict)
+		err = w.WriteJson(rec)
+		if err != nil {
+			log.Warn(fmt.Sprintf("writejson err %s", err))
+		}
+		return true
+	case rerr.ErrIdempotencyKeyReused:
+		rest.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+/*
+GetIdleChannels returns channels without transfers for longer than the reaper's idle timeout,
+and what the reaper does with them.
+*/
+func GetIdleChannels(w rest.ResponseWriter, r *rest.Request) {
+	candidates, err := RaidenAPI.GetReapCandidates()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.WriteJson(candidates)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
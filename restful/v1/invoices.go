@@ -0,0 +1,115 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+CreateInvoice creates an invoice signed by this node
+{"token_address":"0x...","amount":10,"lock_secret_hash":"0x...","expiration":1540000000,"memo":"order 42"}
+`lock_secret_hash`, `expiration` and `memo` are optional.
+*/
+func CreateInvoice(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		Token          string   `json:"token_address"`
+		Amount         *big.Int `json:"amount"`
+		LockSecretHash string   `json:"lock_secret_hash"`
+		Expiration     int64    `json:"expiration"`
+		Memo           string   `json:"memo"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token, err := utils.HexToAddress(req.Token)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inv, err := RaidenAPI.CreateInvoice(token, req.Amount, common.HexToHash(req.LockSecretHash), req.Expiration, req.Memo)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	err = w.WriteJson(inv)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+PayInvoice decodes, verifies and pays an invoice
+{"invoice":"smartraiden:..."}
+202 means the transfer is still going on, for example the target has not registered the secret yet.
+*/
+func PayInvoice(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		Invoice string `json:"invoice"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	inv, err := RaidenAPI.PayInvoice(req.Invoice, params.MaxRequestTimeout)
+	if err == rerr.ErrTransferTimeout {
+		w.WriteHeader(http.StatusAccepted)
+	} else if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	err = w.WriteJson(inv)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetInvoices returns all the invoices created or paid by this node
+*/
+func GetInvoices(w rest.ResponseWriter, r *rest.Request) {
+	invs, err := RaidenAPI.GetInvoices()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(invs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetInvoice returns the invoice with `hash`, its status is open, paid or expired
+*/
+func GetInvoice(w rest.ResponseWriter, r *rest.Request) {
+	hash := common.HexToHash(r.PathParam("hash"))
+	if hash == utils.EmptyHash {
+		rest.Error(w, "Invalid hash", http.StatusBadRequest)
+		return
+	}
+	inv, err := RaidenAPI.GetInvoice(hash)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(inv)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
@@ -30,7 +30,27 @@ func Start() {
 
 	api := rest.NewApi()
 	api.Use(rest.DefaultDevStack...)
-	router, err := rest.MakeRouter(
+	db := RaidenAPI.Raiden.GetDb()
+	if !db.HasAPIKeys() {
+		log.Warn(fmt.Sprintf("no api key found, api on %s:%d is open to everyone who can reach it", Config.APIHost, Config.APIPort))
+	}
+	api.Use(&authMiddleware{db: db})
+	router, err := rest.MakeRouter(makeRoutes()...)
+	if err != nil {
+		log.Crit(fmt.Sprintf("maker router :%s", err))
+	}
+	api.SetApp(router)
+	hub.start()
+	listen := fmt.Sprintf("%s:%d", Config.APIHost, Config.APIPort)
+	log.Crit(fmt.Sprintf("http listen and serve :%s", http.ListenAndServe(listen, api.MakeHandler())))
+}
+
+/*
+makeRoutes returns all the routes of the api, debug routes only if Config.EnableDebugAPI.
+placeholders of routes sharing a prefix must have the same name, or rest.MakeRouter fails.
+*/
+func makeRoutes() []*rest.Route {
+	routes := []*rest.Route{
 
 		/*
 			prepare update
@@ -41,7 +61,16 @@ func Start() {
 		*/
 		rest.Get("/api/1/querysenttransfer", GetSentTransfers),
 		rest.Get("/api/1/queryreceivedtransfer", GetReceivedTransfers),
+		rest.Get("/api/1/querysenttransfer/:identifier", GetSentTransfersByIdentifier),
+		rest.Get("/api/1/queryreceivedtransfer/:identifier", GetReceivedTransfersByIdentifier),
 		rest.Post("/api/1/transfers/:token/:target", Transfers),
+		rest.Get("/api/1/transfers/pending", GetPendingTransfers),
+		rest.Get("/api/1/transfers/status/:locksecrethash", GetTransferStatus),
+		rest.Delete("/api/1/transfers/pending/:locksecrethash", CancelTransfer),
+		rest.Post("/api/1/transfers/batch", BatchTransfer),
+		rest.Get("/api/1/transfers/batch", GetBatchTransfers),
+		rest.Get("/api/1/transfers/batch/:id", GetBatchTransfer),
+		rest.Get("/api/1/routes/:token/:target", GetRoutes),
 		/*
 			transfer with specified secret
 		*/
@@ -66,6 +95,8 @@ func Start() {
 		rest.Get("/api/1/channels", GetChannelList),
 		rest.Put("/api/1/channels", OpenChannel),
 		rest.Patch("/api/1/channels/:channel", CloseSettleDepositChannel),
+		rest.Post("/api/1/channels/rebalance", Rebalance),
+		rest.Get("/api/1/idlechannels", GetIdleChannels),
 		rest.Get("/api/1/thirdparty/:channel/:3rd", ChannelFor3rdParty),
 		/*
 			tokens
@@ -73,6 +104,12 @@ func Start() {
 		rest.Get("/api/1/tokens", Tokens),
 		rest.Get("/api/1/tokens/:token/partners", TokenPartners),
 		rest.Put("/api/1/tokens/:token", RegisterToken),
+		/*
+			connections
+		*/
+		rest.Get("/api/1/connections", GetConnections),
+		rest.Put("/api/1/connections/:token", ConnectTokenNetwork),
+		rest.Delete("/api/1/connections/:token", LeaveTokenNetwork),
 		/*
 			utils
 		*/
@@ -98,33 +135,87 @@ func Start() {
 		*/
 		rest.Put("/api/1/withdraw/:channel", withdraw),
 		/*
-			1. prepare for withdraw:
+			1. settle:
+			{}
+			2. prepare for settle:
 			{"op":"preparesettle",}
 			3. cancel prepare:
 			{"op": "cancelprepare"}
 		*/
-		rest.Put("/api/1/settle/:channel", nil),
+		rest.Put("/api/1/settle/:channel", settle),
+		rest.Get("/api/1/settle", GetSettleRecords),
+		rest.Get("/api/1/settle/:channel", GetSettleRecord),
 		/*
 			events
 		*/
 		rest.Get("/api/1/events/network", EventNetwork),
 		rest.Get("/api/1/events/tokens/:token", EventTokens),
 		rest.Get("/api/1/events/channels/:channel", EventChannels),
+		rest.Get("/api/1/events/stream", EventStream),
 		/*
-			for debug only
+			webhooks
 		*/
-		rest.Get("/api/1/debug/balance/:token/:addr", Balance),
-		rest.Get("/api/1/debug/transfer/:token/:addr/:value", TransferToken),
-		rest.Get("/api/1/debug/ethbalance/:addr", EthBalance),
-		rest.Get("/api/1/debug/ethstatus", EthereumStatus),
-		rest.Get("/api/1/debug/force-unlock/:channel/:locksecrethash/:secrethash", ForceUnlock),
-	)
-	if err != nil {
-		log.Crit(fmt.Sprintf("maker router :%s", err))
+		rest.Get("/api/1/webhooks", GetWebhooks),
+		rest.Put("/api/1/webhooks", AddWebhook),
+		rest.Delete("/api/1/webhooks/:id", RemoveWebhook),
+		/*
+			invoices
+		*/
+		rest.Get("/api/1/invoices", GetInvoices),
+		rest.Get("/api/1/invoices/:hash", GetInvoice),
+		rest.Post("/api/1/invoices", CreateInvoice),
+		rest.Post("/api/1/invoices/pay", PayInvoice),
+		/*
+			hold payments
+		*/
+		rest.Get("/api/1/holdpayments", GetHoldPayments),
+		rest.Get("/api/1/holdpayments/:locksecrethash", GetHoldPayment),
+		rest.Post("/api/1/holdpayments", RegisterHoldPayment),
+		rest.Post("/api/1/holdpayments/settle", SettleHoldPayment),
+		rest.Delete("/api/1/holdpayments/:locksecrethash", RejectHoldPayment),
+		/*
+			balance policies
+		*/
+		rest.Get("/api/1/balancepolicies", GetBalancePolicies),
+		rest.Put("/api/1/balancepolicies", SetBalancePolicy),
+		rest.Get("/api/1/balancepolicies/actions", GetBalancePolicyActions),
+		rest.Get("/api/1/balancepolicies/:key", GetBalancePolicy),
+		rest.Delete("/api/1/balancepolicies/:key", RemoveBalancePolicy),
+		/*
+			mediation fee
+		*/
+		rest.Get("/api/1/fees", GetFeeRates),
+		rest.Put("/api/1/fees", SetFeeRate),
+		rest.Get("/api/1/fees/charge/:token/:partner", GetChargeFee),
+		rest.Get("/api/1/fees/earnings", GetFeeEarnings),
+		rest.Get("/api/1/fees/mediations", GetMediationRecords),
+		rest.Get("/api/1/fees/:key", GetFeeRate),
+		rest.Delete("/api/1/fees/:key", RemoveFeeRate),
+		/*
+			payment schedules
+		*/
+		rest.Get("/api/1/schedules", GetSchedules),
+		rest.Post("/api/1/schedules", CreateSchedule),
+		rest.Get("/api/1/schedules/:id", GetSchedule),
+		rest.Put("/api/1/schedules/:id", UpdateSchedule),
+		rest.Delete("/api/1/schedules/:id", RemoveSchedule),
+		rest.Get("/api/1/schedules/:id/runs", GetScheduleRuns),
 	}
-	api.SetApp(router)
-	listen := fmt.Sprintf("%s:%d", Config.APIHost, Config.APIPort)
-	log.Crit(fmt.Sprintf("http listen and serve :%s", http.ListenAndServe(listen, api.MakeHandler())))
+	if Config.EnableDebugAPI {
+		routes = append(routes,
+			/*
+				for debug only
+			*/
+			rest.Get("/api/1/debug/balance/:token/:addr", Balance),
+			rest.Get("/api/1/debug/transfer/:token/:addr/:value", TransferToken),
+			rest.Get("/api/1/debug/ethbalance/:addr", EthBalance),
+			rest.Get("/api/1/debug/ethstatus", EthereumStatus),
+			rest.Get("/api/1/debug/force-unlock/:channel/:locksecrethash/:secrethash", ForceUnlock),
+			rest.Get("/api/1/debug/mission-control", GetMissionControl),
+			rest.Delete("/api/1/debug/mission-control", ResetMissionControl),
+		)
+	}
+	return routes
 }
 
 /*
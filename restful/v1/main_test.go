@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+func TestMakeRouter(t *testing.T) {
+	old := Config
+	defer func() {
+		Config = old
+	}()
+	for _, debug := range []bool{false, true} {
+		Config = &params.Config{EnableDebugAPI: debug}
+		if _, err := rest.MakeRouter(makeRoutes()...); err != nil {
+			t.Errorf("make router with debug api %v err %s", debug, err)
+		}
+	}
+}
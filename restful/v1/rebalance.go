@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+Rebalance moves balance from one of my channels to another by paying myself,
+the payment goes out through `from_channel` and comes back through `to_channel`.
+{"from_channel":"0x...","to_channel":"0x...","amount":10,"max_fee":1}
+*/
+func Rebalance(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		FromChannel string   `json:"from_channel"`
+		ToChannel   string   `json:"to_channel"`
+		Amount      *big.Int `json:"amount"`
+		MaxFee      *big.Int `json:"max_fee"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.FromChannel) != len(common.Hash{}.String()) || len(req.ToChannel) != len(common.Hash{}.String()) {
+		rest.Error(w, "invalid channel identifier", http.StatusBadRequest)
+		return
+	}
+	if req.Amount == nil || req.Amount.Cmp(big.NewInt(0)) <= 0 {
+		rest.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	res, err := RaidenAPI.Rebalance(common.HexToHash(req.FromChannel), common.HexToHash(req.ToChannel), req.Amount, req.MaxFee, params.MaxRequestTimeout)
+	if err == rerr.ErrTransferTimeout {
+		rest.Error(w, err.Error(), http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	err = w.WriteJson(res)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+/*
+ScheduleData is the request to create or update a payment schedule,
+`interval` is in seconds, `cron` is a cron expression in UTC, only one of them can be given.
+token_address, target_address and start_at cannot be changed once created.
+*/
+type ScheduleData struct {
+	Token    string   `json:"token_address"`
+	Target   string   `json:"target_address"`
+	Amount   *big.Int `json:"amount"`
+	Fee      *big.Int `json:"fee"`
+	Interval int64    `json:"interval"`
+	Cron     string   `json:"cron"`
+	StartAt  int64    `json:"start_at"`
+	EndAt    int64    `json:"end_at"`
+	MaxCount int      `json:"max_count"`
+	Status   string   `json:"status"` //active or paused, only for update
+}
+
+//ToSchedule converts the request to a schedule, addresses are checked only if `withAddress`
+func (d *ScheduleData) ToSchedule(withAddress bool) (s *models.PaymentSchedule, err error) {
+	s = &models.PaymentSchedule{
+		Amount:   d.Amount,
+		Fee:      d.Fee,
+		Interval: d.Interval,
+		Cron:     d.Cron,
+		StartAt:  d.StartAt,
+		EndAt:    d.EndAt,
+		MaxCount: d.MaxCount,
+		Status:   d.Status,
+	}
+	if !withAddress {
+		return
+	}
+	s.Token, err = utils.HexToAddress(d.Token)
+	if err != nil {
+		return
+	}
+	s.Target, err = utils.HexToAddress(d.Target)
+	return
+}
+
+/*
+CreateSchedule creates a schedule of payments
+{"token_address":"0x...","target_address":"0x...","amount":10,"interval":3600,"max_count":24}
+*/
+func CreateSchedule(w rest.ResponseWriter, r *rest.Request) {
+	req := &ScheduleData{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err := req.ToSchedule(true)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err = RaidenAPI.CreatePaymentSchedule(s)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	err = w.WriteJson(s)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+UpdateSchedule changes amount, fee, timing and limits of a schedule, or pauses and resumes it
+*/
+func UpdateSchedule(w rest.ResponseWriter, r *rest.Request) {
+	req := &ScheduleData{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, _ := req.ToSchedule(false)
+	s, err = RaidenAPI.UpdatePaymentSchedule(r.PathParam("id"), s)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.WriteJson(s)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetSchedules returns all the schedules
+*/
+func GetSchedules(w rest.ResponseWriter, r *rest.Request) {
+	ss, err := RaidenAPI.GetPaymentSchedules()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(ss)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetSchedule returns the schedule with `id`
+*/
+func GetSchedule(w rest.ResponseWriter, r *rest.Request) {
+	s, err := RaidenAPI.GetPaymentSchedule(r.PathParam("id"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(s)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+RemoveSchedule removes the schedule with `id` and its history
+*/
+func RemoveSchedule(w rest.ResponseWriter, r *rest.Request) {
+	err := RaidenAPI.RemovePaymentSchedule(r.PathParam("id"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+GetScheduleRuns returns history of payments of the schedule with `id`
+*/
+func GetScheduleRuns(w rest.ResponseWriter, r *rest.Request) {
+	runs, err := RaidenAPI.GetScheduleRuns(r.PathParam("id"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(runs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
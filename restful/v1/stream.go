@@ -0,0 +1,245 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
+	"github.com/ant0ine/go-json-rest/rest"
+	"golang.org/x/net/websocket"
+)
+
+//types of events pushed by the event stream
+const (
+	StreamEventSentTransfer     = "sent_transfer"
+	StreamEventReceivedTransfer = "received_transfer"
+	StreamEventStatus           = "status"
+	StreamEventChannelNew       = "channel_new"
+	StreamEventChannelDeposit   = "channel_deposit"
+	StreamEventChannelState     = "channel_state"
+	StreamEventChannelSettled   = "channel_settled"
+	StreamEventMissed           = "missed" //events before this are dropped from buffer, subscriber cannot resume from them
+)
+
+//streamBufferSize is how many recent events are kept for resuming
+const streamBufferSize = 1024
+
+/*
+StreamEvent is an event pushed to subscribers of the event stream,
+`Sequence` increases by one for each event, a subscriber can resume from the last sequence it received.
+*/
+type StreamEvent struct {
+	Sequence uint64      `json:"sequence"`
+	Type     string      `json:"type"`
+	Data     interface{} `json:"data"`
+}
+
+/*
+eventHub collects events from raiden and dispatches them to all the subscribers.
+it's the only consumer of `ModelDB.SentTransferChan`,`ModelDB.ReceivedTransferChan`,
+`RaidenService.EthConnectionStatus` and transport notify,
+so it should not work together with `mobile.API.Subscribe`.
+*/
+type eventHub struct {
+	lock         sync.Mutex
+	lastSequence uint64
+	events       []*StreamEvent //the most recent `streamBufferSize` events
+	subscribers  map[chan struct{}]bool
+	startOnce    sync.Once
+}
+
+var hub = newEventHub()
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers: make(map[chan struct{}]bool),
+	}
+}
+
+func (h *eventHub) publish(eventType string, data interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.lastSequence++
+	h.events = append(h.events, &StreamEvent{
+		Sequence: h.lastSequence,
+		Type:     eventType,
+		Data:     data,
+	})
+	if len(h.events) > streamBufferSize {
+		h.events = h.events[len(h.events)-streamBufferSize:]
+	}
+	for notify := range h.subscribers {
+		select {
+		case notify <- struct{}{}:
+		default:
+			//already notified
+		}
+	}
+}
+
+/*
+since returns all the buffered events after sequence `from`.
+if some of them have been dropped, a `StreamEventMissed` event is returned first.
+*/
+func (h *eventHub) since(from uint64) (events []*StreamEvent) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if from >= h.lastSequence {
+		return
+	}
+	first := h.lastSequence - uint64(len(h.events)) + 1
+	if from+1 < first {
+		events = append(events, &StreamEvent{
+			Sequence: first - 1,
+			Type:     StreamEventMissed,
+			Data:     first - 1 - from,
+		})
+		from = first - 1
+	}
+	return append(events, h.events[from+1-first:]...)
+}
+
+func (h *eventHub) subscribe() (notify chan struct{}, lastSequence uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	notify = make(chan struct{}, 1)
+	h.subscribers[notify] = true
+	return notify, h.lastSequence
+}
+
+func (h *eventHub) unsubscribe(notify chan struct{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.subscribers, notify)
+}
+
+func newChannelData(c *channeltype.Serialization) *ChannelData {
+	return &ChannelData{
+		ChannelAddress:      c.ChannelIdentifier.ChannelIdentifier.String(),
+		OpenBlockNumber:     c.ChannelIdentifier.OpenBlockNumber,
+		PartnerAddrses:      c.PartnerAddress().String(),
+		Balance:             c.OurBalance(),
+		PartnerBalance:      c.PartnerBalance(),
+		State:               c.State,
+		StateString:         c.State.String(),
+		SettleTimeout:       c.SettleTimeout,
+		TokenAddress:        c.TokenAddress().String(),
+		LockedAmount:        c.OurAmountLocked(),
+		PartnerLockedAmount: c.PartnerAmountLocked(),
+		RevealTimeout:       c.RevealTimeout,
+	}
+}
+
+//start collecting events from raiden, only once.
+func (h *eventHub) start() {
+	h.startOnce.Do(func() {
+		db := RaidenAPI.Raiden.GetDb()
+		channelCallback := func(eventType string) func(c *channeltype.Serialization) bool {
+			return func(c *channeltype.Serialization) bool {
+				h.publish(eventType, newChannelData(c))
+				return false
+			}
+		}
+		db.RegisterNewChannellCallback(channelCallback(StreamEventChannelNew))
+		db.RegisterChannelDepositCallback(channelCallback(StreamEventChannelDeposit))
+		db.RegisterChannelStateCallback(channelCallback(StreamEventChannelState))
+		db.RegisterChannelSettleCallback(channelCallback(StreamEventChannelSettled))
+		var xn <-chan netshare.Status
+		var err error
+		switch t := RaidenAPI.Raiden.Transport.(type) {
+		case *network.MatrixMixTransporter:
+			xn, err = t.GetNotify()
+		case *network.MixTransporter:
+			xn, err = t.GetNotify()
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("transport get notify err %s", err))
+		}
+		cs := ConnectionStatus{
+			XMPPStatus: netshare.Disconnected,
+			EthStatus:  netshare.Disconnected,
+		}
+		go func() {
+			for {
+				select {
+				case s := <-RaidenAPI.Raiden.EthConnectionStatus:
+					cs.EthStatus = s
+					cs.LastBlockTime = db.GetLastBlockNumberTime().Format(BlockTimeFormat)
+					h.publish(StreamEventStatus, cs)
+				case s := <-xn:
+					cs.XMPPStatus = s
+					cs.LastBlockTime = db.GetLastBlockNumberTime().Format(BlockTimeFormat)
+					h.publish(StreamEventStatus, cs)
+				case t := <-db.SentTransferChan:
+					h.publish(StreamEventSentTransfer, t)
+				case t := <-db.ReceivedTransferChan:
+					h.publish(StreamEventReceivedTransfer, t)
+				}
+			}
+		}()
+	})
+}
+
+/*
+EventStream streams events of this node over websocket, each message is a json `StreamEvent`.
+query `from` is the last sequence the client received, events after it are sent first if still buffered,
+without `from` only new events are sent.
+sequence restarts from 1 when this node restarts, so a `from` greater than the latest sequence means resume from the beginning.
+*/
+func EventStream(w rest.ResponseWriter, r *rest.Request) {
+	var from uint64
+	hasFrom := false
+	if s := r.URL.Query().Get("from"); s != "" {
+		var err error
+		from, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			rest.Error(w, fmt.Sprintf("invalid from %s", s), http.StatusBadRequest)
+			return
+		}
+		hasFrom = true
+	}
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			notify, lastSequence := hub.subscribe()
+			defer hub.unsubscribe(notify)
+			if !hasFrom {
+				from = lastSequence
+			} else if from > lastSequence {
+				from = 0
+			}
+			closed := make(chan struct{})
+			go func() {
+				//we don't expect any message from client, just detect close
+				var msg string
+				for {
+					if err := websocket.Message.Receive(ws, &msg); err != nil {
+						close(closed)
+						return
+					}
+				}
+			}()
+			for {
+				for _, e := range hub.since(from) {
+					err := websocket.JSON.Send(ws, e)
+					if err != nil {
+						log.Info(fmt.Sprintf("event stream send err %s", err))
+						return
+					}
+					from = e.Sequence
+				}
+				select {
+				case <-notify:
+				case <-closed:
+					return
+				}
+			}
+		},
+	}
+	server.ServeHTTP(w.(http.ResponseWriter), r.Request)
+}
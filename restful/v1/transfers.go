@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"math/big"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/ethereum/go-ethereum/common"
@@ -22,6 +25,13 @@ type TransferData struct {
 	Secret    string   `json:"secret"` // 当用户想使用自己指定的密码,而非随机密码时使用	// client can assign specific secret
 	Fee       *big.Int `json:"fee"`
 	IsDirect  bool     `json:"is_direct"`
+	// 支付标识和备注,会发送给接收方,但每一跳重新签名,中间节点可以修改
+	// payment identifier and memo delivered to the target, re-signed by every hop, so a mediator can change them
+	Identifier uint64 `json:"identifier"`
+	Memo       string `json:"memo"`
+	// 重试时使用同一个key,不会发起第二笔交易,也可以用Idempotency-Key header
+	// a retry with the same key never starts a second transfer, header Idempotency-Key works too
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 /*
@@ -58,6 +68,46 @@ func GetReceivedTransfers(w rest.ResponseWriter, r *rest.Request) {
 	}
 }
 
+/*
+GetSentTransfersByIdentifier returns list of sent transfer with payment identifier `identifier`
+*/
+func GetSentTransfersByIdentifier(w rest.ResponseWriter, r *rest.Request) {
+	identifier, err := strconv.ParseUint(r.PathParam("identifier"), 10, 64)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trs, err := RaidenAPI.GetSentTransfersByIdentifier(identifier)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(trs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetReceivedTransfersByIdentifier returns list of received transfer with payment identifier `identifier`
+*/
+func GetReceivedTransfersByIdentifier(w rest.ResponseWriter, r *rest.Request) {
+	identifier, err := strconv.ParseUint(r.PathParam("identifier"), 10, 64)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trs, err := RaidenAPI.GetReceivedTransfersByIdentifier(identifier)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(trs)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
 /*
 Transfers is the api of /transfer/:token/:partner
 */
@@ -104,7 +154,23 @@ func Transfers(w rest.ResponseWriter, r *rest.Request) {
 		rest.Error(w, "Invalid secret", http.StatusBadRequest)
 		return
 	}
-	err = RaidenAPI.Transfer(tokenAddr, req.Amount, req.Fee, targetAddr, common.HexToHash(req.Secret), params.MaxRequestTimeout, req.IsDirect)
+	if len(req.Memo) > params.MaxMemoLength {
+		rest.Error(w, "Invalid memo", http.StatusBadRequest)
+		return
+	}
+	req.IdempotencyKey = idempotencyKey(r, req.IdempotencyKey)
+	if req.IdempotencyKey == "" {
+		err = RaidenAPI.TransferAndWait(tokenAddr, req.Amount, req.Fee, targetAddr, common.HexToHash(req.Secret), params.MaxRequestTimeout, req.IsDirect, req.Identifier, req.Memo)
+	} else {
+		var rec *models.IdempotencyRecord
+		rec, err = RaidenAPI.TransferIdempotent(req.IdempotencyKey, tokenAddr, req.Amount, req.Fee, targetAddr, common.HexToHash(req.Secret), params.MaxRequestTimeout, req.IsDirect, req.Identifier, req.Memo)
+		if err == rerr.ErrTransferTimeout {
+			err = rerr.ErrRequestInProgress
+		}
+		if writeIdempotencyError(w, rec, err) {
+			return
+		}
+	}
 	if err != nil {
 		rest.Error(w, err.Error(), http.StatusConflict)
 		return
@@ -117,3 +183,92 @@ func Transfers(w rest.ResponseWriter, r *rest.Request) {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
 	}
 }
+
+/*
+GetPendingTransfers returns status of all transfers not finished,
+no matter I'm the initiator, mediator or target.
+*/
+func GetPendingTransfers(w rest.ResponseWriter, r *rest.Request) {
+	statuses, err := RaidenAPI.GetPendingTransfers()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(statuses)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+GetTransferStatus returns status of the pending transfer with `locksecrethash`
+*/
+func GetTransferStatus(w rest.ResponseWriter, r *rest.Request) {
+	lockSecretHash := common.HexToHash(r.PathParam("locksecrethash"))
+	if lockSecretHash == utils.EmptyHash {
+		rest.Error(w, "Invalid lockSecretHash", http.StatusBadRequest)
+		return
+	}
+	statuses, err := RaidenAPI.GetTransferStatus(lockSecretHash)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	err = w.WriteJson(statuses)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+CancelTransfer cancels the transfer with `locksecrethash` initiated by this node.
+409 means the secret is already revealed, this transfer cannot be canceled anymore.
+*/
+func CancelTransfer(w rest.ResponseWriter, r *rest.Request) {
+	lockSecretHash := common.HexToHash(r.PathParam("locksecrethash"))
+	if lockSecretHash == utils.EmptyHash {
+		rest.Error(w, "Invalid lockSecretHash", http.StatusBadRequest)
+		return
+	}
+	err := RaidenAPI.CancelTransfer(lockSecretHash)
+	if err == rerr.ErrTransferNotFound {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+/*
+GetRoutes returns the routes a transfer of `amount` to `target` would try now, without sending anything.
+empty list means `target` is not reachable at the moment.
+*/
+func GetRoutes(w rest.ResponseWriter, r *rest.Request) {
+	tokenAddr, err := utils.HexToAddress(r.PathParam("token"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	targetAddr, err := utils.HexToAddress(r.PathParam("target"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	amount, ok := new(big.Int).SetString(r.URL.Query().Get("amount"), 0)
+	if !ok || amount.Cmp(utils.BigInt0) <= 0 {
+		rest.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	routes, err := RaidenAPI.GetRoutes(tokenAddr, targetAddr, amount)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	err = w.WriteJson(routes)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+/*
+GetWebhooks returns all the webhook targets, secrets are not included.
+*/
+func GetWebhooks(w rest.ResponseWriter, r *rest.Request) {
+	targets, err := RaidenAPI.GetWebhooks()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(targets)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+AddWebhook registers a webhook target
+{"url":"https://example.com/hook","secret":"xxx","events":["received_transfer"]}
+empty `events` means all events.
+*/
+func AddWebhook(w rest.ResponseWriter, r *rest.Request) {
+	type Req struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	}
+	req := &Req{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target, err := RaidenAPI.AddWebhook(req.URL, req.Secret, req.Events)
+	if err != nil {
+		log.Error(err.Error())
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	err = w.WriteJson(target)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+RemoveWebhook removes a webhook target and all its undelivered events
+*/
+func RemoveWebhook(w rest.ResponseWriter, r *rest.Request) {
+	id, err := strconv.Atoi(r.PathParam("id"))
+	if err != nil {
+		rest.Error(w, "argument error", http.StatusBadRequest)
+		return
+	}
+	err = RaidenAPI.RemoveWebhook(id)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
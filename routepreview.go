@@ -0,0 +1,88 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+RouteInfo is a candidate first hop of a mediated transfer,
+it's the same `route.State` a transfer would try, in the same order.
+`Paths` are the cheapest full paths behind this hop, at most params.RoutePreviewPaths of all the hops together.
+they are advisory only: a transfer chooses just its first hop, every hop after it chooses the next one by itself,
+so the path actually taken may be none of them.
+*/
+type RouteInfo struct {
+	HopNode           common.Address `json:"hop_node"`
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	AvailableBalance  *big.Int       `json:"available_balance"`
+	Fee               *big.Int       `json:"fee"`       //fee charged by hop node
+	TotalFee          *big.Int       `json:"total_fee"` //fee of the whole path through this hop
+	IsOnline          bool           `json:"is_online"`
+	DeviceType        string         `json:"device_type"`
+	Paths             []*graph.Path  `json:"paths"` //advisory full paths through this hop, the cheapest first
+}
+
+/*
+getRoutes returns the routes a mediated transfer of `amount` to `target` would try now,
+without any side effect. must be called in the main loop.
+*/
+func (rs *RaidenService) getRoutes(tokenAddress, target common.Address, amount *big.Int) (routes []*RouteInfo, err error) {
+	if rs.Config.IsMeshNetwork {
+		err = errors.New("no mediated transfer on mesh only network")
+		return
+	}
+	g := rs.getToken2ChannelGraph(tokenAddress)
+	if g == nil {
+		err = fmt.Errorf("token %s doesn't exist", tokenAddress.String())
+		return
+	}
+	routes = []*RouteInfo{}
+	hop2route := make(map[common.Address]*RouteInfo)
+	for _, r := range g.GetBestRoutes(rs.Protocol, rs.NodeAddress, target, amount, graph.EmptyExlude, rs) {
+		deviceType, isOnline := rs.Protocol.GetNetworkStatus(r.HopNode())
+		ri := &RouteInfo{
+			HopNode:           r.HopNode(),
+			ChannelIdentifier: r.ChannelIdentifier,
+			AvailableBalance:  r.AvailableBalance(),
+			Fee:               r.Fee,
+			TotalFee:          r.TotalFee,
+			IsOnline:          isOnline,
+			DeviceType:        deviceType,
+			Paths:             []*graph.Path{},
+		}
+		routes = append(routes, ri)
+		hop2route[ri.HopNode] = ri
+	}
+	for _, p := range g.GetKShortestPaths(rs.NodeAddress, target, amount, params.RoutePreviewPaths, graph.EmptyExlude, rs) {
+		//paths through a hop the transfer would not try are useless
+		if ri := hop2route[p.Hops[0]]; ri != nil {
+			ri.Paths = append(ri.Paths, p)
+		}
+	}
+	return
+}
+
+/*
+GetRoutes returns the routes a mediated transfer of `amount` to `target` would try now, the best first,
+empty means the target is not reachable at the moment.
+*/
+func (r *RaidenAPI) GetRoutes(tokenAddress, target common.Address, amount *big.Int) (routes []*RouteInfo, err error) {
+	if amount == nil || amount.Cmp(utils.BigInt0) <= 0 {
+		err = errors.New("amount should be positive")
+		return
+	}
+	result := r.Raiden.routesClient(tokenAddress, target, amount)
+	err = <-result.Result
+	if err != nil {
+		return
+	}
+	routes, _ = result.Tag.([]*RouteInfo)
+	return
+}
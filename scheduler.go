@@ -0,0 +1,391 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+/*
+schedulerService fires payments of schedules when they are due, one payment of a schedule at a time.
+payments due when the node is down are marked missed and paid after the node restarts,
+time of payments is the wall clock.
+*/
+type schedulerService struct {
+	db            *models.ModelDB
+	quitChan      chan struct{}
+	wakeChan      chan struct{}
+	pay           func(s *models.PaymentSchedule) error //blocks until the transfer finishes
+	checkInterval time.Duration
+	retryInterval time.Duration
+	lock          sync.Mutex      //protects schedules in db and running
+	running       map[string]bool //schedules with a payment going on
+}
+
+func newSchedulerService(db *models.ModelDB, quitChan chan struct{}, pay func(s *models.PaymentSchedule) error) *schedulerService {
+	return &schedulerService{
+		db:            db,
+		quitChan:      quitChan,
+		wakeChan:      make(chan struct{}, 1),
+		pay:           pay,
+		checkInterval: params.ScheduleCheckInterval,
+		retryInterval: params.ScheduleRetryInterval,
+		running:       make(map[string]bool),
+	}
+}
+
+/*
+start marks payments going on when the node stopped as interrupted, they are never retried,
+because the transfer may have been finished.
+*/
+func (ss *schedulerService) start() {
+	ss.lock.Lock()
+	schedules, err := ss.db.GetPaymentSchedules()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetPaymentSchedules err %s", err))
+	}
+	for _, s := range schedules {
+		runs, err := ss.db.GetScheduleRuns(s.ID)
+		if err != nil {
+			log.Error(fmt.Sprintf("GetScheduleRuns %s err %s", s.ID, err))
+			continue
+		}
+		for _, r := range runs {
+			if r.Status == models.ScheduleRunPending {
+				r.Status = models.ScheduleRunInterrupted
+				r.Error = "node stopped while transfer is going on"
+				ss.saveRun(r)
+			}
+		}
+	}
+	ss.lock.Unlock()
+	go ss.loop()
+}
+
+func (ss *schedulerService) wake() {
+	select {
+	case ss.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+func (ss *schedulerService) loop() {
+	ticker := time.NewTicker(ss.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ss.quitChan:
+			return
+		case <-ss.wakeChan:
+		case <-ticker.C:
+		}
+		ss.runDue(time.Now())
+	}
+}
+
+//runDue records payments due before `now` and starts the first payment waiting of each schedule
+func (ss *schedulerService) runDue(now time.Time) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	schedules, err := ss.db.GetPaymentSchedules()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetPaymentSchedules err %s", err))
+		return
+	}
+	for _, s := range schedules {
+		if s.Status == models.ScheduleStatusPaused {
+			continue
+		}
+		ss.recordDueRuns(s, now.Unix())
+		if ss.running[s.ID] {
+			continue
+		}
+		runs, err := ss.db.GetScheduleRuns(s.ID)
+		if err != nil {
+			log.Error(fmt.Sprintf("GetScheduleRuns %s err %s", s.ID, err))
+			continue
+		}
+		for _, r := range runs {
+			if r.Status == models.ScheduleRunWaiting ||
+				(r.Status == models.ScheduleRunFailed && r.Attempts < params.ScheduleMaxAttempts &&
+					now.Unix() >= r.LastAttemptAt+int64(ss.retryInterval/time.Second)) {
+				ss.startRun(s, r, now.Unix())
+				break
+			}
+		}
+	}
+}
+
+//recordDueRuns creates a run for every payment of `s` due before `now`
+func (ss *schedulerService) recordDueRuns(s *models.PaymentSchedule, now int64) {
+	changed := false
+	for s.Status == models.ScheduleStatusActive && s.NextRunAt <= now {
+		changed = true
+		if s.EndAt > 0 && s.NextRunAt > s.EndAt {
+			s.Status = models.ScheduleStatusFinished
+			break
+		}
+		s.Count++
+		ss.saveRun(&models.ScheduleRun{
+			ScheduleID: s.ID,
+			Seq:        s.Count,
+			DueAt:      s.NextRunAt,
+			Missed:     now-s.NextRunAt > 2*int64(ss.checkInterval/time.Second),
+			Status:     models.ScheduleRunWaiting,
+		})
+		s.NextRunAt = nextRunAt(s, s.NextRunAt)
+		if (s.MaxCount > 0 && s.Count >= s.MaxCount) || s.NextRunAt == 0 {
+			s.Status = models.ScheduleStatusFinished
+		}
+	}
+	if changed {
+		ss.saveSchedule(s)
+	}
+}
+
+func (ss *schedulerService) startRun(s *models.PaymentSchedule, r *models.ScheduleRun, now int64) {
+	r.Status = models.ScheduleRunPending
+	r.Attempts++
+	r.LastAttemptAt = now
+	ss.saveRun(r)
+	ss.running[s.ID] = true
+	log.Info(fmt.Sprintf("scheduled payment %s of %s, amount=%s target=%s", r.ID, s.ID, s.Amount, utils.APex(s.Target)))
+	go func() {
+		err := ss.pay(s)
+		ss.lock.Lock()
+		defer ss.lock.Unlock()
+		delete(ss.running, s.ID)
+		if err != nil {
+			log.Error(fmt.Sprintf("scheduled payment %s err %s", r.ID, err))
+			r.Status = models.ScheduleRunFailed
+			r.Error = err.Error()
+		} else {
+			r.Status = models.ScheduleRunSuccess
+			r.Error = ""
+		}
+		ss.saveRun(r)
+		//the next payment may be waiting
+		ss.wake()
+	}()
+}
+
+func (ss *schedulerService) saveRun(r *models.ScheduleRun) {
+	err := ss.db.SaveScheduleRun(r)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveScheduleRun %s err %s", r.ID, err))
+	}
+}
+
+func (ss *schedulerService) saveSchedule(s *models.PaymentSchedule) {
+	err := ss.db.UpdatePaymentSchedule(s)
+	if err != nil {
+		log.Error(fmt.Sprintf("UpdatePaymentSchedule %s err %s", s.ID, err))
+	}
+}
+
+/*
+nextRunAt returns the time of the payment after the one due at `last`,
+0 if there is no more payment.
+*/
+func nextRunAt(s *models.PaymentSchedule, last int64) int64 {
+	if s.Interval > 0 {
+		return last + s.Interval
+	}
+	c, err := utils.ParseCron(s.Cron)
+	if err != nil {
+		return 0
+	}
+	next := c.Next(time.Unix(last, 0).UTC())
+	if next.IsZero() {
+		return 0
+	}
+	return next.Unix()
+}
+
+//firstRunAt returns the time of the first payment at or after `StartAt`
+func firstRunAt(s *models.PaymentSchedule) int64 {
+	if s.Interval > 0 {
+		return s.StartAt
+	}
+	return nextRunAt(s, s.StartAt-1)
+}
+
+//validateSchedule checks timing of `s`, exactly one of interval and cron must be given
+func validateSchedule(s *models.PaymentSchedule) error {
+	if s.Amount == nil || s.Amount.Cmp(utils.BigInt0) <= 0 {
+		return rerr.ErrInvalidAmount
+	}
+	if s.Fee == nil {
+		s.Fee = utils.BigInt0
+	}
+	if s.Fee.Cmp(utils.BigInt0) < 0 {
+		return errors.New("invalid fee")
+	}
+	if (s.Interval == 0) == (s.Cron == "") {
+		return errors.New("one and only one of interval and cron must be given")
+	}
+	if s.Interval != 0 && s.Interval < params.MinScheduleInterval {
+		return fmt.Errorf("interval must be at least %d seconds", params.MinScheduleInterval)
+	}
+	if s.Cron != "" {
+		if _, err := utils.ParseCron(s.Cron); err != nil {
+			return err
+		}
+	}
+	if s.EndAt != 0 && s.EndAt < s.StartAt {
+		return errors.New("end_at must be after start_at")
+	}
+	if s.MaxCount < 0 {
+		return errors.New("invalid max_count")
+	}
+	return nil
+}
+
+/*
+CreatePaymentSchedule saves a new schedule of payments, `StartAt` is now if it's 0.
+*/
+func (r *RaidenAPI) CreatePaymentSchedule(s *models.PaymentSchedule) (*models.PaymentSchedule, error) {
+	if !r.hasToken(s.Token) {
+		return nil, errors.New("token not exist")
+	}
+	if s.Target == r.Raiden.NodeAddress {
+		return nil, errors.New("cannot pay to myself")
+	}
+	if s.StartAt == 0 {
+		s.StartAt = time.Now().Unix()
+	}
+	err := validateSchedule(s)
+	if err != nil {
+		return nil, err
+	}
+	s.ID = utils.RandomString(16)
+	s.Count = 0
+	s.Status = models.ScheduleStatusActive
+	s.NextRunAt = firstRunAt(s)
+	if s.NextRunAt == 0 {
+		return nil, errors.New("cron expression never matches")
+	}
+	s.CreatedAt = time.Now().Unix()
+	ss := r.Raiden.scheduler
+	ss.lock.Lock()
+	err = ss.db.NewPaymentSchedule(s)
+	ss.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ss.wake()
+	return s, nil
+}
+
+/*
+UpdatePaymentSchedule changes amount, fee, timing and limits of schedule `id`, and pauses or resumes it by `u.Status`.
+token, target and start of a schedule cannot be changed.
+*/
+func (r *RaidenAPI) UpdatePaymentSchedule(id string, u *models.PaymentSchedule) (*models.PaymentSchedule, error) {
+	ss := r.Raiden.scheduler
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	s, err := ss.db.GetPaymentSchedule(id)
+	if err != nil {
+		return nil, errors.New("payment schedule not found")
+	}
+	if u.Status != "" && u.Status != models.ScheduleStatusActive && u.Status != models.ScheduleStatusPaused {
+		return nil, fmt.Errorf("status must be %s or %s", models.ScheduleStatusActive, models.ScheduleStatusPaused)
+	}
+	u.StartAt = s.StartAt
+	err = validateSchedule(u)
+	if err != nil {
+		return nil, err
+	}
+	timingChanged := u.Interval != s.Interval || u.Cron != s.Cron
+	s.Amount, s.Fee, s.Interval, s.Cron, s.EndAt, s.MaxCount = u.Amount, u.Fee, u.Interval, u.Cron, u.EndAt, u.MaxCount
+	if timingChanged {
+		if s.Count == 0 {
+			s.NextRunAt = firstRunAt(s)
+		} else {
+			runs, err := ss.db.GetScheduleRuns(id)
+			if err != nil {
+				return nil, err
+			}
+			s.NextRunAt = nextRunAt(s, runs[len(runs)-1].DueAt)
+		}
+	}
+	if u.Status != "" && s.Status != u.Status {
+		if s.Status == models.ScheduleStatusPaused {
+			//payments due while paused are skipped
+			now := time.Now().Unix()
+			for s.NextRunAt != 0 && s.NextRunAt < now {
+				s.NextRunAt = nextRunAt(s, s.NextRunAt)
+			}
+		}
+		s.Status = u.Status
+	}
+	//limits may be raised
+	if s.Status == models.ScheduleStatusFinished || s.Status == models.ScheduleStatusActive {
+		s.Status = models.ScheduleStatusActive
+		if (s.MaxCount > 0 && s.Count >= s.MaxCount) || s.NextRunAt == 0 || (s.EndAt > 0 && s.NextRunAt > s.EndAt) {
+			s.Status = models.ScheduleStatusFinished
+		}
+	}
+	err = ss.db.UpdatePaymentSchedule(s)
+	if err != nil {
+		return nil, err
+	}
+	ss.wake()
+	return s, nil
+}
+
+//GetPaymentSchedule returns the schedule with `id`
+func (r *RaidenAPI) GetPaymentSchedule(id string) (*models.PaymentSchedule, error) {
+	s, err := r.Raiden.db.GetPaymentSchedule(id)
+	if err != nil {
+		return nil, errors.New("payment schedule not found")
+	}
+	return s, nil
+}
+
+//GetPaymentSchedules returns all the schedules
+func (r *RaidenAPI) GetPaymentSchedules() ([]*models.PaymentSchedule, error) {
+	return r.Raiden.db.GetPaymentSchedules()
+}
+
+//GetScheduleRuns returns history of payments of schedule `id`
+func (r *RaidenAPI) GetScheduleRuns(id string) ([]*models.ScheduleRun, error) {
+	_, err := r.GetPaymentSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Raiden.db.GetScheduleRuns(id)
+}
+
+//RemovePaymentSchedule removes schedule `id` and its history, it fails if a payment of it is going on.
+func (r *RaidenAPI) RemovePaymentSchedule(id string) error {
+	ss := r.Raiden.scheduler
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	if ss.running[id] {
+		return errors.New("a payment of this schedule is going on, try later")
+	}
+	err := ss.db.RemovePaymentSchedule(id)
+	if err != nil {
+		return errors.New("payment schedule not found")
+	}
+	return nil
+}
+
+//payScheduled sends a payment of `s` through the api request pipeline and waits for the result
+func (rs *RaidenService) payScheduled(s *models.PaymentSchedule) error {
+	if rs.StopCreateNewTransfers {
+		return rerr.ErrStopCreateNewTransfer
+	}
+	result := rs.transferAsyncClient(s.Token, s.Amount, s.Fee, s.Target, utils.EmptyHash, false, &encoding.PaymentData{})
+	return <-result.Result
+}
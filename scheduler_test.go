@@ -0,0 +1,132 @@
+package smartraiden
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+func newTestSchedulerService(t *testing.T, pay func(s *models.PaymentSchedule) error) *schedulerService {
+	db := setupDb(t, "testscheduler.db")
+	return newSchedulerService(db, make(chan struct{}), pay)
+}
+
+//waitRunning waits until no payment of `ss` is going on
+func waitRunning(ss *schedulerService) {
+	for i := 0; i < 100; i++ {
+		ss.lock.Lock()
+		n := len(ss.running)
+		ss.lock.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSchedulerMissedAndRetry(t *testing.T) {
+	fail := true
+	paid := 0
+	ss := newTestSchedulerService(t, func(s *models.PaymentSchedule) error {
+		if fail {
+			return errors.New("no available route")
+		}
+		paid++
+		return nil
+	})
+	defer ss.db.CloseDB()
+	start := time.Now().Add(-time.Hour).Unix()
+	s := &models.PaymentSchedule{
+		ID:        "s1",
+		Token:     utils.NewRandomAddress(),
+		Target:    utils.NewRandomAddress(),
+		Amount:    big.NewInt(10),
+		Fee:       big.NewInt(0),
+		Interval:  1200,
+		StartAt:   start,
+		MaxCount:  3,
+		NextRunAt: start,
+		Status:    models.ScheduleStatusActive,
+	}
+	err := ss.db.NewPaymentSchedule(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the node was down for an hour, three payments are missed
+	now := time.Now()
+	ss.runDue(now)
+	waitRunning(ss)
+	runs, err := ss.db.GetScheduleRuns(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 3, len(runs))
+	for _, r := range runs {
+		assert(t, true, r.Missed)
+	}
+	assert(t, models.ScheduleRunFailed, runs[0].Status)
+	assert(t, models.ScheduleRunWaiting, runs[1].Status)
+	s, err = ss.db.GetPaymentSchedule(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, models.ScheduleStatusFinished, s.Status)
+	//the failed one is retried after retryInterval, others are paid one by one
+	fail = false
+	ss.runDue(now)
+	waitRunning(ss)
+	ss.runDue(now)
+	waitRunning(ss)
+	ss.runDue(now.Add(ss.retryInterval))
+	waitRunning(ss)
+	runs, err = ss.db.GetScheduleRuns(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 3, paid)
+	for _, r := range runs {
+		assert(t, models.ScheduleRunSuccess, r.Status)
+	}
+	assert(t, 2, runs[0].Attempts)
+}
+
+func TestSchedulerInterrupted(t *testing.T) {
+	ss := newTestSchedulerService(t, func(s *models.PaymentSchedule) error {
+		return nil
+	})
+	defer ss.db.CloseDB()
+	r := &models.ScheduleRun{
+		ScheduleID: "s1",
+		Seq:        1,
+		Status:     models.ScheduleRunPending,
+	}
+	err := ss.db.NewPaymentSchedule(&models.PaymentSchedule{ID: "s1", Status: models.ScheduleStatusFinished})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ss.saveRun(r)
+	ss.start()
+	close(ss.quitChan)
+	runs, err := ss.db.GetScheduleRuns("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, models.ScheduleRunInterrupted, runs[0].Status)
+}
+
+func TestNextRunAt(t *testing.T) {
+	s := &models.PaymentSchedule{
+		Cron:    "0 * * * *",
+		StartAt: time.Date(2018, 10, 17, 10, 0, 0, 0, time.UTC).Unix(),
+	}
+	assert(t, s.StartAt, firstRunAt(s))
+	assert(t, s.StartAt+3600, nextRunAt(s, s.StartAt))
+	s.Cron = ""
+	s.Interval = 600
+	assert(t, s.StartAt, firstRunAt(s))
+	assert(t, s.StartAt+600, nextRunAt(s, s.StartAt))
+}
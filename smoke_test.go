@@ -10,7 +10,14 @@ import (
 
 	"fmt"
 
+	"os"
+	"path"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts/test/tokens/tokenerc223approve"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
@@ -24,6 +31,30 @@ var x = big.NewInt(0)
 func assert(t *testing.T, expected, actual interface{}, msgAndArgs ...interface{}) bool {
 	return assert2.EqualValues(t, expected, actual, msgAndArgs...)
 }
+
+//setupDb opens an empty db named `name` in the temp dir, caller should close it
+func setupDb(t *testing.T, name string) *models.ModelDB {
+	dbPath := path.Join(os.TempDir(), name)
+	os.Remove(dbPath)
+	os.Remove(dbPath + ".lock")
+	db, err := models.OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+//newTestChannel makes an open channel which needs no blockchain
+func newTestChannel(ourAddress, partnerAddress, tokenAddress common.Address, ourBalance, partnerBalance int64) *channel.Channel {
+	ourState := channel.NewChannelEndState(ourAddress, big.NewInt(ourBalance), nil, mtree.EmptyTree)
+	partnerState := channel.NewChannelEndState(partnerAddress, big.NewInt(partnerBalance), nil, mtree.EmptyTree)
+	c, err := channel.NewChannel(ourState, partnerState, &channel.ExternalState{}, tokenAddress,
+		&contracts.ChannelUniqueID{ChannelIdentifier: utils.NewRandomHash()}, 5, 30)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
 func deployAToken(t *testing.T, raiden *RaidenService) (addr common.Address) {
 	n := new(big.Int)
 	n.SetBytes(raiden.NodeAddress[:])
@@ -105,6 +105,7 @@ type StateManager struct {
 	Identifier          common.Hash //transfer identifier
 	Name                string
 	LastReceivedMessage encoding.SignedMessager
+	LastMessage         string //the last message received for this transfer, only for status report
 }
 
 //MessageTag for save and restore
@@ -5,6 +5,7 @@ import (
 
 	"math/big"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -35,6 +36,7 @@ type EventTransferSentSuccess struct {
 	Target            common.Address
 	ChannelIdentifier common.Hash
 	Token             common.Address
+	encoding.PaymentData
 }
 
 /*
@@ -64,6 +66,7 @@ type EventTransferReceivedSuccess struct {
 	Amount            *big.Int
 	Initiator         common.Address
 	ChannelIdentifier common.Hash
+	encoding.PaymentData
 }
 
 func init() {
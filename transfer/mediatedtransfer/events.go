@@ -5,6 +5,7 @@ import (
 
 	"math/big"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -18,6 +19,7 @@ type EventSendMediatedTransfer struct {
 	Expiration     int64
 	Receiver       common.Address
 	Fee            *big.Int // target should get amount-fee.
+	encoding.PaymentData
 	/*
 		which channel received a mediated transfer and then I have to send another mediated transfer,
 		因为哪个 channel 收到了 MediatedTransfer, 导致我需要发送新的 Transfer.
@@ -40,6 +42,7 @@ func NewEventSendMediatedTransfer(transfer *LockedTransferState, receiver common
 		Expiration:     transfer.Expiration,
 		Receiver:       receiver,
 		Fee:            transfer.Fee,
+		PaymentData:    transfer.PaymentData,
 	}
 }
 
@@ -198,6 +201,7 @@ type EventUnlockFailed struct {
 //EventWithdrawSuccess emitted when a lock withdraw succeded.
 type EventWithdrawSuccess struct {
 	LockSecretHash common.Hash
+	Pair           *MediationPairState //the pair whose payer has paid me, only by a mediator, whose state may be removed when the event is handled
 }
 
 /*
@@ -370,9 +370,24 @@ func TestCancelTransfer(t *testing.T) {
 
 	events := sm.Dispatch(stateChange)
 	assert(t, len(events), 1)
-	_, ok := events[0].(*transfer.EventTransferSentFailed)
+	ev, ok := events[0].(*transfer.EventTransferSentFailed)
 	assert(t, ok, true)
-	assert(t, sm.CurrentState == nil, true)
+	assert(t, ev.LockSecretHash, currentState.LockSecretHash)
+	//the lock is still on the route, keep the state until it's removed or expired.
+	assert(t, sm.CurrentState != nil, true)
+	assert(t, currentState.Canceled, true)
+	assert(t, currentState.Route != nil, true)
+
+	events = sm.Dispatch(&mediatedtransfer.ReceiveSecretRequestStateChange{
+		Amount:         amount,
+		LockSecretHash: currentState.LockSecretHash,
+		Sender:         targetAddress,
+	})
+	assert(t, len(events), 0)
+	assert(t, currentState.RevealSecret == nil, true)
+
+	events = sm.Dispatch(stateChange)
+	assert(t, len(events), 0)
 }
 
 func assertStateEqual(t *testing.T, currentState, beforeState *mediatedtransfer.InitiatorState) {
@@ -390,3 +405,77 @@ func assertStateEqual(t *testing.T, currentState, beforeState *mediatedtransfer.
 	assert(t, currentState.BlockNumber, beforeState.BlockNumber)
 	//assert(t, currentState, beforeState)
 }
+
+func TestMultiPartPayment(t *testing.T) {
+	amount := big.NewInt(10)
+	target := utest.HOP1
+	routes := []*route.State{
+		utest.MakeRoute(utest.HOP2, big.NewInt(6), utest.UnitSettleTimeout, utest.UnitRevealTimeout, 0, utils.NewRandomHash()),
+		utest.MakeRoute(utest.HOP3, big.NewInt(4), utest.UnitSettleTimeout, utest.UnitRevealTimeout, 0, utils.NewRandomHash()),
+	}
+	secret := utils.NewRandomHash()
+	st := &mediatedtransfer.ActionInitMultiPartInitiatorStateChange{
+		OurAddress: utest.ADDR,
+		Tranfer: &mediatedtransfer.LockedTransferState{
+			Amount:       amount,
+			TargetAmount: amount,
+			Initiator:    utest.ADDR,
+			Target:       target,
+			Token:        utest.UnitTokenAddress,
+			Fee:          utils.BigInt0,
+		},
+		Routes:         routes,
+		Amounts:        []*big.Int{big.NewInt(6), big.NewInt(4)},
+		BlockNumber:    utest.UnitBlockNumber,
+		Secret:         secret,
+		LockSecretHash: utils.ShaSecret(secret[:]),
+	}
+	it := MultiPartStateTransition(nil, st)
+	state := it.NewState.(*mediatedtransfer.MultiPartInitiatorState)
+	assert(t, len(it.Events), 2)
+	for i, e := range it.Events {
+		mtr := e.(*mediatedtransfer.EventSendMediatedTransfer)
+		assert(t, mtr.Receiver, routes[i].HopNode())
+		assert(t, mtr.Amount, st.Amounts[i])
+		assert(t, mtr.TotalAmount, amount)
+		assert(t, mtr.LockSecretHash, st.LockSecretHash)
+	}
+
+	//secret request must be for the total amount
+	it = MultiPartStateTransition(state, &mediatedtransfer.ReceiveSecretRequestStateChange{
+		Amount:         big.NewInt(6),
+		LockSecretHash: st.LockSecretHash,
+		Sender:         target,
+	})
+	assert(t, len(it.Events), 0)
+	it = MultiPartStateTransition(state, &mediatedtransfer.ReceiveSecretRequestStateChange{
+		Amount:         amount,
+		LockSecretHash: st.LockSecretHash,
+		Sender:         target,
+	})
+	assert(t, len(it.Events), 1)
+	assert(t, it.Events[0].(*mediatedtransfer.EventSendRevealSecret).Receiver, target)
+
+	//the first unlocked part finishes the payment
+	it = MultiPartStateTransition(state, &mediatedtransfer.ReceiveSecretRevealStateChange{
+		Secret: secret,
+		Sender: utest.HOP2,
+	})
+	assert(t, it.NewState != nil, true)
+	var success *transfer.EventTransferSentSuccess
+	for _, e := range it.Events {
+		if e2, ok := e.(*transfer.EventTransferSentSuccess); ok {
+			success = e2
+		}
+	}
+	assert(t, success != nil, true)
+	assert(t, success.Amount, amount)
+
+	//state manager is removed after all the parts are unlocked
+	it = MultiPartStateTransition(state, &mediatedtransfer.ReceiveSecretRevealStateChange{
+		Secret: secret,
+		Sender: utest.HOP3,
+	})
+	assert(t, it.NewState == nil, true)
+	assert(t, len(it.Events), 2)
+}
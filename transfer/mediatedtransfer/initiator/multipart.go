@@ -0,0 +1,338 @@
+package initiator
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	mt "github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/mediator"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//NameMultiPartInitiatorTransition name for state manager of a multi-part payment
+const NameMultiPartInitiatorTransition = "MultiPartInitiatorTransition"
+
+/*
+handleMultiPartInit sends one MediatedTransfer for each part,
+every part carries the total amount so the target knows when it has received all of them.
+*/
+func handleMultiPartInit(st *mt.ActionInitMultiPartInitiatorStateChange) *transfer.TransitionResult {
+	state := &mt.MultiPartInitiatorState{
+		OurAddress:     st.OurAddress,
+		Transfer:       st.Tranfer,
+		BlockNumber:    st.BlockNumber,
+		LockSecretHash: st.LockSecretHash,
+		Secret:         st.Secret,
+		Db:             st.Db,
+	}
+	state.Transfer.TotalAmount = new(big.Int).Set(state.Transfer.TargetAmount)
+	var events []transfer.Event
+	for i, r := range st.Routes {
+		lockExpiration := state.BlockNumber + int64(r.SettleTimeout())
+		if lockExpiration > state.Transfer.Expiration && state.Transfer.Expiration != 0 {
+			lockExpiration = state.Transfer.Expiration
+		}
+		tr := &mt.LockedTransferState{
+			TargetAmount:   st.Amounts[i],
+			Amount:         new(big.Int).Add(st.Amounts[i], r.TotalFee),
+			Token:          state.Transfer.Token,
+			Initiator:      state.Transfer.Initiator,
+			Target:         state.Transfer.Target,
+			Expiration:     lockExpiration,
+			LockSecretHash: state.LockSecretHash,
+			Secret:         state.Secret,
+			Fee:            r.TotalFee,
+			PaymentData:    state.Transfer.PaymentData,
+		}
+		msg := mt.NewEventSendMediatedTransfer(tr, r.HopNode())
+		state.Parts = append(state.Parts, &mt.InitiatorPartState{
+			Route:    r,
+			Transfer: tr,
+			Message:  msg,
+			State:    mt.StatePayeePending,
+		})
+		log.Trace(fmt.Sprintf("send mediated transfer part id=%s,amount=%s,total=%s,token=%s,target=%s", utils.HPex(tr.LockSecretHash), tr.Amount, tr.TotalAmount, utils.APex(tr.Token), utils.APex(tr.Target)))
+		events = append(events, msg)
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+//multiPartFailed marks the whole payment failed, only the first failure is reported.
+func multiPartFailed(state *mt.MultiPartInitiatorState, reason string) (events []transfer.Event) {
+	if state.Failed || hasUnlockedPart(state) {
+		return
+	}
+	state.Failed = true
+	events = append(events, &transfer.EventTransferSentFailed{
+		LockSecretHash: state.LockSecretHash,
+		Reason:         reason,
+		Target:         state.Transfer.Target,
+		Token:          state.Transfer.Token,
+	})
+	return
+}
+
+func hasUnlockedPart(state *mt.MultiPartInitiatorState) bool {
+	for _, p := range state.Parts {
+		if p.State == mt.StatePayeeBalanceProof {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+unlockPartEvents sends the balance proof of part `p` to its next hop,
+the payment is reported successful when the first part is unlocked, because the target has learned the secret.
+*/
+func unlockPartEvents(state *mt.MultiPartInitiatorState, p *mt.InitiatorPartState) (events []transfer.Event) {
+	first := !hasUnlockedPart(state)
+	p.State = mt.StatePayeeBalanceProof
+	events = append(events, &mt.EventSendBalanceProof{
+		LockSecretHash:    state.LockSecretHash,
+		ChannelIdentifier: p.Route.ChannelIdentifier,
+		Token:             state.Transfer.Token,
+		Receiver:          p.Route.HopNode(),
+	})
+	if !first || state.Failed {
+		return
+	}
+	amount := new(big.Int)
+	for _, p2 := range state.Parts {
+		amount.Add(amount, p2.Transfer.Amount)
+	}
+	events = append(events, &transfer.EventTransferSentSuccess{
+		LockSecretHash:    state.LockSecretHash,
+		Amount:            amount,
+		Target:            state.Transfer.Target,
+		ChannelIdentifier: p.Route.ChannelIdentifier,
+		Token:             state.Transfer.Token,
+		PaymentData:       state.Transfer.PaymentData,
+	}, &mt.EventUnlockSuccess{
+		LockSecretHash: state.LockSecretHash,
+	})
+	return
+}
+
+//expire the locks of all pending parts, the payment fails if no part has been unlocked.
+func handleMultiPartBlock(state *mt.MultiPartInitiatorState, st *transfer.BlockStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	if state.BlockNumber < st.BlockNumber {
+		state.BlockNumber = st.BlockNumber
+	}
+	expired := false
+	for _, p := range state.Parts {
+		if p.State != mt.StatePayeePending || state.BlockNumber <= p.Transfer.Expiration {
+			continue
+		}
+		expired = true
+		p.State = mt.StatePayeeExpired
+		events = append(events, &mt.EventUnlockFailed{
+			LockSecretHash:    state.LockSecretHash,
+			ChannelIdentifier: p.Route.ChannelIdentifier,
+			Reason:            "lock expired",
+		})
+	}
+	if expired {
+		events = append(events, multiPartFailed(state, "lock expired")...)
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+/*
+the target requests the secret only when it has received all the parts,
+so the amount must be the total amount.
+*/
+func handleMultiPartSecretRequest(state *mt.MultiPartInitiatorState, st *mt.ReceiveSecretRequestStateChange) *transfer.TransitionResult {
+	isValid := st.Sender == state.Transfer.Target &&
+		st.LockSecretHash == state.LockSecretHash &&
+		st.Amount.Cmp(state.Transfer.TargetAmount) == 0
+	if !isValid || state.Failed {
+		log.Warn(fmt.Sprintf("ignore secret request of multi-part payment %s,amount=%s,failed=%v", utils.HPex(st.LockSecretHash), st.Amount, state.Failed))
+		return &transfer.TransitionResult{
+			NewState: state,
+			Events:   nil,
+		}
+	}
+	revealSecret := &mt.EventSendRevealSecret{
+		LockSecretHash: state.LockSecretHash,
+		Secret:         state.Secret,
+		Token:          state.Transfer.Token,
+		Receiver:       state.Transfer.Target,
+		Sender:         state.OurAddress,
+	}
+	state.RevealSecret = revealSecret
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   []transfer.Event{revealSecret},
+	}
+}
+
+//next hop of a part learned the secret, unlock that part
+func handleMultiPartSecretReveal(state *mt.MultiPartInitiatorState, st *mt.ReceiveSecretRevealStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	//the secret may be unknown to me when the lock secret hash is given by the target, e.g. paying an invoice
+	if utils.ShaSecret(st.Secret[:]) == state.LockSecretHash {
+		state.Secret = st.Secret
+		for _, p := range state.Parts {
+			if p.State != mt.StatePayeePending || p.Route.HopNode() != st.Sender || state.BlockNumber >= p.Transfer.Expiration {
+				continue
+			}
+			events = append(events, unlockPartEvents(state, p)...)
+		}
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+//secret registered on chain, unlock all the parts which are not expired
+func handleMultiPartSecretRevealOnChain(state *mt.MultiPartInitiatorState, st *mt.ContractSecretRevealOnChainStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	for _, p := range state.Parts {
+		if p.State != mt.StatePayeePending {
+			continue
+		}
+		if p.Transfer.Expiration < st.BlockNumber {
+			p.State = mt.StatePayeeExpired
+			events = append(events, &mt.EventUnlockFailed{
+				LockSecretHash:    state.LockSecretHash,
+				ChannelIdentifier: p.Route.ChannelIdentifier,
+				Reason:            "lock expired",
+			})
+			continue
+		}
+		events = append(events, unlockPartEvents(state, p)...)
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+/*
+a hop refused one part, the whole payment fails.
+there is no need to cancel the other parts, the target will never have the full amount, so their locks will expire.
+*/
+func handleMultiPartRefund(state *mt.MultiPartInitiatorState, st *mt.ReceiveAnnounceDisposedStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	for _, p := range state.Parts {
+		if p.State != mt.StatePayeePending || !mediator.IsValidRefund(p.Transfer, p.Route, st) {
+			continue
+		}
+		p.State = mt.StatePayeeExpired
+		events = append(events, multiPartFailed(state, fmt.Sprintf("part refused by %s", utils.APex(st.Sender)))...)
+		events = append(events, &mt.EventSendAnnounceDisposedResponse{
+			LockSecretHash: st.Lock.LockSecretHash,
+			Token:          state.Transfer.Token,
+			Receiver:       st.Sender,
+		})
+		break
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+//channel of a part has been cooperative settled or withdrawn, its lock is gone.
+func handleMultiPartChannelRemoved(state *mt.MultiPartInitiatorState, channelIdentifier common.Hash) *transfer.TransitionResult {
+	var events []transfer.Event
+	for _, p := range state.Parts {
+		if p.State != mt.StatePayeePending || p.Route.ChannelIdentifier != channelIdentifier {
+			continue
+		}
+		p.State = mt.StatePayeeExpired
+		events = append(events, multiPartFailed(state, "channel of a part removed")...)
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+//remove the state manager once every part is either unlocked or expired
+func clearMultiPartIfFinalized(it *transfer.TransitionResult) *transfer.TransitionResult {
+	state, ok := it.NewState.(*mt.MultiPartInitiatorState)
+	if !ok {
+		return it
+	}
+	for _, p := range state.Parts {
+		if p.State == mt.StatePayeePending {
+			return it
+		}
+	}
+	return &transfer.TransitionResult{
+		NewState: nil,
+		Events: append(it.Events, &mt.EventRemoveStateManager{
+			Key: utils.Sha3(state.LockSecretHash[:], state.Transfer.Token[:]),
+		}),
+	}
+}
+
+/*
+MultiPartStateTransition is State machine for a node starting a multi-part payment.
+the payment is split across several routes, all the parts use the same lock secret hash.
+*/
+func MultiPartStateTransition(originalState transfer.State, st transfer.StateChange) *transfer.TransitionResult {
+	it := &transfer.TransitionResult{
+		NewState: originalState,
+		Events:   nil,
+	}
+	state, ok := originalState.(*mt.MultiPartInitiatorState)
+	if !ok {
+		if originalState != nil {
+			panic("MultiPartInitiatorState StateTransition get type error")
+		}
+		staii, ok := st.(*mt.ActionInitMultiPartInitiatorStateChange)
+		if ok {
+			return handleMultiPartInit(staii)
+		}
+		log.Warn(fmt.Sprintf("originalState,statechange should not be here originalState=\n%s\n,statechange=\n%s",
+			utils.StringInterface1(originalState), utils.StringInterface1(st)))
+		return it
+	}
+	switch st2 := st.(type) {
+	case *transfer.BlockStateChange:
+		it = handleMultiPartBlock(state, st2)
+	case *mt.ReceiveSecretRevealStateChange:
+		it = handleMultiPartSecretReveal(state, st2)
+	case *mt.ContractSecretRevealOnChainStateChange:
+		it = handleMultiPartSecretRevealOnChain(state, st2)
+	case *mt.ReceiveSecretRequestStateChange:
+		if state.RevealSecret == nil {
+			it = handleMultiPartSecretRequest(state, st2)
+		} else {
+			log.Warn(fmt.Sprintf("recevie secret request but initiator have already sent reveal secret"))
+		}
+	case *mt.ReceiveAnnounceDisposedStateChange:
+		if state.RevealSecret == nil {
+			it = handleMultiPartRefund(state, st2)
+		} else {
+			log.Warn(fmt.Sprintf("secret already revealed ,but initiator recevied announce disposed %s", utils.StringInterface(st, 3)))
+		}
+	case *transfer.ActionCancelTransferStateChange:
+		if state.RevealSecret == nil {
+			it.Events = multiPartFailed(state, "user canceled transfer")
+		} else {
+			log.Warn(fmt.Sprintf("secret already revealed,transfer cannot canceled"))
+		}
+	case *mt.ContractCooperativeSettledStateChange:
+		it = handleMultiPartChannelRemoved(state, st2.ChannelIdentifier)
+	case *mt.ContractChannelWithdrawStateChange:
+		it = handleMultiPartChannelRemoved(state, st2.ChannelIdentifier.ChannelIdentifier)
+	default:
+		log.Error(fmt.Sprintf("multi-part initiator received unkown state change %s", utils.StringInterface(st, 3)))
+	}
+	return clearMultiPartIfFinalized(it)
+}
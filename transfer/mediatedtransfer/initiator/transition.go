@@ -35,17 +35,23 @@ func cancelCurrentRoute(state *mt.InitiatorState) *transfer.TransitionResult {
 	return tryNewRoute(state)
 }
 
-//Cancel the current in-transit message
+/*
+userCancelTransfer the user gives up this transfer before the secret is revealed.
+the lock stays on the current route until it's removed by AnnounceDisposed or expired,
+meanwhile the secret is never revealed and no other route is tried.
+*/
 func userCancelTransfer(state *mt.InitiatorState) *transfer.TransitionResult {
 	if state.RevealSecret != nil {
 		panic("cannot cancel a transfer with a RevealSecret in flight")
 	}
-	state.Transfer.Secret = utils.EmptyHash
-	state.Transfer.LockSecretHash = utils.EmptyHash
-	state.Message = nil
-	state.Route = nil
+	if state.Canceled {
+		return &transfer.TransitionResult{
+			NewState: state,
+			Events:   nil,
+		}
+	}
+	state.Canceled = true
 	state.SecretRequest = nil
-	state.RevealSecret = nil
 	cancel := &transfer.EventTransferSentFailed{
 		LockSecretHash: state.Transfer.LockSecretHash,
 		Reason:         "user canceled transfer",
@@ -53,7 +59,7 @@ func userCancelTransfer(state *mt.InitiatorState) *transfer.TransitionResult {
 		Token:          state.Transfer.Token,
 	}
 	return &transfer.TransitionResult{
-		NewState: nil,
+		NewState: state,
 		Events:   []transfer.Event{cancel},
 	}
 }
@@ -123,6 +129,7 @@ func tryNewRoute(state *mt.InitiatorState) *transfer.TransitionResult {
 		LockSecretHash: state.LockSecretHash,
 		Secret:         state.Secret,
 		Fee:            tryRoute.TotalFee,
+		PaymentData:    state.Transfer.PaymentData,
 	}
 	msg := mt.NewEventSendMediatedTransfer(tr, tryRoute.HopNode())
 	if len(state.Routes.CanceledRoutes) > 0 {
@@ -188,7 +195,18 @@ func handleBlock(state *mt.InitiatorState, stateChange *transfer.BlockStateChang
 
 func handleRefund(state *mt.InitiatorState, stateChange *mt.ReceiveAnnounceDisposedStateChange) *transfer.TransitionResult {
 	if mediator.IsValidRefund(state.Transfer, state.Route, stateChange) {
-		it := cancelCurrentRoute(state)
+		var it *transfer.TransitionResult
+		if state.Canceled {
+			//user canceled this transfer, the lock is removed, don't try another route.
+			it = &transfer.TransitionResult{
+				NewState: nil,
+				Events: []transfer.Event{&mt.EventRemoveStateManager{
+					Key: utils.Sha3(state.LockSecretHash[:], state.Transfer.Token[:]),
+				}},
+			}
+		} else {
+			it = cancelCurrentRoute(state)
+		}
 		ev := &mt.EventSendAnnounceDisposedResponse{
 			LockSecretHash: stateChange.Lock.LockSecretHash,
 			Token:          state.Transfer.Token,
@@ -217,6 +235,22 @@ func handleCancelTransfer(state *mt.InitiatorState) *transfer.TransitionResult {
 	return userCancelTransfer(state)
 }
 
+/*
+the channel of current route is cooperative settled or withdrawed, the lock on it has gone.
+a canceled transfer is finished, otherwise try another route.
+*/
+func channelRemoved(state *mt.InitiatorState) *transfer.TransitionResult {
+	if state.Canceled {
+		return &transfer.TransitionResult{
+			NewState: nil,
+			Events: []transfer.Event{&mt.EventRemoveStateManager{
+				Key: utils.Sha3(state.LockSecretHash[:], state.Transfer.Token[:]),
+			}},
+		}
+	}
+	return cancelCurrentRoute(state)
+}
+
 func handleSecretRequest(state *mt.InitiatorState, stateChange *mt.ReceiveSecretRequestStateChange) *transfer.TransitionResult {
 	isValid := stateChange.Sender == state.Transfer.Target &&
 		stateChange.LockSecretHash == state.Transfer.LockSecretHash &&
@@ -308,6 +342,7 @@ func transferSuccessEvents(state *mt.InitiatorState) (events []transfer.Event) {
 		Target:            tr.Target,
 		ChannelIdentifier: state.Route.ChannelIdentifier,
 		Token:             tr.Token,
+		PaymentData:       tr.PaymentData,
 	}
 	unlockSuccess := &mt.EventUnlockSuccess{
 		LockSecretHash: tr.LockSecretHash,
@@ -334,11 +369,14 @@ func handleSecretReveal(state *mt.InitiatorState, st *mt.ReceiveSecretRevealStat
 			Events:   nil,
 		}
 	}
-	if st.Sender == state.Route.HopNode() && st.Secret == state.Transfer.Secret {
+	//the secret may be unknown to me when the lock secret hash is given by the target, e.g. paying an invoice
+	if st.Sender == state.Route.HopNode() && utils.ShaSecret(st.Secret[:]) == state.Transfer.LockSecretHash {
 		/*
 					   next hop learned the secret, unlock the token locally and send the
 			         unlock message to next hop
 		*/
+		state.Secret = st.Secret
+		state.Transfer.Secret = st.Secret
 		return &transfer.TransitionResult{
 			NewState: nil,
 			Events:   transferSuccessEvents(state),
@@ -424,7 +462,9 @@ func StateTransition(originalState transfer.State, st transfer.StateChange) *tra
 		case *mt.ContractSecretRevealOnChainStateChange:
 			it = handleSecretRevealOnChain(state, st2)
 		case *mt.ReceiveSecretRequestStateChange:
-			if state.RevealSecret == nil {
+			if state.Canceled {
+				log.Warn(fmt.Sprintf("recevie secret request but transfer %s is canceled by user", utils.HPex(state.LockSecretHash)))
+			} else if state.RevealSecret == nil {
 				it = handleSecretRequest(state, st2)
 			} else {
 				log.Warn(fmt.Sprintf("recevie secret request but initiator have already sent reveal secret"))
@@ -436,7 +476,9 @@ func StateTransition(originalState transfer.State, st transfer.StateChange) *tra
 				log.Warn(fmt.Sprintf("secret already revealed ,but initiator recevied announce disposed %s", utils.StringInterface(st, 3)))
 			}
 		case *mt.ActionCancelRouteStateChange:
-			if state.RevealSecret == nil {
+			if state.Canceled {
+				log.Info(fmt.Sprintf("transfer %s is canceled by user, no other route will be tried", utils.HPex(state.LockSecretHash)))
+			} else if state.RevealSecret == nil {
 				it = handleCancelRoute(state, st2)
 			} else {
 				panic(fmt.Sprintf("secret already revealed,route cannot canceled"))
@@ -448,9 +490,9 @@ func StateTransition(originalState transfer.State, st transfer.StateChange) *tra
 				panic(fmt.Sprintf("secret already revealed,transfer cannot canceled"))
 			}
 		case *mt.ContractCooperativeSettledStateChange:
-			it = cancelCurrentRoute(state)
+			it = channelRemoved(state)
 		case *mt.ContractChannelWithdrawStateChange:
-			it = cancelCurrentRoute(state)
+			it = channelRemoved(state)
 		default:
 			log.Error(fmt.Sprintf("initiator received unkown state change %s", utils.StringInterface(st, 3)))
 		}
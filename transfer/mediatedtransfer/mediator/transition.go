@@ -317,6 +317,7 @@ func nextTransferPair(payerRoute *route.State, payerTransfer *mediatedtransfer.L
 			LockSecretHash: payerTransfer.LockSecretHash,
 			Secret:         payerTransfer.Secret,
 			Fee:            big.NewInt(0).Sub(payerTransfer.Fee, payeeRoute.Fee),
+			PaymentData:    payerTransfer.PaymentData,
 		}
 		if payeeRoute.HopNode() == payeeTransfer.Target {
 			//i'm the last hop,so take the rest of the fee
@@ -905,11 +906,12 @@ func handleBalanceProof(state *mediatedtransfer.MediatorState, st *mediatedtrans
 			events = append(events, balanceProof, unlockSuccess)
 		}
 		if pair.PayerRoute.HopNode() == st.NodeAddress {
+			pair.PayerState = mediatedtransfer.StatePayerBalanceProof
 			withdraw := &mediatedtransfer.EventWithdrawSuccess{
 				LockSecretHash: pair.PayeeTransfer.LockSecretHash,
+				Pair:           pair,
 			}
 			events = append(events, withdraw)
-			pair.PayerState = mediatedtransfer.StatePayerBalanceProof
 		}
 	}
 	return &transfer.TransitionResult{
@@ -17,15 +17,16 @@ import (
 LockedTransferState is State of a transfer that is time hash locked.
 */
 type LockedTransferState struct {
-	TargetAmount   *big.Int       //amount target should recevied
-	Amount         *big.Int       // Amount of `token` being transferred.
-	Token          common.Address //Token being transferred.
-	Initiator      common.Address //Transfer initiator
-	Target         common.Address //Transfer target address.
-	Expiration     int64          //The absolute block number that the lock expires.
-	LockSecretHash common.Hash    // The hashlock.
-	Secret         common.Hash    //The secret that unlocks the lock, may be None.
-	Fee            *big.Int       // how much fee left for other hop node.
+	TargetAmount         *big.Int       //amount target should recevied
+	Amount               *big.Int       // Amount of `token` being transferred.
+	Token                common.Address //Token being transferred.
+	Initiator            common.Address //Transfer initiator
+	Target               common.Address //Transfer target address.
+	Expiration           int64          //The absolute block number that the lock expires.
+	LockSecretHash       common.Hash    // The hashlock.
+	Secret               common.Hash    //The secret that unlocks the lock, may be None.
+	Fee                  *big.Int       // how much fee left for other hop node.
+	encoding.PaymentData                //identifier and memo from initiator to target
 }
 
 //AlmostEqual if two state equals?
@@ -49,6 +50,7 @@ func LockedTransferFromMessage(msg *encoding.MediatedTransfer, tokenAddress comm
 		LockSecretHash: msg.LockSecretHash,
 		Fee:            msg.Fee,
 		Token:          tokenAddress,
+		PaymentData:    msg.PaymentData,
 	}
 }
 
@@ -86,9 +88,37 @@ type InitiatorState struct {
 	SecretRequest     *encoding.SecretRequest
 	RevealSecret      *EventSendRevealSecret
 	CanceledTransfers []*EventSendMediatedTransfer
+	Canceled          bool //user canceled this transfer, never reveal the secret and never try another route
 	Db                channeltype.Db
 }
 
+/*
+InitiatorPartState is one part of a multi-part payment sent by the initiator.
+*/
+type InitiatorPartState struct {
+	Route    *route.State               //route used by this part
+	Transfer *LockedTransferState       //TargetAmount is what the target receives from this part
+	Message  *EventSendMediatedTransfer //message of this part
+	State    string                     //StatePayeePending, StatePayeeBalanceProof or StatePayeeExpired
+}
+
+/*
+MultiPartInitiatorState is State of a node initiating a multi-part payment.
+all the parts use the same lock secret hash,
+the target requests the secret only when it has received all of them.
+*/
+type MultiPartInitiatorState struct {
+	OurAddress     common.Address
+	Transfer       *LockedTransferState //the whole payment, TargetAmount is the total amount target should receive
+	Parts          []*InitiatorPartState
+	BlockNumber    int64
+	LockSecretHash common.Hash
+	Secret         common.Hash
+	RevealSecret   *EventSendRevealSecret
+	Failed         bool //payment failed, waiting for the locks of other parts to expire
+	Db             channeltype.Db
+}
+
 /*
 MediatorState is State of a node mediating a transfer.
 */
@@ -155,6 +185,16 @@ type TargetState struct {
 	Secret       common.Hash
 	State        string // default secret_request
 	Db           channeltype.Db
+	Parts        []*TargetPartState //parts received of a multi-part payment, the first one is FromRoute and FromTransfer
+	Hold         bool               //hold payment, the secret is never requested, wait for the user to settle or reject it
+	Key          common.Hash        //key of the state manager, Sha3(lockSecretHash,token) if empty
+}
+
+//TargetPartState is one part of a multi-part payment received by the target
+type TargetPartState struct {
+	FromRoute    *route.State
+	FromTransfer *LockedTransferState
+	State        string //StatePayerPending, StatePayerBalanceProof or StatePayerExpired
 }
 
 /*
@@ -248,6 +288,7 @@ func NewMediationPairState(payerRoute, payeeRoute *route.State, payerTransfer, p
 func init() {
 	gob.Register(&LockedTransferState{})
 	gob.Register(&InitiatorState{})
+	gob.Register(&MultiPartInitiatorState{})
 	gob.Register(&MediatorState{})
 	gob.Register(&TargetState{})
 	gob.Register(&MediationPairState{})
@@ -42,6 +42,21 @@ type ActionInitInitiatorStateChange struct {
 	Secret         common.Hash
 }
 
+/*
+ActionInitMultiPartInitiatorStateChange Initial state of a new multi-part payment.
+`Amounts[i]` is what the target should receive through `Routes[i]`.
+*/
+type ActionInitMultiPartInitiatorStateChange struct {
+	OurAddress     common.Address       //This node address.
+	Tranfer        *LockedTransferState //the whole payment
+	Routes         []*route.State       //one route for each part
+	Amounts        []*big.Int
+	BlockNumber    int64
+	Db             channeltype.Db
+	LockSecretHash common.Hash
+	Secret         common.Hash
+}
+
 //ActionInitMediatorStateChange  Initial state for a new mediator.
 type ActionInitMediatorStateChange struct {
 	OurAddress  common.Address             //This node address.
@@ -69,6 +84,16 @@ type ActionInitTargetStateChange struct {
 	BlockNumber int64
 	Message     *encoding.MediatedTransfer //the message trigger this statechange
 	Db          channeltype.Db             //get the latest channel state
+	Hold        bool                       //hold payment, don't request the secret, wait for the user to settle or reject it
+	Key         common.Hash                //key of the state manager, Sha3(lockSecretHash,token) if empty
+}
+
+//ReceiveTransferPartStateChange target received another part of a multi-part payment
+type ReceiveTransferPartStateChange struct {
+	FromRoute    *route.State
+	FromTransfer *LockedTransferState
+	BlockNumber  int64
+	Message      *encoding.MediatedTransfer //the message trigger this statechange
 }
 
 /*
@@ -81,6 +106,17 @@ type ActionCancelRouteStateChange struct {
 	LockSecretHash common.Hash
 }
 
+//ActionSettleHoldStateChange user settles a held payment with its secret
+type ActionSettleHoldStateChange struct {
+	Secret common.Hash
+}
+
+//ActionRejectHoldStateChange user rejects a held payment, the payer will be notified by AnnounceDisposed
+type ActionRejectHoldStateChange struct {
+	LockSecretHash common.Hash
+	Reason         string
+}
+
 //ReceiveSecretRequestStateChange A SecretRequest message received.
 type ReceiveSecretRequestStateChange struct {
 	Amount         *big.Int
@@ -301,9 +337,13 @@ func (e *ContractBalanceProofUpdatedStateChange) GetBlockNumber() int64 {
 }
 func init() {
 	gob.Register(&ActionInitInitiatorStateChange{})
+	gob.Register(&ActionInitMultiPartInitiatorStateChange{})
 	gob.Register(&ActionInitMediatorStateChange{})
 	gob.Register(&ActionInitTargetStateChange{})
+	gob.Register(&ReceiveTransferPartStateChange{})
 	gob.Register(&ActionCancelRouteStateChange{})
+	gob.Register(&ActionSettleHoldStateChange{})
+	gob.Register(&ActionRejectHoldStateChange{})
 	gob.Register(&ReceiveSecretRequestStateChange{})
 	gob.Register(&ReceiveSecretRevealStateChange{})
 	gob.Register(&ReceiveAnnounceDisposedStateChange{})
@@ -0,0 +1,99 @@
+package target
+
+import (
+	"fmt"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/mediator"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+/*
+handleSettleHold the user settles a held payment,
+it's the same as the secret is revealed to me.
+*/
+func handleSettleHold(state *mediatedtransfer.TargetState, st *mediatedtransfer.ActionSettleHoldStateChange) *transfer.TransitionResult {
+	reveal := &mediatedtransfer.ReceiveSecretRevealStateChange{
+		Secret: st.Secret,
+		Sender: state.OurAddress,
+	}
+	if len(state.Parts) > 0 {
+		return handleMultiPartSecretReveal(state, reveal)
+	}
+	return handleSecretReveal(state, reveal)
+}
+
+/*
+rejectHold gives back every pending lock of a held payment by AnnounceDisposed,
+so the payer doesn't need to wait for the lock to expire.
+*/
+func rejectHold(state *mediatedtransfer.TargetState, reason string) *transfer.TransitionResult {
+	var events []transfer.Event
+	reject := func(r *mediatedtransfer.TargetPartState) {
+		events = append(events, &mediatedtransfer.EventSendAnnounceDisposed{
+			Amount:         r.FromTransfer.Amount,
+			LockSecretHash: r.FromTransfer.LockSecretHash,
+			Expiration:     r.FromTransfer.Expiration,
+			Token:          r.FromTransfer.Token,
+			Receiver:       r.FromRoute.HopNode(),
+		})
+		events = append(events, &mediatedtransfer.EventWithdrawFailed{
+			LockSecretHash:    r.FromTransfer.LockSecretHash,
+			ChannelIdentifier: r.FromRoute.ChannelIdentifier,
+			Reason:            reason,
+		})
+	}
+	if len(state.Parts) > 0 {
+		for _, p := range state.Parts {
+			if p.State == mediatedtransfer.StatePayerPending {
+				p.State = mediatedtransfer.StatePayerExpired
+				reject(p)
+			}
+		}
+	} else {
+		reject(&mediatedtransfer.TargetPartState{
+			FromRoute:    state.FromRoute,
+			FromTransfer: state.FromTransfer,
+		})
+	}
+	events = append(events, &mediatedtransfer.EventRemoveStateManager{
+		Key: stateManagerKey(state),
+	})
+	return &transfer.TransitionResult{
+		NewState: nil,
+		Events:   events,
+	}
+}
+
+//handleRejectHold the user rejects a held payment, it's too late if the secret is known.
+func handleRejectHold(state *mediatedtransfer.TargetState, st *mediatedtransfer.ActionRejectHoldStateChange) *transfer.TransitionResult {
+	if state.FromTransfer.LockSecretHash != st.LockSecretHash || state.Secret != utils.EmptyHash || state.FromTransfer.Secret != utils.EmptyHash {
+		log.Warn(fmt.Sprintf("cannot reject hold payment %s, secret known=%v", utils.HPex(st.LockSecretHash), state.Secret != utils.EmptyHash || state.FromTransfer.Secret != utils.EmptyHash))
+		return &transfer.TransitionResult{
+			NewState: state,
+			Events:   nil,
+		}
+	}
+	return rejectHold(state, st.Reason)
+}
+
+/*
+isHoldExpiring returns true if a held payment is not settled in time,
+after that the secret could not be registered on chain safely.
+*/
+func isHoldExpiring(state *mediatedtransfer.TargetState) bool {
+	if !state.Hold || state.Secret != utils.EmptyHash || state.FromTransfer.Secret != utils.EmptyHash {
+		return false
+	}
+	if len(state.Parts) > 0 {
+		for _, p := range state.Parts {
+			if p.State == mediatedtransfer.StatePayerPending && !mediator.IsSafeToWait(p.FromTransfer, p.FromRoute.RevealTimeout(), state.BlockNumber) {
+				return true
+			}
+		}
+		return false
+	}
+	return !mediator.IsSafeToWait(state.FromTransfer, state.FromRoute.RevealTimeout(), state.BlockNumber)
+}
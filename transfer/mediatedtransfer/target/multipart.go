@@ -0,0 +1,264 @@
+package target
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/mediator"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+isSamePayment returns true if `tr` is another part of the multi-part payment `state` is receiving.
+*/
+func isSamePayment(state *mediatedtransfer.TargetState, tr *mediatedtransfer.LockedTransferState) bool {
+	first := state.FromTransfer
+	return tr.IsMultiPart() &&
+		tr.TotalAmount.Cmp(first.TotalAmount) == 0 &&
+		tr.Initiator == first.Initiator &&
+		tr.Token == first.Token &&
+		tr.LockSecretHash == first.LockSecretHash &&
+		tr.PaymentIdentifier == first.PaymentIdentifier
+}
+
+/*
+eventsForMultiPartSecretRequest requests the secret only once,
+when the parts received add up to the total amount and it's safe to wait for all of them.
+a held payment never requests the secret.
+the request is saved with the channel of `fromRoute`, which is the part just received.
+*/
+func eventsForMultiPartSecretRequest(state *mediatedtransfer.TargetState, fromRoute *route.State) (events []transfer.Event) {
+	if state.State == mediatedtransfer.StateSecretRequest || state.Hold {
+		return
+	}
+	received := new(big.Int)
+	for _, p := range state.Parts {
+		if !mediator.IsSafeToWait(p.FromTransfer, p.FromRoute.RevealTimeout(), state.BlockNumber) {
+			//not enough time to withdraw this part on-chain, silently let the payment expire.
+			return
+		}
+		received.Add(received, p.FromTransfer.Amount)
+	}
+	if received.Cmp(state.FromTransfer.TotalAmount) < 0 {
+		log.Trace(fmt.Sprintf("multi-part payment %s received %s of %s", utils.HPex(state.FromTransfer.LockSecretHash), received, state.FromTransfer.TotalAmount))
+		return
+	}
+	state.State = mediatedtransfer.StateSecretRequest
+	events = append(events, &mediatedtransfer.EventSendSecretRequest{
+		ChannelIdentifier: fromRoute.ChannelIdentifier,
+		LockSecretHash:    state.FromTransfer.LockSecretHash,
+		Amount:            new(big.Int).Set(state.FromTransfer.TotalAmount),
+		Receiver:          state.FromTransfer.Initiator,
+	})
+	return
+}
+
+//handleInitMultiPartTarget the first part of a multi-part payment
+func handleInitMultiPartTarget(st *mediatedtransfer.ActionInitTargetStateChange) *transfer.TransitionResult {
+	state := &mediatedtransfer.TargetState{
+		OurAddress:   st.OurAddress,
+		FromRoute:    st.FromRoute,
+		FromTransfer: st.FromTranfer,
+		BlockNumber:  st.BlockNumber,
+		Db:           st.Db,
+		Hold:         st.Hold,
+		Key:          st.Key,
+		Parts: []*mediatedtransfer.TargetPartState{
+			{
+				FromRoute:    st.FromRoute,
+				FromTransfer: st.FromTranfer,
+				State:        mediatedtransfer.StatePayerPending,
+			},
+		},
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   eventsForMultiPartSecretRequest(state, st.FromRoute),
+	}
+}
+
+/*
+handleTransferPart another part received,
+if the secret is already known, reveal it to the hop of this part directly.
+*/
+func handleTransferPart(state *mediatedtransfer.TargetState, st *mediatedtransfer.ReceiveTransferPartStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	if state.BlockNumber < st.BlockNumber {
+		state.BlockNumber = st.BlockNumber
+	}
+	tr := st.FromTransfer
+	if len(state.Parts) == 0 || !isSamePayment(state, tr) {
+		log.Error(fmt.Sprintf("receive a part which doesn't match the payment, part=%s,state=%s", utils.StringInterface(tr, 3), utils.StringInterface(state, 3)))
+		return &transfer.TransitionResult{
+			NewState: state,
+			Events:   nil,
+		}
+	}
+	state.Parts = append(state.Parts, &mediatedtransfer.TargetPartState{
+		FromRoute:    st.FromRoute,
+		FromTransfer: tr,
+		State:        mediatedtransfer.StatePayerPending,
+	})
+	if state.Secret != utils.EmptyHash {
+		tr.Secret = state.Secret
+		events = append(events, &mediatedtransfer.EventSendRevealSecret{
+			LockSecretHash: tr.LockSecretHash,
+			Secret:         tr.Secret,
+			Token:          tr.Token,
+			Receiver:       st.FromRoute.HopNode(),
+			Sender:         state.OurAddress,
+		})
+	} else {
+		events = eventsForMultiPartSecretRequest(state, st.FromRoute)
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+func setMultiPartSecret(state *mediatedtransfer.TargetState, secret common.Hash) {
+	state.Secret = secret
+	state.FromTransfer.Secret = secret
+	for _, p := range state.Parts {
+		p.FromTransfer.Secret = secret
+	}
+}
+
+//reveal the secret to the hop of every part
+func handleMultiPartSecretReveal(state *mediatedtransfer.TargetState, st *mediatedtransfer.ReceiveSecretRevealStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	if utils.ShaSecret(st.Secret[:]) == state.FromTransfer.LockSecretHash {
+		state.State = mediatedtransfer.StateRevealSecret
+		setMultiPartSecret(state, st.Secret)
+		for _, p := range state.Parts {
+			if p.State != mediatedtransfer.StatePayerPending {
+				continue
+			}
+			events = append(events, &mediatedtransfer.EventSendRevealSecret{
+				LockSecretHash: p.FromTransfer.LockSecretHash,
+				Secret:         st.Secret,
+				Token:          p.FromTransfer.Token,
+				Receiver:       p.FromRoute.HopNode(),
+				Sender:         state.OurAddress,
+			})
+		}
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+//one part unlocked, the payment is finished when all the parts are unlocked.
+func handleMultiPartBalanceProof(state *mediatedtransfer.TargetState, st *mediatedtransfer.ReceiveUnlockStateChange) *transfer.TransitionResult {
+	var events []transfer.Event
+	unlocked := true
+	for _, p := range state.Parts {
+		if p.State == mediatedtransfer.StatePayerPending && p.FromRoute.HopNode() == st.NodeAddress && p.FromTransfer.LockSecretHash == st.LockSecretHash {
+			p.State = mediatedtransfer.StatePayerBalanceProof
+		}
+		if p.State != mediatedtransfer.StatePayerBalanceProof {
+			unlocked = false
+		}
+	}
+	if unlocked {
+		state.State = mediatedtransfer.StateBalanceProof
+		events = append(events, &mediatedtransfer.EventRemoveStateManager{
+			Key: stateManagerKey(state),
+		})
+	}
+	return &transfer.TransitionResult{
+		NewState: state,
+		Events:   events,
+	}
+}
+
+//register the secret on chain if it's not safe to wait for any part
+func eventsForMultiPartRegisterSecret(state *mediatedtransfer.TargetState) (events []transfer.Event) {
+	if state.Secret == utils.EmptyHash {
+		return
+	}
+	for _, p := range state.Parts {
+		if p.State == mediatedtransfer.StatePayerPending && !mediator.IsSafeToWait(p.FromTransfer, p.FromRoute.RevealTimeout(), state.BlockNumber) {
+			state.State = mediatedtransfer.StateWaitingRegisterSecret
+			events = append(events, &mediatedtransfer.EventContractSendRegisterSecret{
+				Secret: state.Secret,
+			})
+			return
+		}
+	}
+	return
+}
+
+/*
+clearMultiPartIfFinalized is clearIfFinalized for a multi-part payment.
+the payment fails if any part expires before the secret is known,
+one success event is reported for the whole payment when all the parts are unlocked.
+*/
+func clearMultiPartIfFinalized(it *transfer.TransitionResult, state *mediatedtransfer.TargetState) *transfer.TransitionResult {
+	key := stateManagerKey(state)
+	if state.State == mediatedtransfer.StateBalanceProof {
+		amount := new(big.Int)
+		for _, p := range state.Parts {
+			amount.Add(amount, p.FromTransfer.Amount)
+		}
+		transferSuccess := &transfer.EventTransferReceivedSuccess{
+			LockSecretHash:    state.FromTransfer.LockSecretHash,
+			Amount:            amount,
+			Initiator:         state.FromTransfer.Initiator,
+			ChannelIdentifier: state.FromRoute.ChannelIdentifier,
+			PaymentData:       state.FromTransfer.PaymentData,
+		}
+		unlockSuccess := &mediatedtransfer.EventWithdrawSuccess{
+			LockSecretHash: state.FromTransfer.LockSecretHash,
+		}
+		return &transfer.TransitionResult{
+			NewState: nil,
+			Events:   append(it.Events, transferSuccess, unlockSuccess),
+		}
+	}
+	expired := false
+	allExpired := true
+	for _, p := range state.Parts {
+		if state.BlockNumber > p.FromTransfer.Expiration {
+			expired = true
+		} else if p.State == mediatedtransfer.StatePayerPending {
+			allExpired = false
+		}
+	}
+	if expired && state.Secret == utils.EmptyHash {
+		events := it.Events
+		for _, p := range state.Parts {
+			if p.State != mediatedtransfer.StatePayerPending {
+				continue
+			}
+			p.State = mediatedtransfer.StatePayerExpired
+			events = append(events, &mediatedtransfer.EventWithdrawFailed{
+				LockSecretHash:    p.FromTransfer.LockSecretHash,
+				ChannelIdentifier: p.FromRoute.ChannelIdentifier,
+				Reason:            "lock expired",
+			})
+		}
+		events = append(events, &mediatedtransfer.EventRemoveStateManager{
+			Key: key,
+		})
+		return &transfer.TransitionResult{
+			NewState: nil,
+			Events:   events,
+		}
+	}
+	//一旦所有锁都过期或者解锁了,就结束了,注销StateManager
+	//Once all locks expired or unlocked, remove StateManager.
+	if expired && allExpired {
+		it.Events = append(it.Events, &mediatedtransfer.EventRemoveStateManager{
+			Key: key,
+		})
+	}
+	return it
+}
@@ -283,3 +283,129 @@ func TestStateTransition(t *testing.T) {
 	assert(t, newstate.BlockNumber, blockNumber+1)
 
 }
+
+/*
+target of a multi-part payment must request the secret only when all the parts are received,
+and the payment finishes when all the parts are unlocked.
+*/
+func TestMultiPartTarget(t *testing.T) {
+	var blockNumber int64 = 1
+	var expire = int64(utest.UnitRevealTimeout) + blockNumber + 1
+	initiator := utest.HOP1
+	total := big.NewInt(5)
+
+	st := makeInitStateChange(utest.ADDR, 3, blockNumber, initiator, expire)
+	st.FromTranfer.TotalAmount = total
+	it := StateTransiton(nil, st)
+	assert(t, len(it.Events), 0)
+	state := it.NewState.(*mediatedtransfer.TargetState)
+	assert(t, len(state.Parts), 1)
+
+	fromRoute := utest.MakeRoute(utest.HOP2, big.NewInt(2), utest.UnitSettleTimeout, utest.UnitRevealTimeout, 0, utils.NewRandomHash())
+	fromTransfer := utest.MakeTransfer(big.NewInt(2), initiator, utest.ADDR, expire, utils.EmptyHash, utils.EmptyHash, utest.UnitTokenAddress)
+	fromTransfer.TotalAmount = total
+	it = StateTransiton(state, &mediatedtransfer.ReceiveTransferPartStateChange{
+		FromRoute:    fromRoute,
+		FromTransfer: fromTransfer,
+		BlockNumber:  blockNumber,
+	})
+	assert(t, len(it.Events), 1)
+	ev := it.Events[0].(*mediatedtransfer.EventSendSecretRequest)
+	assert(t, ev.Amount, total)
+	assert(t, ev.Receiver, initiator)
+	assert(t, ev.ChannelIdentifier, fromRoute.ChannelIdentifier)
+
+	it = StateTransiton(state, &mediatedtransfer.ReceiveSecretRevealStateChange{
+		Secret: utest.UnitSecret,
+		Sender: initiator,
+	})
+	assert(t, len(it.Events), 2)
+	assert(t, it.Events[0].(*mediatedtransfer.EventSendRevealSecret).Receiver, initiator)
+	assert(t, it.Events[1].(*mediatedtransfer.EventSendRevealSecret).Receiver, utest.HOP2)
+
+	it = StateTransiton(state, &mediatedtransfer.ReceiveUnlockStateChange{
+		LockSecretHash: utest.UnitHashLock,
+		NodeAddress:    initiator,
+	})
+	assert(t, len(it.Events), 0)
+	assert(t, it.NewState != nil, true)
+	it = StateTransiton(state, &mediatedtransfer.ReceiveUnlockStateChange{
+		LockSecretHash: utest.UnitHashLock,
+		NodeAddress:    utest.HOP2,
+	})
+	assert(t, it.NewState == nil, true)
+	var received *transfer.EventTransferReceivedSuccess
+	for _, e := range it.Events {
+		if e2, ok := e.(*transfer.EventTransferReceivedSuccess); ok {
+			received = e2
+		}
+	}
+	assert(t, received != nil, true)
+	assert(t, received.Amount, total)
+}
+
+func TestHoldPayment(t *testing.T) {
+	var blockNumber int64 = 1
+	var expire = int64(utest.UnitRevealTimeout) + blockNumber + 10
+	initiator := utest.HOP1
+
+	st := makeInitStateChange(utest.ADDR, 3, blockNumber, initiator, expire)
+	st.Hold = true
+	it := StateTransiton(nil, st)
+	//never request the secret
+	assert(t, len(it.Events), 0)
+	state := it.NewState.(*mediatedtransfer.TargetState)
+	assert(t, state.Hold, true)
+
+	//settle
+	it = StateTransiton(state, &mediatedtransfer.ActionSettleHoldStateChange{
+		Secret: utest.UnitSecret,
+	})
+	assert(t, len(it.Events), 1)
+	assert(t, it.Events[0].(*mediatedtransfer.EventSendRevealSecret).Receiver, initiator)
+	//too late to reject
+	it = StateTransiton(state, &mediatedtransfer.ActionRejectHoldStateChange{
+		LockSecretHash: utest.UnitHashLock,
+	})
+	assert(t, it.NewState != nil, true)
+
+	//reject
+	st = makeInitStateChange(utest.ADDR, 3, blockNumber, initiator, expire)
+	st.Hold = true
+	it = StateTransiton(nil, st)
+	state = it.NewState.(*mediatedtransfer.TargetState)
+	it = StateTransiton(state, &mediatedtransfer.ActionRejectHoldStateChange{
+		LockSecretHash: utest.UnitHashLock,
+		Reason:         "rejected by user",
+	})
+	assert(t, it.NewState == nil, true)
+	disposed, ok := it.Events[0].(*mediatedtransfer.EventSendAnnounceDisposed)
+	assert(t, ok, true)
+	assert(t, disposed.Receiver, initiator)
+	assert(t, disposed.LockSecretHash, utest.UnitHashLock)
+
+	//the state manager is removed by the key given, a rebalance target doesn't use the standard one
+	st = makeInitStateChange(utest.ADDR, 3, blockNumber, initiator, expire)
+	st.Hold = true
+	st.Key = utils.NewRandomHash()
+	it = StateTransiton(nil, st)
+	state = it.NewState.(*mediatedtransfer.TargetState)
+	it = StateTransiton(state, &mediatedtransfer.ActionRejectHoldStateChange{
+		LockSecretHash: utest.UnitHashLock,
+	})
+	remove, ok := it.Events[len(it.Events)-1].(*mediatedtransfer.EventRemoveStateManager)
+	assert(t, ok, true)
+	assert(t, remove.Key, st.Key)
+
+	//not settled in time
+	st = makeInitStateChange(utest.ADDR, 3, blockNumber, initiator, expire)
+	st.Hold = true
+	it = StateTransiton(nil, st)
+	state = it.NewState.(*mediatedtransfer.TargetState)
+	it = StateTransiton(state, &transfer.BlockStateChange{
+		BlockNumber: expire - int64(utest.UnitRevealTimeout),
+	})
+	assert(t, it.NewState == nil, true)
+	_, ok = it.Events[0].(*mediatedtransfer.EventSendAnnounceDisposed)
+	assert(t, ok, true)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/mediator"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 //NameTargetTransition name for state manager
@@ -35,9 +36,23 @@ func eventsForRegisterSecret(state *mediatedtransfer.TargetState) (events []tran
 	return
 }
 
+/*
+stateManagerKey is the key of the state manager of `state` in RaidenService.Transfer2StateManager,
+a rebalance has both the initiator and the target on this node, so the target's key is given by ActionInitTargetStateChange.
+*/
+func stateManagerKey(state *mediatedtransfer.TargetState) common.Hash {
+	if state.Key != utils.EmptyHash {
+		return state.Key
+	}
+	return utils.Sha3(state.FromTransfer.LockSecretHash[:], state.FromTransfer.Token[:])
+}
+
 //handleInitTraget Handle an ActionInitTarget state change.
 func handleInitTraget(st *mediatedtransfer.ActionInitTargetStateChange) *transfer.TransitionResult {
 	tr := st.FromTranfer
+	if tr.IsMultiPart() {
+		return handleInitMultiPartTarget(st)
+	}
 	route := st.FromRoute
 	blockNumber := st.BlockNumber
 	state := &mediatedtransfer.TargetState{
@@ -46,13 +61,16 @@ func handleInitTraget(st *mediatedtransfer.ActionInitTargetStateChange) *transfe
 		FromTransfer: tr,
 		BlockNumber:  blockNumber,
 		Db:           st.Db,
+		Hold:         st.Hold,
+		Key:          st.Key,
 	}
 	safeToWait := mediator.IsSafeToWait(tr, route.RevealTimeout(), blockNumber)
 	/*
 			  if there is not enough time to safely withdraw the token on-chain
 		     silently let the transfer expire.
 	*/
-	if safeToWait {
+	//a held payment waits for the user to settle or reject it.
+	if safeToWait && !st.Hold {
 		secretRequest := &mediatedtransfer.EventSendSecretRequest{
 			ChannelIdentifier: route.ChannelIdentifier,
 			LockSecretHash:    tr.LockSecretHash,
@@ -88,11 +106,10 @@ func handleSecretRegisteredOnChain(state *mediatedtransfer.TargetState, st *medi
 		 */
 		state.State = mediatedtransfer.StateSecretRegistered
 		ev := &mediatedtransfer.EventRemoveStateManager{
-			Key: utils.Sha3(st.LockSecretHash[:], state.FromTransfer.Token[:]),
+			Key: stateManagerKey(state),
 		}
 		events = append(events, ev)
-		state.Secret = st.Secret
-		state.FromTransfer.Secret = st.Secret
+		setMultiPartSecret(state, st.Secret)
 	} else {
 		panic("should not here")
 	}
@@ -144,7 +161,7 @@ func handleBalanceProof(state *mediatedtransfer.TargetState, st *mediatedtransfe
 	if st.NodeAddress == state.FromRoute.HopNode() && state.FromTransfer.LockSecretHash == st.LockSecretHash {
 		state.State = mediatedtransfer.StateBalanceProof
 		ev := &mediatedtransfer.EventRemoveStateManager{
-			Key: utils.Sha3(state.FromTransfer.LockSecretHash[:], state.FromTransfer.Token[:]),
+			Key: stateManagerKey(state),
 		}
 		events = append(events, ev)
 	}
@@ -163,13 +180,20 @@ func handleBlock(state *mediatedtransfer.TargetState, st *transfer.BlockStateCha
 	if state.BlockNumber < st.BlockNumber {
 		state.BlockNumber = st.BlockNumber
 	}
+	if isHoldExpiring(state) {
+		return rejectHold(state, "hold payment not settled in time")
+	}
 	/*
 	   only emit the close event once
 
 	*/
 	var events []transfer.Event
 	if state.State != mediatedtransfer.StateWaitingRegisterSecret && state.State != mediatedtransfer.StateSecretRegistered {
-		events = eventsForRegisterSecret(state)
+		if len(state.Parts) > 0 {
+			events = eventsForMultiPartRegisterSecret(state)
+		} else {
+			events = eventsForRegisterSecret(state)
+		}
 	}
 	it = &transfer.TransitionResult{
 		NewState: state,
@@ -188,6 +212,9 @@ func clearIfFinalized(previt *transfer.TransitionResult) (it *transfer.Transitio
 		panic(fmt.Sprintf("clearIfFinalized for targetstate type error:%s", utils.StringInterface1(previt)))
 	}
 	it = previt
+	if len(state.Parts) > 0 {
+		return clearMultiPartIfFinalized(it, state)
+	}
 	if state.FromTransfer.Secret == utils.EmptyHash && state.BlockNumber > state.FromTransfer.Expiration {
 		failed := &mediatedtransfer.EventWithdrawFailed{
 			LockSecretHash:    state.FromTransfer.LockSecretHash,
@@ -206,6 +233,7 @@ func clearIfFinalized(previt *transfer.TransitionResult) (it *transfer.Transitio
 			Amount:            state.FromTransfer.Amount,
 			Initiator:         state.FromTransfer.Initiator,
 			ChannelIdentifier: state.FromRoute.ChannelIdentifier,
+			PaymentData:       state.FromTransfer.PaymentData,
 		}
 		unlockSuccess := &mediatedtransfer.EventWithdrawSuccess{
 			LockSecretHash: state.FromTransfer.LockSecretHash,
@@ -219,7 +247,7 @@ func clearIfFinalized(previt *transfer.TransitionResult) (it *transfer.Transitio
 	// Once locks expired, remove StateManager.
 	if state.BlockNumber > state.FromTransfer.Expiration {
 		it.Events = append(it.Events, &mediatedtransfer.EventRemoveStateManager{
-			Key: utils.Sha3(state.FromTransfer.LockSecretHash[:], state.FromTransfer.Token[:]),
+			Key: stateManagerKey(state),
 		})
 	}
 	return it
@@ -246,8 +274,14 @@ func StateTransiton(originalState transfer.State, stateChange transfer.StateChan
 			it = handleBlock(state, st2)
 		case *mediatedtransfer.ContractSecretRevealOnChainStateChange:
 			it = handleSecretRegisteredOnChain(state, st2)
+		case *mediatedtransfer.ReceiveTransferPartStateChange:
+			it = handleTransferPart(state, st2)
 		case *mediatedtransfer.ReceiveSecretRevealStateChange:
-			if state.FromTransfer.Secret == utils.EmptyHash {
+			if len(state.Parts) > 0 {
+				if state.Secret == utils.EmptyHash {
+					it = handleMultiPartSecretReveal(state, st2)
+				}
+			} else if state.FromTransfer.Secret == utils.EmptyHash {
 				//可能会反复收到 reveal secret, 比如 token swap的时候,再比如存在环路的时候
 				// Maybe we can receive reveal secret over and over again,
 				// such as when using token swap, or circuit exist.
@@ -256,7 +290,17 @@ func StateTransiton(originalState transfer.State, stateChange transfer.StateChan
 		case *mediatedtransfer.ReceiveUnlockStateChange:
 			//有可能在不知道密码的情况下直接收到 unlock 消息,比如
 			// Maybe we can receive unlock message without receiving secret.
-			it = handleBalanceProof(state, st2)
+			if len(state.Parts) > 0 {
+				it = handleMultiPartBalanceProof(state, st2)
+			} else {
+				it = handleBalanceProof(state, st2)
+			}
+		case *mediatedtransfer.ActionSettleHoldStateChange:
+			if state.Hold && state.Secret == utils.EmptyHash && state.FromTransfer.Secret == utils.EmptyHash {
+				it = handleSettleHold(state, st2)
+			}
+		case *mediatedtransfer.ActionRejectHoldStateChange:
+			it = handleRejectHold(state, st2)
 		default:
 			log.Error(fmt.Sprintf("target state manager receive unkown state change %s", utils.StringInterface(stateChange, 3)))
 		}
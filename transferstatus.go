@@ -0,0 +1,234 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//roles of this node in a transfer
+const (
+	TransferRoleInitiator = "initiator"
+	TransferRoleMediator  = "mediator"
+	TransferRoleTarget    = "target"
+	TransferRoleCrash     = "crash" //transfer not finished before restart
+)
+
+/*
+TransferStatus is a snapshot of a pending transfer,
+taken from the StateManager which is processing this transfer.
+*/
+type TransferStatus struct {
+	Role           string         `json:"role"`
+	LockSecretHash common.Hash    `json:"lock_secret_hash"`
+	Token          common.Address `json:"token_address"`
+	Initiator      common.Address `json:"initiator_address"`
+	Target         common.Address `json:"target_address"`
+	Amount         *big.Int       `json:"amount"`
+	Fee            *big.Int       `json:"fee"`
+	FromHop        common.Address `json:"from_hop"` //the node which send this transfer to me,empty for initiator
+	ToHop          common.Address `json:"to_hop"`   //the node I send this transfer to,empty for target
+	FromChannel    common.Hash    `json:"from_channel"`
+	ToChannel      common.Hash    `json:"to_channel"`
+	Expiration     int64          `json:"expiration"`
+	BlockNumber    int64          `json:"block_number"`    //current block number
+	ExpiresIn      int64          `json:"expires_in"`      //blocks left before lock expires, negative means expired
+	SecretKnown    bool           `json:"secret_known"`    //do I know the secret of this transfer
+	State          string         `json:"state"`           //state of target, or payer/payee states of mediator
+	LastMessage    string         `json:"last_message"`    //the last message received for this transfer
+	Identifier     uint64         `json:"identifier"`      //payment identifier
+	Parts          []*PartStatus  `json:"parts,omitempty"` //parts of a multi-part payment
+}
+
+//PartStatus is status of one part of a multi-part payment
+type PartStatus struct {
+	Hop        common.Address `json:"hop"` //next hop for initiator, previous hop for target
+	Channel    common.Hash    `json:"channel"`
+	Amount     *big.Int       `json:"amount"`
+	Expiration int64          `json:"expiration"`
+	State      string         `json:"state"`
+}
+
+func newPartStatus(r *route.State, tr *mediatedtransfer.LockedTransferState, state string) *PartStatus {
+	ps := &PartStatus{
+		Amount:     tr.Amount,
+		Expiration: tr.Expiration,
+		State:      state,
+	}
+	ps.Hop, ps.Channel = hopOfRoute(r)
+	return ps
+}
+
+func hopOfRoute(r *route.State) (hop common.Address, ch common.Hash) {
+	if r == nil {
+		return
+	}
+	return r.HopNode(), r.ChannelIdentifier
+}
+
+func (ts *TransferStatus) fromLockedTransfer(tr *mediatedtransfer.LockedTransferState) {
+	if tr == nil {
+		return
+	}
+	ts.Token = tr.Token
+	ts.Initiator = tr.Initiator
+	ts.Target = tr.Target
+	ts.Amount = tr.Amount
+	ts.Fee = tr.Fee
+	ts.Expiration = tr.Expiration
+	ts.LockSecretHash = tr.LockSecretHash
+	ts.Identifier = tr.PaymentIdentifier
+}
+
+/*
+newTransferStatus create a snapshot of the transfer managed by `mgr`,
+returns nil if this transfer is finished.
+must be called in the main loop.
+*/
+func (rs *RaidenService) newTransferStatus(mgr *transfer.StateManager) *TransferStatus {
+	ts := &TransferStatus{
+		LastMessage: mgr.LastMessage,
+		BlockNumber: rs.GetBlockNumber(),
+	}
+	switch st := mgr.CurrentState.(type) {
+	case *mediatedtransfer.InitiatorState:
+		ts.Role = TransferRoleInitiator
+		ts.fromLockedTransfer(st.Transfer)
+		if st.Message != nil {
+			//the transfer in-transit, it contains the fee and expiration of current route
+			ts.Amount = st.Message.Amount
+			ts.Fee = st.Message.Fee
+			ts.Expiration = st.Message.Expiration
+		}
+		ts.ToHop, ts.ToChannel = hopOfRoute(st.Route)
+		ts.SecretKnown = st.Secret != utils.EmptyHash
+		if st.RevealSecret != nil {
+			ts.State = "reveal_secret"
+		} else if st.SecretRequest != nil {
+			ts.State = "secret_request"
+		} else {
+			ts.State = "pending"
+		}
+	case *mediatedtransfer.MultiPartInitiatorState:
+		ts.Role = TransferRoleInitiator
+		ts.fromLockedTransfer(st.Transfer)
+		ts.Fee = new(big.Int)
+		for _, p := range st.Parts {
+			ts.Fee.Add(ts.Fee, p.Transfer.Fee)
+			if p.Transfer.Expiration > ts.Expiration {
+				ts.Expiration = p.Transfer.Expiration
+			}
+			ts.Parts = append(ts.Parts, newPartStatus(p.Route, p.Transfer, p.State))
+		}
+		ts.SecretKnown = st.Secret != utils.EmptyHash
+		if st.Failed {
+			ts.State = "failed"
+		} else if st.RevealSecret != nil {
+			ts.State = "reveal_secret"
+		} else {
+			ts.State = "pending"
+		}
+	case *mediatedtransfer.MediatorState:
+		ts.Role = TransferRoleMediator
+		ts.Token = st.Token
+		ts.LockSecretHash = st.LockSecretHash
+		ts.SecretKnown = st.Secret != utils.EmptyHash
+		if len(st.TransfersPair) == 0 {
+			return nil
+		}
+		//the last pair is the one in use, the others have been refunded
+		pair := st.TransfersPair[len(st.TransfersPair)-1]
+		ts.fromLockedTransfer(pair.PayeeTransfer)
+		ts.FromHop, ts.FromChannel = hopOfRoute(pair.PayerRoute)
+		ts.ToHop, ts.ToChannel = hopOfRoute(pair.PayeeRoute)
+		ts.State = pair.PayerState + "," + pair.PayeeState
+	case *mediatedtransfer.TargetState:
+		ts.Role = TransferRoleTarget
+		ts.fromLockedTransfer(st.FromTransfer)
+		ts.FromHop, ts.FromChannel = hopOfRoute(st.FromRoute)
+		ts.SecretKnown = st.Secret != utils.EmptyHash
+		ts.State = st.State
+		for _, p := range st.Parts {
+			if p.FromTransfer.Expiration > ts.Expiration {
+				ts.Expiration = p.FromTransfer.Expiration
+			}
+			ts.Parts = append(ts.Parts, newPartStatus(p.FromRoute, p.FromTransfer, p.State))
+		}
+	case *mediatedtransfer.CrashState:
+		ts.Role = TransferRoleCrash
+		ts.Token = st.Token
+		ts.LockSecretHash = st.LockSecretHash
+		for _, l := range st.ReceivedLocks {
+			ts.FromHop = l.Channel.PartnerState.Address
+			ts.FromChannel = l.Channel.ChannelIdentifier.ChannelIdentifier
+			ts.Amount = l.Lock.Amount
+			ts.Expiration = l.Lock.Expiration
+		}
+		for _, l := range st.SentLocks {
+			ts.ToHop = l.Channel.PartnerState.Address
+			ts.ToChannel = l.Channel.ChannelIdentifier.ChannelIdentifier
+			ts.Amount = l.Lock.Amount
+			ts.Expiration = l.Lock.Expiration
+		}
+	default:
+		//finished
+		return nil
+	}
+	ts.ExpiresIn = ts.Expiration - ts.BlockNumber
+	return ts
+}
+
+/*
+getTransferStatus returns status of all pending transfers with `lockSecretHash`,
+empty `lockSecretHash` means all pending transfers.
+must be called in the main loop.
+*/
+func (rs *RaidenService) getTransferStatus(lockSecretHash common.Hash) (statuses []*TransferStatus) {
+	for _, mgr := range rs.Transfer2StateManager {
+		if lockSecretHash != utils.EmptyHash && mgr.Identifier != lockSecretHash {
+			continue
+		}
+		ts := rs.newTransferStatus(mgr)
+		if ts != nil {
+			statuses = append(statuses, ts)
+		}
+	}
+	return
+}
+
+//GetPendingTransfers returns status of all transfers which are not finished
+func (r *RaidenAPI) GetPendingTransfers() (statuses []*TransferStatus, err error) {
+	return r.getTransferStatus(utils.EmptyHash)
+}
+
+/*
+GetTransferStatus returns status of the pending transfer with `lockSecretHash`,
+a node may have more than one transfer with the same lockSecretHash, for example token swap.
+*/
+func (r *RaidenAPI) GetTransferStatus(lockSecretHash common.Hash) (statuses []*TransferStatus, err error) {
+	if lockSecretHash == utils.EmptyHash {
+		err = errors.New("lock secret hash is empty")
+		return
+	}
+	statuses, err = r.getTransferStatus(lockSecretHash)
+	if err == nil && len(statuses) == 0 {
+		err = fmt.Errorf("no pending transfer with lock secret hash %s", lockSecretHash.String())
+	}
+	return
+}
+
+func (r *RaidenAPI) getTransferStatus(lockSecretHash common.Hash) (statuses []*TransferStatus, err error) {
+	result := r.Raiden.transferStatusClient(lockSecretHash)
+	err = <-result.Result
+	if err != nil {
+		return
+	}
+	statuses, _ = result.Tag.([]*TransferStatus)
+	return
+}
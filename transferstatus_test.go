@@ -0,0 +1,55 @@
+package smartraiden
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/target"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils/utest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestGetTransferStatus(t *testing.T) {
+	rs := &RaidenService{
+		BlockNumber:           new(atomic.Value),
+		Transfer2StateManager: make(map[common.Hash]*transfer.StateManager),
+	}
+	rs.BlockNumber.Store(int64(10))
+	initiator := utils.NewRandomAddress()
+	ourAddress := utils.NewRandomAddress()
+	//route needs a channel on chain, so leave it empty
+	fromTransfer := utest.MakeTransfer(big.NewInt(3), initiator, ourAddress, 30, utils.EmptyHash, utils.EmptyHash, utest.UnitTokenAddress)
+	fromTransfer.PaymentIdentifier = 7
+	state := &mediatedtransfer.TargetState{
+		OurAddress:   ourAddress,
+		FromTransfer: fromTransfer,
+		BlockNumber:  10,
+		State:        mediatedtransfer.StateSecretRequest,
+	}
+	mgr := transfer.NewStateManager(target.StateTransiton, state, target.NameTargetTransition, fromTransfer.LockSecretHash, fromTransfer.Token)
+	mgr.LastMessage = "MediatedTransfer from test"
+	rs.Transfer2StateManager[utils.Sha3(fromTransfer.LockSecretHash[:], fromTransfer.Token[:])] = mgr
+	//finished transfer should be ignored
+	rs.Transfer2StateManager[utils.NewRandomHash()] = transfer.NewStateManager(target.StateTransiton, nil, target.NameTargetTransition, utils.NewRandomHash(), utils.NewRandomAddress())
+
+	statuses := rs.getTransferStatus(utils.EmptyHash)
+	if !assert(t, 1, len(statuses)) {
+		return
+	}
+	s := statuses[0]
+	assert(t, TransferRoleTarget, s.Role)
+	assert(t, initiator, s.Initiator)
+	assert(t, utils.EmptyAddress, s.FromHop)
+	assert(t, 20, s.ExpiresIn)
+	assert(t, 7, s.Identifier)
+	assert(t, false, s.SecretKnown)
+	assert(t, mediatedtransfer.StateSecretRequest, s.State)
+	assert(t, mgr.LastMessage, s.LastMessage)
+
+	assert(t, 1, len(rs.getTransferStatus(fromTransfer.LockSecretHash)))
+	assert(t, 0, len(rs.getTransferStatus(utils.NewRandomHash())))
+}
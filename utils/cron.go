@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Cron is a parsed cron expression with five fields: minute hour day-of-month month day-of-week.
+//each field is `*`, a number, a range `a-b`, a step like `*/15` or `a-b/n`, or a list of them separated by `,`.
+//day-of-week is 0-6 starting from Sunday, 7 is also Sunday.
+//like the standard cron, if both day-of-month and day-of-week are restricted, a day matching either of them matches.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+//ParseCron parses a cron expression like "0 * * * *"
+func ParseCron(expr string) (c *Cron, err error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	c = &Cron{}
+	bounds := []struct {
+		field    *uint64
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		*b.field, err = parseCronField(fields[i], b.min, b.max)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %s", expr, err)
+		}
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+			part = part[:i]
+		}
+		start, end := min, max
+		if part != "*" {
+			if i := strings.Index(part, "-"); i >= 0 {
+				start, err = strconv.Atoi(part[:i])
+				if err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+				end, err = strconv.Atoi(part[i+1:])
+				if err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+			} else {
+				start, err = strconv.Atoi(part)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+				end = start
+				if step > 1 {
+					end = max
+				}
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+/*
+Next returns the first time matching `c` after `t`, in the location of `t`.
+it returns the zero time if nothing matches in five years, for example "0 0 30 2 *".
+*/
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
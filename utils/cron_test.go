@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2018, 10, 17, 10, 30, 20, 0, time.UTC) //Wednesday
+	cases := map[string]time.Time{
+		"* * * * *":      time.Date(2018, 10, 17, 10, 31, 0, 0, time.UTC),
+		"0 * * * *":      time.Date(2018, 10, 17, 11, 0, 0, 0, time.UTC),
+		"*/15 * * * *":   time.Date(2018, 10, 17, 10, 45, 0, 0, time.UTC),
+		"30 9 * * *":     time.Date(2018, 10, 18, 9, 30, 0, 0, time.UTC),
+		"0 0 1 * *":      time.Date(2018, 11, 1, 0, 0, 0, 0, time.UTC),
+		"0 8 * * 1-5":    time.Date(2018, 10, 18, 8, 0, 0, 0, time.UTC),
+		"0 8 * * 0,6":    time.Date(2018, 10, 20, 8, 0, 0, 0, time.UTC),
+		"0 8 * * 7":      time.Date(2018, 10, 21, 8, 0, 0, 0, time.UTC),
+		"0 0 1 1 *":      time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 20 * 1":     time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC), //20th or monday
+		"0 12 29 2 *":    time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC),
+		"10-20/5 10 * *": {},
+	}
+	for expr, expect := range cases {
+		c, err := ParseCron(expr)
+		if expect.IsZero() {
+			if err == nil {
+				t.Errorf("%s should be invalid", expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if next := c.Next(from); !next.Equal(expect) {
+			t.Errorf("next of %s should be %s,got %s", expr, expect, next)
+		}
+	}
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !c.Next(from).IsZero() {
+		t.Error("30th February should never match")
+	}
+	for _, expr := range []string{"60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err = ParseCron(expr)
+		if err == nil {
+			t.Errorf("%s should be invalid", expr)
+		}
+	}
+}
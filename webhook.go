@@ -0,0 +1,353 @@
+package smartraiden
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//events notified by webhook
+const (
+	WebhookEventReceivedTransfer = "received_transfer"
+	WebhookEventChannelClosed    = "channel_closed" //channel closed by partner
+	WebhookEventChannelDeposit   = "channel_deposit"
+	WebhookEventChannelWithdraw  = "channel_withdraw"
+)
+
+//headers of webhook request
+const (
+	WebhookHeaderEvent     = "X-SmartRaiden-Event"
+	WebhookHeaderEventID   = "X-SmartRaiden-Event-ID"
+	WebhookHeaderSignature = "X-SmartRaiden-Signature" //hex encoded HMAC-SHA256 of body
+)
+
+var webhookEvents = map[string]bool{
+	WebhookEventReceivedTransfer: true,
+	WebhookEventChannelClosed:    true,
+	WebhookEventChannelDeposit:   true,
+	WebhookEventChannelWithdraw:  true,
+}
+
+/*
+WebhookPayload is the body posted to webhook targets,
+a payload is delivered at least once, `EventID` is the same for redelivery of one event.
+*/
+type WebhookPayload struct {
+	EventID   string      `json:"event_id"`
+	EventType string      `json:"event_type"`
+	Time      int64       `json:"time"`
+	Data      interface{} `json:"data"`
+}
+
+//WebhookChannelData is the data of channel events
+type WebhookChannelData struct {
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	OpenBlockNumber   int64          `json:"open_block_number"`
+	TokenAddress      common.Address `json:"token_address"`
+	PartnerAddress    common.Address `json:"partner_address"`
+	Balance           *big.Int       `json:"balance"`
+	PartnerBalance    *big.Int       `json:"partner_balance"`
+	State             string         `json:"state"`
+	ClosedBlock       int64          `json:"closed_block"`
+}
+
+func newWebhookChannelData(c *channeltype.Serialization) *WebhookChannelData {
+	return &WebhookChannelData{
+		ChannelIdentifier: c.ChannelIdentifier.ChannelIdentifier,
+		OpenBlockNumber:   c.ChannelIdentifier.OpenBlockNumber,
+		TokenAddress:      c.TokenAddress(),
+		PartnerAddress:    c.PartnerAddress(),
+		Balance:           c.OurBalance(),
+		PartnerBalance:    c.PartnerBalance(),
+		State:             c.State.String(),
+		ClosedBlock:       c.ClosedBlock,
+	}
+}
+
+//signWebhookPayload returns hex encoded HMAC-SHA256 of `body`
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+/*
+webhookService posts events to webhook targets.
+events are saved to db first, then delivered by a background goroutine,
+failed deliveries are retried with exponential backoff until success or the target is removed.
+*/
+type webhookService struct {
+	db              *models.ModelDB
+	client          *http.Client
+	wakeChan        chan struct{}
+	quitChan        chan struct{}
+	notifiedClosed  map[common.Hash]int64                    //channel -> closed block, avoid notifying close more than once
+	closedByPartner func(channelIdentifier common.Hash) bool //false if the channel is not being closed on chain now
+	retryInterval   time.Duration
+	maxRetryBackoff time.Duration
+}
+
+func newWebhookService(db *models.ModelDB, quitChan chan struct{}, closedByPartner func(channelIdentifier common.Hash) bool) *webhookService {
+	return &webhookService{
+		db:              db,
+		client:          &http.Client{Timeout: params.WebhookTimeout},
+		wakeChan:        make(chan struct{}, 1),
+		quitChan:        quitChan,
+		notifiedClosed:  make(map[common.Hash]int64),
+		closedByPartner: closedByPartner,
+		retryInterval:   params.WebhookRetryInterval,
+		maxRetryBackoff: params.WebhookMaxRetryBackoff,
+	}
+}
+
+/*
+start register callbacks to db and start delivering.
+callbacks are called in the main loop of raiden.
+*/
+func (ws *webhookService) start() {
+	ws.db.RegisterReceivedTransferCallback(func(t *models.ReceivedTransfer) bool {
+		ws.notify(WebhookEventReceivedTransfer, t.Key, t)
+		return false
+	})
+	ws.db.RegisterChannelDepositCallback(func(c *channeltype.Serialization) bool {
+		ws.notify(WebhookEventChannelDeposit,
+			fmt.Sprintf("%s-%d-%s-%s", c.ChannelIdentifier.ChannelIdentifier.String(), c.ChannelIdentifier.OpenBlockNumber,
+				c.OurContractBalance, c.PartnerContractBalance),
+			newWebhookChannelData(c))
+		return false
+	})
+	ws.db.RegisterChannelStateCallback(func(c *channeltype.Serialization) bool {
+		channelIdentifier := c.ChannelIdentifier.ChannelIdentifier
+		switch c.State {
+		case channeltype.StateClosed:
+			//unlock,punish and update balance proof also change state of a closed channel, only the close event tells who closed it
+			if !ws.closedByPartner(channelIdentifier) || ws.notifiedClosed[channelIdentifier] == c.ClosedBlock {
+				return false
+			}
+			ws.notifiedClosed[channelIdentifier] = c.ClosedBlock
+			ws.notify(WebhookEventChannelClosed, fmt.Sprintf("%s-%d-closed", channelIdentifier.String(), c.ClosedBlock),
+				newWebhookChannelData(c))
+		case channeltype.StateOpened:
+			//only withdraw makes a channel opened again
+			ws.notify(WebhookEventChannelWithdraw, fmt.Sprintf("%s-%d", channelIdentifier.String(), c.ChannelIdentifier.OpenBlockNumber),
+				newWebhookChannelData(c))
+		}
+		return false
+	})
+	go ws.loop()
+}
+
+//notify saves an event for every target interested in it
+func (ws *webhookService) notify(eventType, eventID string, data interface{}) {
+	ts, err := ws.db.GetWebhookTargets()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetWebhookTargets err %s", err))
+		return
+	}
+	if len(ts) == 0 {
+		return
+	}
+	p := &WebhookPayload{
+		EventID:   eventID,
+		EventType: eventType,
+		Time:      time.Now().Unix(),
+		Data:      data,
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.Error(fmt.Sprintf("marshal webhook payload err %s", err))
+		return
+	}
+	for _, t := range ts {
+		if !t.Interests(eventType) {
+			continue
+		}
+		err = ws.db.NewWebhookDelivery(&models.WebhookDelivery{
+			TargetID:    t.ID,
+			EventID:     eventID,
+			EventType:   eventType,
+			Payload:     body,
+			NextAttempt: p.Time,
+		})
+		if err != nil {
+			log.Error(fmt.Sprintf("NewWebhookDelivery err %s", err))
+		}
+	}
+	select {
+	case ws.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+func (ws *webhookService) loop() {
+	ticker := time.NewTicker(ws.retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.quitChan:
+			return
+		case <-ws.wakeChan:
+		case <-ticker.C:
+		}
+		ws.deliverDue(time.Now())
+	}
+}
+
+//deliverDue tries to deliver all the deliveries whose next attempt is before `now`
+func (ws *webhookService) deliverDue(now time.Time) {
+	ds, err := ws.db.GetDueWebhookDeliveries(now.Unix())
+	if err != nil {
+		log.Error(fmt.Sprintf("GetDueWebhookDeliveries err %s", err))
+		return
+	}
+	for _, d := range ds {
+		select {
+		case <-ws.quitChan:
+			return
+		default:
+		}
+		t, err := ws.db.GetWebhookTarget(d.TargetID)
+		if err != nil {
+			//target removed
+			err = ws.db.RemoveWebhookDelivery(d)
+			if err != nil {
+				log.Error(fmt.Sprintf("RemoveWebhookDelivery err %s", err))
+			}
+			continue
+		}
+		err = ws.post(t, d)
+		if err == nil {
+			err = ws.db.RemoveWebhookDelivery(d)
+			if err != nil {
+				log.Error(fmt.Sprintf("RemoveWebhookDelivery err %s", err))
+			}
+			continue
+		}
+		log.Warn(fmt.Sprintf("webhook %s deliver %s err %s", t.URL, d.EventID, err))
+		d.Attempts++
+		d.LastError = err.Error()
+		backoff := ws.retryInterval << uint(d.Attempts-1)
+		if backoff > ws.maxRetryBackoff || backoff <= 0 {
+			backoff = ws.maxRetryBackoff
+		}
+		d.NextAttempt = now.Add(backoff).Unix()
+		err = ws.db.UpdateWebhookDelivery(d)
+		if err != nil {
+			log.Error(fmt.Sprintf("UpdateWebhookDelivery err %s", err))
+		}
+	}
+}
+
+func (ws *webhookService) post(t *models.WebhookTarget, d *models.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, t.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderEvent, d.EventType)
+	req.Header.Set(WebhookHeaderEventID, d.EventID)
+	if t.Secret != "" {
+		req.Header.Set(WebhookHeaderSignature, signWebhookPayload(t.Secret, d.Payload))
+	}
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http status %s", resp.Status)
+	}
+	return nil
+}
+
+//GetWebhooks returns all the webhook targets
+func (r *RaidenAPI) GetWebhooks() ([]*models.WebhookTarget, error) {
+	return r.Raiden.db.GetWebhookTargets()
+}
+
+/*
+AddWebhook add a webhook target, payload posted to `targetURL` is signed with `secret`,
+`events` is the events this target interests, empty means all the events.
+*/
+func (r *RaidenAPI) AddWebhook(targetURL, secret string, events []string) (t *models.WebhookTarget, err error) {
+	return addWebhookTarget(r.Raiden.db, targetURL, secret, events)
+}
+
+func addWebhookTarget(db *models.ModelDB, targetURL, secret string, events []string) (t *models.WebhookTarget, err error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		err = errors.New("webhook url must be http or https")
+		return
+	}
+	for _, e := range events {
+		if !webhookEvents[e] {
+			err = fmt.Errorf("unknown webhook event %s", e)
+			return
+		}
+	}
+	t = &models.WebhookTarget{
+		URL:    targetURL,
+		Secret: secret,
+		Events: events,
+	}
+	err = db.AddWebhookTarget(t)
+	return
+}
+
+/*
+startWebhook start notifying events to webhook targets,
+the target configured by command line is added if not exist.
+*/
+func (rs *RaidenService) startWebhook() {
+	if rs.Config.WebhookURL != "" {
+		ts, err := rs.db.GetWebhookTargets()
+		if err != nil {
+			log.Error(fmt.Sprintf("GetWebhookTargets err %s", err))
+		}
+		exist := false
+		for _, t := range ts {
+			if t.URL == rs.Config.WebhookURL {
+				exist = true
+			}
+		}
+		if !exist {
+			_, err = addWebhookTarget(rs.db, rs.Config.WebhookURL, rs.Config.WebhookSecret, nil)
+			if err != nil {
+				log.Error(fmt.Sprintf("add webhook %s err %s", rs.Config.WebhookURL, err))
+			}
+		}
+	}
+	ws := newWebhookService(rs.db, rs.quitChan, func(channelIdentifier common.Hash) bool {
+		closingAddress, ok := rs.channelClosingAddress[channelIdentifier]
+		return ok && closingAddress != rs.NodeAddress
+	})
+	ws.start()
+}
+
+//RemoveWebhook remove webhook target with `id`, its undelivered payloads are dropped.
+func (r *RaidenAPI) RemoveWebhook(id int) error {
+	return r.Raiden.db.RemoveWebhookTarget(id)
+}
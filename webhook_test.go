@@ -0,0 +1,91 @@
+package smartraiden
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func newTestWebhookService(t *testing.T) *webhookService {
+	db := setupDb(t, "testwebhook.db")
+	return newWebhookService(db, make(chan struct{}), func(channelIdentifier common.Hash) bool {
+		return false
+	})
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	rc := make(chan *received, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		rc <- &received{r.Header, body}
+	}))
+	defer server.Close()
+	ws := newTestWebhookService(t)
+	defer ws.db.CloseDB()
+	_, err := addWebhookTarget(ws.db, server.URL, "secret", []string{WebhookEventReceivedTransfer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.notify(WebhookEventChannelClosed, "closed", nil) //not interested
+	ws.notify(WebhookEventReceivedTransfer, "transfer", "data")
+	ws.deliverDue(time.Now())
+	r := <-rc
+	assert(t, WebhookEventReceivedTransfer, r.header.Get(WebhookHeaderEvent))
+	assert(t, "transfer", r.header.Get(WebhookHeaderEventID))
+	assert(t, signWebhookPayload("secret", r.body), r.header.Get(WebhookHeaderSignature))
+	assert(t, 0, len(rc))
+	ds, err := ws.db.GetDueWebhookDeliveries(time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 0, len(ds))
+}
+
+func TestWebhookRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	ws := newTestWebhookService(t)
+	defer ws.db.CloseDB()
+	_, err := addWebhookTarget(ws.db, server.URL, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.notify(WebhookEventChannelDeposit, "deposit", nil)
+	now := time.Now()
+	ws.deliverDue(now)
+	ds, err := ws.db.GetDueWebhookDeliveries(now.Add(ws.retryInterval).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 1, len(ds))
+	assert(t, 1, ds[0].Attempts)
+	assert(t, now.Add(ws.retryInterval).Unix(), ds[0].NextAttempt)
+	ws.deliverDue(now.Add(ws.retryInterval))
+	ds, err = ws.db.GetDueWebhookDeliveries(now.Add(3 * ws.retryInterval).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 1, len(ds))
+	assert(t, 2, ds[0].Attempts)
+	assert(t, now.Add(3*ws.retryInterval).Unix(), ds[0].NextAttempt)
+	//undelivered events are removed together with the target
+	err = ws.db.RemoveWebhookTarget(ds[0].TargetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err = ws.db.GetDueWebhookDeliveries(now.Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, 0, len(ds))
+}